@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// ContentSelectors control which part of a page counts as its main content.
+type ContentSelectors struct {
+	Main    []string // the first selector matching the page is its main content, <body> otherwise
+	Exclude []string // removed from the main content, e.g. navigation and sidebars
+}
+
+// Content is the searchable text of a page.
+type Content struct {
+	Title       string
+	Description string
+	Lang        string // language of the <html> element, e.g. "en" or "fr-CA"
+	Headings    []string
+	Text        string // main content text, including headings, whitespace collapsed
+}
+
+// neverContent lists elements whose text is not page content.
+const neverContent = "script, style, noscript, template, svg, iframe, object"
+
+// ExtractContent parses an HTML body and extracts its title, headings and main text.
+func ExtractContent(body []byte, sel ContentSelectors) (*Content, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	content := &Content{
+		Title:       collapse(doc.Find("head title").First().Text()),
+		Description: collapse(doc.Find(`head meta[name="description"]`).AttrOr("content", "")),
+		Lang:        strings.TrimSpace(doc.Find("html").AttrOr("lang", "")),
+	}
+
+	main := doc.Find("body")
+	for _, selector := range sel.Main {
+		if found := doc.Find(selector).First(); found.Length() > 0 {
+			main = found
+			break
+		}
+	}
+
+	main.Find(neverContent).Remove()
+	for _, selector := range sel.Exclude {
+		main.Find(selector).Remove()
+	}
+
+	main.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		if heading := collapse(textOf(s)); heading != "" {
+			content.Headings = append(content.Headings, heading)
+		}
+	})
+	content.Text = collapse(textOf(main))
+
+	return content, nil
+}
+
+// textOf returns the text of a selection, with elements separated by spaces
+// so "<p>a</p><p>b</p>" reads "a b" rather than "ab" as with Selection.Text.
+func textOf(s *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+		case html.ElementNode, html.DocumentNode:
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+			b.WriteByte(' ')
+		}
+	}
+	for _, n := range s.Nodes {
+		walk(n)
+	}
+	return b.String()
+}
+
+// collapse trims a string and collapses its whitespace runs to single spaces.
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,259 @@
+package checker
+
+import (
+	"bytes"
+	"html"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/compressor"
+	"github.com/felixdorn/bare/core/domain/rewriter"
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// LocalHosts are hosts that never make sense in a deployed site.
+var LocalHosts = []string{"localhost", "127.0.0.1", "0.0.0.0", "[::1]"}
+
+// Roots are files that are requested without being referenced, so they are never orphans.
+var Roots = url.Paths{"/index.html", "/404.html", "/robots.txt", "/favicon.ico", "/_redirects", "/_headers"}
+
+// locRegex matches the page URLs listed in a sitemap.
+var locRegex = regexp.MustCompile(`(?i)<loc>\s*([^<\s]+)\s*</loc>`)
+
+// textExtensions are the files scanned for leftover origin URLs.
+var textExtensions = map[string]bool{
+	".html": true, ".htm": true, ".css": true, ".js": true, ".mjs": true, ".json": true,
+	".xml": true, ".txt": true, ".svg": true, ".webmanifest": true,
+}
+
+// Options configures the Checker.
+type Options struct {
+	OriginURL *url.URL  // URL the site was exported from, its host counts as a leftover origin
+	PublicURL *url.URL  // URL the export is deployed to, absolute references to it are checked like root-relative ones
+	Ignore    url.Paths // paths never reported as orphans, e.g. files only loaded from JavaScript
+	// Entrypoints are the pages the export crawled from, e.g. its entrypoints and expanded routes:
+	// nothing has to link to them
+	Entrypoints []string
+}
+
+// Problem is a reference found in an exported file.
+type Problem struct {
+	File string // path of the file, relative to the output directory
+	Line int
+	URL  string
+}
+
+// Report lists the problems found in an export.
+type Report struct {
+	Files      int
+	References int
+	Missing    []Problem // references to files that aren't in the export
+	Leftovers  []Problem // absolute URLs to the origin or to a local server
+	Orphans    []string  // files nothing references, relative to the output directory
+}
+
+// Problems returns the number of problems in the report.
+func (r *Report) Problems() int {
+	return len(r.Missing) + len(r.Leftovers) + len(r.Orphans)
+}
+
+// Checker verifies that the references of an exported site resolve, without a server.
+type Checker struct {
+	OutputDir   string
+	opts        Options
+	originRegex *regexp.Regexp
+}
+
+// New creates a new Checker instance.
+func New(outputDir string, opts Options) *Checker {
+	hosts := make([]string, 0, len(LocalHosts)+1)
+	for _, h := range LocalHosts {
+		hosts = append(hosts, regexp.QuoteMeta(h))
+	}
+	if opts.OriginURL != nil && opts.OriginURL.Hostname() != "" {
+		hosts = append(hosts, regexp.QuoteMeta(opts.OriginURL.Hostname()))
+	}
+
+	return &Checker{
+		OutputDir:   outputDir,
+		opts:        opts,
+		originRegex: regexp.MustCompile(`(?i)\bhttps?://(?:` + strings.Join(hosts, "|") + `)(?::\d+)?(?:[/?#][^\s"'<>)\x60]*)?`),
+	}
+}
+
+// Run walks the output directory and checks every reference of its HTML and CSS files.
+func (c *Checker) Run() (*Report, error) {
+	report := &Report{}
+
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+	report.Files = len(files)
+
+	referenced := make(map[string]bool)
+	for _, rel := range files {
+		content, err := os.ReadFile(filepath.Join(c.OutputDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		ext := strings.ToLower(path.Ext(rel))
+
+		for _, ref := range rewriter.FindReferences(content, ext) {
+			report.References++
+			target, internal := c.target(rel, ref.URL)
+			if !internal {
+				continue
+			}
+
+			resolved, ok := rewriter.ResolvePath(c.OutputDir, target)
+			if !ok {
+				report.Missing = append(report.Missing, Problem{File: rel, Line: lineOf(content, ref.Start), URL: ref.URL})
+				continue
+			}
+			if r, err := filepath.Rel(c.OutputDir, resolved); err == nil {
+				referenced[filepath.ToSlash(r)] = true
+			}
+		}
+
+		if textExtensions[ext] {
+			for _, m := range c.originRegex.FindAllIndex(content, -1) {
+				u := string(content[m[0]:m[1]])
+				if strings.Contains(u, "norewrite") {
+					continue // deliberately left absolute
+				}
+				report.Leftovers = append(report.Leftovers, Problem{File: rel, Line: lineOf(content, m[0]), URL: u})
+			}
+		}
+
+		// robots.txt points crawlers to the sitemap
+		if rel == "robots.txt" {
+			for _, line := range strings.Split(string(content), "\n") {
+				if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "sitemap") {
+					c.markReferenced(referenced, rel, strings.TrimSpace(value))
+				}
+			}
+		}
+
+		// and sitemaps point them to the pages, and to the other sitemaps of an index
+		if isSitemap(rel) {
+			for _, m := range locRegex.FindAllSubmatch(content, -1) {
+				c.markReferenced(referenced, rel, html.UnescapeString(string(m[1])))
+			}
+		}
+	}
+
+	for _, ep := range c.opts.Entrypoints {
+		c.markReferenced(referenced, "", ep)
+	}
+
+	existing := make(map[string]bool, len(files))
+	for _, rel := range files {
+		existing[rel] = true
+	}
+	for _, rel := range files {
+		if referenced[rel] || c.isRoot(rel, existing) {
+			continue
+		}
+		report.Orphans = append(report.Orphans, rel)
+	}
+
+	return report, nil
+}
+
+// markReferenced records the file a reference from file points to, if it is in the export.
+func (c *Checker) markReferenced(referenced map[string]bool, file, ref string) {
+	target, internal := c.target(file, ref)
+	if !internal {
+		return
+	}
+	resolved, ok := rewriter.ResolvePath(c.OutputDir, target)
+	if !ok {
+		return
+	}
+	if r, err := filepath.Rel(c.OutputDir, resolved); err == nil {
+		referenced[filepath.ToSlash(r)] = true
+	}
+}
+
+// files lists the files of the output directory, relative to it, in order.
+func (c *Checker) files() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(c.OutputDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(c.OutputDir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// target returns the root-relative path a reference from file points to,
+// and false if the reference leaves the export.
+func (c *Checker) target(file, ref string) (string, bool) {
+	u, err := neturl.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+
+	if u.Scheme != "" || u.Host != "" {
+		if c.opts.PublicURL == nil || !strings.EqualFold(u.Host, c.opts.PublicURL.Host) {
+			return "", false // external, or an origin URL reported as a leftover
+		}
+		prefix := strings.TrimSuffix(c.opts.PublicURL.Path, "/")
+		if !strings.HasPrefix(u.Path, prefix+"/") && u.Path != prefix {
+			return "", false
+		}
+		u.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(u.Path, prefix), "/")
+	}
+
+	if u.Path == "" {
+		return "", false // query or fragment on the same page
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = path.Join(path.Dir("/"+file), u.Path)
+		if strings.HasSuffix(ref, "/") {
+			u.Path += "/"
+		}
+	}
+	return u.Path, true
+}
+
+// isRoot reports whether a file is requested without being referenced.
+func (c *Checker) isRoot(rel string, existing map[string]bool) bool {
+	p := "/" + rel
+	if Roots.MatchAny(p) || c.opts.Ignore.MatchAny(p) {
+		return true
+	}
+	// sitemap.xml, or the parts of a sitemap index
+	if isSitemap(rel) {
+		return true
+	}
+	// Precompressed sidecars are served in place of the file they compress
+	return compressor.IsSidecar(rel, existing)
+}
+
+// isSitemap reports whether a file is sitemap.xml or a part of a sitemap index.
+func isSitemap(rel string) bool {
+	name := path.Base(rel)
+	return strings.HasPrefix(name, "sitemap") && path.Ext(name) == ".xml" && path.Dir(rel) == "."
+}
+
+// lineOf returns the 1-based line of an offset in content.
+func lineOf(content []byte, offset int) int {
+	return bytes.Count(content[:offset], []byte("\n")) + 1
+}
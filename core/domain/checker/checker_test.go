@@ -0,0 +1,99 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Run(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html": `<html><head><link href="/css/style.css" rel="stylesheet"></head><body>
+<a href="/about">About</a>
+<a href="blog/">Blog</a>
+<a href="/missing">Missing</a>
+<a href="https://example.com/">External</a>
+<a href="#top">Top</a>
+<a href="http://127.0.0.1:8000/contact">Contact</a>
+<a href="http://127.0.0.1:8000/admin?norewrite">Admin</a>
+</body></html>`,
+		"about.html":         `<img src="img/logo.png" srcset="img/logo.png 1x, img/logo@2x.png 2x">`,
+		"blog/index.html":    `<a href="../about?ref=blog">About</a><a href="post/">Post</a>`,
+		"css/style.css":      `body { background: url(../img/bg.png) } @import "fonts.css";`,
+		"css/style.css.gz":   "",
+		"img/logo.png":       "",
+		"img/bg.png":         "",
+		"img/unused.png":     "",
+		"js/app.js":          `fetch("http://localhost:8000/api/posts.json")`,
+		"robots.txt":         "User-agent: *\nDisallow:\n\nSitemap: https://www.example.org/sitemap.xml\n",
+		"sitemap.xml":        `<urlset><url><loc>https://www.example.org/</loc></url></urlset>`,
+		"_search/docs.json":  "[]",
+		"unlinked/page.html": "<p>Nobody links here</p>",
+	})
+
+	origin, err := url.Parse("http://127.0.0.1:8000")
+	require.NoError(t, err)
+	public, err := url.Parse("https://www.example.org")
+	require.NoError(t, err)
+
+	report, err := New(dir, Options{
+		OriginURL: origin,
+		PublicURL: public,
+		Ignore:    url.Paths{"/_search/**"},
+	}).Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, 13, report.Files)
+	assert.Equal(t, []Problem{
+		{File: "about.html", Line: 1, URL: "img/logo@2x.png"},
+		{File: "blog/index.html", Line: 1, URL: "post/"},
+		{File: "css/style.css", Line: 1, URL: "fonts.css"},
+		{File: "index.html", Line: 4, URL: "/missing"},
+	}, report.Missing)
+	assert.Equal(t, []Problem{
+		{File: "index.html", Line: 7, URL: "http://127.0.0.1:8000/contact"},
+		{File: "js/app.js", Line: 1, URL: "http://localhost:8000/api/posts.json"},
+	}, report.Leftovers)
+	assert.Equal(t, []string{"img/unused.png", "js/app.js", "unlinked/page.html"}, report.Orphans)
+	assert.Equal(t, 9, report.Problems())
+}
+
+func TestChecker_Run_Clean(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html":       `<a href="/posts/hello/">Hello</a><a href="mailto:me@example.com">Mail</a>`,
+		"posts/hello.html": `<a href="/">Home</a><img src="data:image/png;base64,AAAA">`,
+		"404.html":         `<a href="/">Home</a>`,
+	})
+
+	report, err := New(dir, Options{}).Run()
+	require.NoError(t, err)
+
+	assert.Zero(t, report.Problems())
+}
+
+func TestChecker_Run_Roots(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html":         `<p>Links to nothing</p>`,
+		"landing/index.html": `<p>An entrypoint</p>`,
+		"products/1.html":    `<p>A page of an expanded route</p>`,
+		"sitemap.xml":        `<sitemapindex><sitemap><loc>https://www.example.org/sitemap-1.xml</loc></sitemap></sitemapindex>`,
+		"sitemap-1.xml":      `<urlset><url><loc>https://www.example.org/legal?a=1&amp;b=2</loc></url></urlset>`,
+		"legal.html":         `<p>Only listed in the sitemap</p>`,
+		"unlinked.html":      `<p>Nobody links here</p>`,
+	})
+
+	public, err := url.Parse("https://www.example.org")
+	require.NoError(t, err)
+
+	report, err := New(dir, Options{
+		PublicURL:   public,
+		Entrypoints: []string{"/", "/landing/", "/products/1"},
+	}).Run()
+	require.NoError(t, err)
+
+	assert.Empty(t, report.Missing)
+	assert.Equal(t, []string{"unlinked.html"}, report.Orphans)
+}
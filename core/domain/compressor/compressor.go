@@ -0,0 +1,264 @@
+package compressor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Format is a compression format written next to exported files.
+type Format string
+
+const (
+	Gzip   Format = "gzip"
+	Brotli Format = "br"
+)
+
+// Extension returns the file extension of the format's sidecar files.
+func (f Format) Extension() string {
+	switch f {
+	case Gzip:
+		return ".gz"
+	case Brotli:
+		return ".br"
+	}
+	return ""
+}
+
+// IsSidecar reports whether p is the sidecar of a file in files, keyed by path like p.
+func IsSidecar(p string, files map[string]bool) bool {
+	for _, f := range []Format{Gzip, Brotli} {
+		if base, ok := strings.CutSuffix(p, f.Extension()); ok && files[base] {
+			return true
+		}
+	}
+	return false
+}
+
+// compressible lists the extensions of text-based files worth compressing.
+// Images, fonts (except SVG and legacy formats) and archives are already compressed.
+var compressible = map[string]bool{
+	".html":        true,
+	".htm":         true,
+	".css":         true,
+	".js":          true,
+	".mjs":         true,
+	".json":        true,
+	".xml":         true,
+	".svg":         true,
+	".txt":         true,
+	".map":         true,
+	".webmanifest": true,
+	".ico":         true,
+	".wasm":        true,
+	".ttf":         true,
+	".otf":         true,
+	".eot":         true,
+}
+
+// IsCompressible checks if a file should get compressed sidecars based on its extension.
+func IsCompressible(path string) bool {
+	return compressible[strings.ToLower(filepath.Ext(path))]
+}
+
+// Options configures the Compressor.
+type Options struct {
+	Formats     []Format
+	GzipLevel   int   // 0-9, used as given: 0 stores the content uncompressed
+	BrotliLevel int   // 0-11, used as given: 0 is the fastest
+	MinSize     int64 // files smaller than this are skipped
+	Workers     int   // defaults to the number of CPUs
+}
+
+// Stats summarizes a compression run.
+type Stats struct {
+	Files           int // files that got at least one sidecar
+	Sidecars        int
+	OriginalBytes   int64
+	CompressedBytes map[Format]int64
+}
+
+// Compressor walks a directory of exported files and writes precompressed
+// sidecars (file.css.gz, file.css.br) next to compressible files.
+type Compressor struct {
+	OutputDir string
+	opts      Options
+}
+
+// New creates a new Compressor instance.
+func New(outputDir string, opts Options) *Compressor {
+	if len(opts.Formats) == 0 {
+		opts.Formats = []Format{Gzip, Brotli}
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+
+	return &Compressor{
+		OutputDir: outputDir,
+		opts:      opts,
+	}
+}
+
+// Run compresses every eligible file in the output directory.
+func (c *Compressor) Run() (*Stats, error) {
+	for _, f := range c.opts.Formats {
+		if f.Extension() == "" {
+			return nil, fmt.Errorf("unknown compression format %q", f)
+		}
+	}
+
+	var files []string
+	err := filepath.WalkDir(c.OutputDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !IsCompressible(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() < c.opts.MinSize {
+			// The file may have shrunk since a previous export compressed it
+			return removeSidecars(path)
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{CompressedBytes: make(map[Format]int64)}
+	var mu sync.Mutex
+	var firstErr error
+
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < c.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				sizes, original, err := c.compressFile(path)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if len(sizes) > 0 {
+					stats.Files++
+					stats.OriginalBytes += original
+					for f, size := range sizes {
+						stats.Sidecars++
+						stats.CompressedBytes[f] += size
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, path := range files {
+		paths <- path
+	}
+	close(paths)
+	wg.Wait()
+
+	return stats, firstErr
+}
+
+// Compress writes the sidecars of a single file, e.g. a page exported again, and removes
+// its stale ones. Files that aren't compressible are left alone.
+func (c *Compressor) Compress(path string) error {
+	if !IsCompressible(path) {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() < c.opts.MinSize {
+		return removeSidecars(path)
+	}
+	_, _, err = c.compressFile(path)
+	return err
+}
+
+// removeSidecars removes the sidecars of a file in every format, if any.
+func removeSidecars(path string) error {
+	for _, f := range []Format{Gzip, Brotli} {
+		if err := os.Remove(path + f.Extension()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressFile writes a sidecar for each format, returning the size of every sidecar written.
+// Sidecars that wouldn't be smaller than the original are not written, and stale ones are removed.
+func (c *Compressor) compressFile(path string) (map[Format]int64, int64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sizes := make(map[Format]int64)
+	for _, f := range c.opts.Formats {
+		compressed, err := c.compress(f, content)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to compress %s: %w", path, err)
+		}
+
+		sidecar := path + f.Extension()
+		if len(compressed) >= len(content) {
+			_ = os.Remove(sidecar)
+			continue
+		}
+
+		if err := os.WriteFile(sidecar, compressed, 0644); err != nil {
+			return nil, 0, fmt.Errorf("failed to write %s: %w", sidecar, err)
+		}
+		sizes[f] = int64(len(compressed))
+	}
+
+	return sizes, int64(len(content)), nil
+}
+
+func (c *Compressor) compress(f Format, content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch f {
+	case Gzip:
+		gw, err := gzip.NewWriterLevel(&buf, c.opts.GzipLevel)
+		if err != nil {
+			return nil, err
+		}
+		w = gw
+	case Brotli:
+		w = brotli.NewWriterLevel(&buf, c.opts.BrotliLevel)
+	default:
+		return nil, fmt.Errorf("unknown compression format %q", f)
+	}
+
+	if _, err := w.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
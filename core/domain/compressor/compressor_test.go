@@ -0,0 +1,132 @@
+package compressor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressor_Run(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	css := strings.Repeat("body { color: blue; }\n", 200)
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "style.css"), []byte(css), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "about"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "about", "index.html"), []byte(strings.Repeat("<p>About</p>", 200)), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "tiny.js"), []byte(`console.log(1)`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "logo.png"), bytes.Repeat([]byte{0x89}, 4096), 0644))
+
+	stats, err := New(tmpDir, Options{MinSize: 1024, Workers: 2, GzipLevel: 9, BrotliLevel: 11}).Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, stats.Files)
+	assert.Equal(t, 4, stats.Sidecars)
+
+	// gzip sidecar decompresses to the original
+	gz, err := os.Open(filepath.Join(tmpDir, "style.css.gz"))
+	require.NoError(t, err)
+	defer gz.Close()
+	gr, err := gzip.NewReader(gz)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Equal(t, css, string(content))
+
+	// brotli sidecar decompresses to the original
+	br, err := os.ReadFile(filepath.Join(tmpDir, "style.css.br"))
+	require.NoError(t, err)
+	content, err = io.ReadAll(brotli.NewReader(bytes.NewReader(br)))
+	require.NoError(t, err)
+	assert.Equal(t, css, string(content))
+
+	assert.FileExists(t, filepath.Join(tmpDir, "about", "index.html.br"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "tiny.js.gz"), "files below the size threshold are skipped")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "logo.png.gz"), "non-compressible types are skipped")
+}
+
+func TestCompressor_Formats(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "data.json"), []byte(strings.Repeat(`{"a":1}`, 500)), 0644))
+
+	_, err := New(tmpDir, Options{Formats: []Format{Gzip}, GzipLevel: 9}).Run()
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "data.json.gz"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "data.json.br"))
+
+	_, err = New(tmpDir, Options{Formats: []Format{"zstd"}}).Run()
+	assert.ErrorContains(t, err, "zstd")
+}
+
+func TestCompressor_Run_RemovesStaleSidecars(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "app.js")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("console.log(1);\n", 200)), 0644))
+
+	_, err := New(tmpDir, Options{MinSize: 1024, GzipLevel: 9, BrotliLevel: 11}).Run()
+	require.NoError(t, err)
+	assert.FileExists(t, path+".gz")
+
+	// The file shrinks below the threshold in a later export into the same directory
+	require.NoError(t, os.WriteFile(path, []byte(`console.log(2)`), 0644))
+	_, err = New(tmpDir, Options{MinSize: 1024, GzipLevel: 9, BrotliLevel: 11}).Run()
+	require.NoError(t, err)
+	assert.NoFileExists(t, path+".gz")
+	assert.NoFileExists(t, path+".br")
+}
+
+func TestCompressor_Compress(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "index.html")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("<p>Hello</p>\n", 200)), 0644))
+
+	c := New(tmpDir, Options{MinSize: 1024, GzipLevel: 9, BrotliLevel: 11})
+	require.NoError(t, c.Compress(path))
+	assert.FileExists(t, path+".gz")
+	assert.FileExists(t, path+".br")
+
+	require.NoError(t, os.WriteFile(path, []byte(`<p>Hi</p>`), 0644))
+	require.NoError(t, c.Compress(path))
+	assert.NoFileExists(t, path+".gz")
+	assert.NoFileExists(t, path+".br")
+
+	image := filepath.Join(tmpDir, "logo.png")
+	require.NoError(t, os.WriteFile(image, []byte(strings.Repeat("png", 1024)), 0644))
+	require.NoError(t, c.Compress(image))
+	assert.NoFileExists(t, image+".gz")
+}
+
+func TestCompressor_Levels(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "style.css")
+	var css strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&css, ".c%d { color: #%06x; margin: %dpx }\n", i, i*2654435%0xffffff, i%37)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(css.String()), 0644))
+
+	size := func(level int) int64 {
+		_, err := New(tmpDir, Options{Formats: []Format{Brotli}, BrotliLevel: level}).Run()
+		require.NoError(t, err)
+		info, err := os.Stat(path + ".br")
+		require.NoError(t, err)
+		return info.Size()
+	}
+	// Level 0 is the fastest, not a default for the best compression
+	assert.Greater(t, size(0), size(11))
+
+	// Gzip level 0 stores the content, which is never smaller than the file
+	require.NoError(t, os.Remove(path+".br"))
+	_, err := New(tmpDir, Options{Formats: []Format{Gzip}, GzipLevel: 0}).Run()
+	require.NoError(t, err)
+	assert.NoFileExists(t, path+".gz")
+}
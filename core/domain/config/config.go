@@ -17,29 +17,188 @@ type Pages struct {
 
 type JS struct {
 	Enabled        bool          `toml:"enabled"`
-	Wait           time.Duration `toml:"wait_for"`
+	Wait           Duration      `toml:"wait_for" unit:"ms"`
 	MaxTabs        int           `toml:"max_tabs"`
 	ExecutablePath string        `toml:"executable_path,omitempty"`
 	Flags          []string      `toml:"flags,omitempty"`
+	Device         string        `toml:"device,omitempty"` // "desktop" or "mobile"
+	Interactions   []Interaction `toml:"interactions,omitempty"`
+}
+
+// Step is a single scripted interaction run in the page before its HTML is captured.
+// Exactly one of Scroll, Click or Eval is expected; a step with only Wait just pauses.
+type Step struct {
+	Scroll int      `toml:"scroll,omitempty"`         // scroll to the bottom of the page N times
+	Click  string   `toml:"click,omitempty"`          // CSS selector of the element to click
+	Times  int      `toml:"times,omitempty"`          // how many times to click (default 1)
+	Eval   string   `toml:"eval,omitempty"`           // JavaScript snippet to evaluate
+	Wait   Duration `toml:"wait,omitempty" unit:"ms"` // time to wait after the step
+}
+
+// Interaction is a list of steps to run on pages matching Paths.
+type Interaction struct {
+	Paths url.Paths `toml:"paths"`
+	Steps []Step    `toml:"steps"`
+}
+
+// Snippet is a piece of markup injected into exported pages.
+type Snippet struct {
+	Selector string `toml:"selector"`
+	// Position is one of "append", "prepend", "before", "after" or "replace".
+	Position string `toml:"position"`
+	HTML     string `toml:"html"`
+}
+
+// Transform configures the DOM transforms applied to HTML pages before they are saved.
+type Transform struct {
+	RemoveSelectors  []string  `toml:"remove_selectors"`
+	RemoveAttributes []string  `toml:"remove_attributes"` // glob patterns, e.g. "data-v-*"
+	DedupeScripts    bool      `toml:"dedupe_scripts"`
+	Inject           []Snippet `toml:"inject,omitempty"`
+}
+
+// Compress configures the precompressed sidecar files written after the export.
+type Compress struct {
+	Enabled     bool     `toml:"enabled"`
+	Formats     []string `toml:"formats"` // "gzip" and/or "br"
+	GzipLevel   int      `toml:"gzip_level"`
+	BrotliLevel int      `toml:"brotli_level"`
+	MinSize     int64    `toml:"min_size"` // bytes
+}
+
+// Minify configures the minification of exported files.
+type Minify struct {
+	Enabled bool      `toml:"enabled"`
+	HTML    bool      `toml:"html"`
+	CSS     bool      `toml:"css"`
+	Exclude url.Paths `toml:"exclude"`
+}
+
+// Fingerprint configures the renaming of assets to content-hashed names.
+type Fingerprint struct {
+	Enabled    bool      `toml:"enabled"`
+	Extensions []string  `toml:"extensions,omitempty"` // defaults to common CSS, JS, image and font types
+	Exclude    url.Paths `toml:"exclude"`
+	Manifest   string    `toml:"manifest"`
+}
+
+// Sitemap configures the sitemap.xml generated from the exported pages.
+type Sitemap struct {
+	Enabled bool   `toml:"enabled"`
+	Robots  bool   `toml:"robots"` // also write a robots.txt pointing to the sitemap
+	State   string `toml:"state"`  // remembers content hashes between exports to compute lastmod
+}
+
+// Search configures the static search index written with the export.
+type Search struct {
+	Enabled      bool      `toml:"enabled"`
+	Dir          string    `toml:"dir"`       // relative to the output directory
+	Languages    []string  `toml:"languages"` // en, fr, de or es, the first is the default
+	Content      []string  `toml:"content"`   // selectors of the main content, the first match wins
+	Exclude      []string  `toml:"exclude"`   // selectors removed from the main content
+	ExcludePages url.Paths `toml:"exclude_pages"`
+}
+
+// Diff configures what `bare diff` ignores when comparing two exports.
+type Diff struct {
+	IgnoreSelectors  []string `toml:"ignore_selectors"`  // elements removed from HTML pages, e.g. CSRF token inputs
+	IgnoreAttributes []string `toml:"ignore_attributes"` // attributes removed from HTML elements, e.g. nonce
+	IgnorePatterns   []string `toml:"ignore_patterns"`   // regular expressions masked in every text file, e.g. timestamps
+}
+
+// Lint configures `dalin report`, which shares the crawl settings of the export.
+type Lint struct {
+	Output      string   `toml:"output"`
+	Screenshots bool     `toml:"screenshots"`      // attach screenshot thumbnails, requires js.enabled
+	Device      string   `toml:"device,omitempty"` // "desktop", "mobile" or "both", defaults to js.device
+	Disable     []string `toml:"disable"`          // IDs of the rules to skip
+}
+
+// S3 holds the connection settings of an S3-compatible service, such as AWS or MinIO.
+type S3 struct {
+	Endpoint        string `toml:"endpoint,omitempty"` // defaults to AWS, e.g. http://127.0.0.1:9000 for MinIO
+	Region          string `toml:"region"`
+	PathStyle       bool   `toml:"path_style"`                  // required by MinIO
+	AccessKeyID     string `toml:"access_key_id,omitempty"`     // defaults to $AWS_ACCESS_KEY_ID
+	SecretAccessKey string `toml:"secret_access_key,omitempty"` // defaults to $AWS_SECRET_ACCESS_KEY
+}
+
+// CacheControl sets the Cache-Control header of the uploaded files matching Paths.
+type CacheControl struct {
+	Paths url.Paths `toml:"paths"`
+	Value string    `toml:"value"`
+}
+
+// Deploy configures the upload of the export when the output is an s3:// URL.
+type Deploy struct {
+	S3           S3             `toml:"s3"`
+	Delete       bool           `toml:"delete"` // delete keys that are no longer part of the export
+	CacheControl []CacheControl `toml:"cache_control,omitempty"`
+}
+
+// Server configures the origin server bare starts before the crawl and stops after it.
+type Server struct {
+	Command  string   `toml:"command,omitempty"`   // e.g. "php artisan serve", no server is started if empty
+	Dir      string   `toml:"dir,omitempty"`       // working directory of the command
+	ReadyURL string   `toml:"ready_url,omitempty"` // defaults to url
+	Timeout  Duration `toml:"timeout" unit:"s"`    // how long to wait for ready_url
+}
+
+// Hooks are shell commands run at each step of the export.
+type Hooks struct {
+	PreExport   []string `toml:"pre_export"`
+	PostExport  []string `toml:"post_export"`
+	PostRewrite []string `toml:"post_rewrite"`
+}
+
+// RouteParam is the source of the values of a route placeholder. Exactly one source is set.
+type RouteParam struct {
+	Values []string `toml:"values,omitempty"`
+	Range  []int    `toml:"range,omitempty"` // [from, to] or [from, to, step], inclusive
+	From   string   `toml:"from,omitempty"`  // JSON endpoint on the origin, e.g. "/api/posts.json"
+	Field  string   `toml:"field,omitempty"` // dotted path to the values in the JSON, e.g. "data.slug"
+}
+
+// Route is a templated path, such as "/blog/page/{page}", expanded into entrypoints before the crawl.
+type Route struct {
+	Path   string                `toml:"path"`
+	Params map[string]RouteParam `toml:"params"`
 }
 
 type Config struct {
 	URL          *url.URL `toml:"url"`
+	PublicURL    *url.URL `toml:"public_url,omitempty"` // where the export is deployed, e.g. https://example.com
 	Output       string   `toml:"output"`
 	WorkersCount int      `toml:"workers_count"`
 
-	JS    JS    `toml:"js"`
-	Pages Pages `toml:"pages"`
+	Server      Server      `toml:"server"`
+	Hooks       Hooks       `toml:"hooks"`
+	JS          JS          `toml:"js"`
+	Pages       Pages       `toml:"pages"`
+	Routes      []Route     `toml:"routes,omitempty"`
+	Rules       []Rule      `toml:"rules,omitempty"`
+	Transform   Transform   `toml:"transform"`
+	Sitemap     Sitemap     `toml:"sitemap"`
+	Search      Search      `toml:"search"`
+	Minify      Minify      `toml:"minify"`
+	Fingerprint Fingerprint `toml:"fingerprint"`
+	Compress    Compress    `toml:"compress"`
+	Deploy      Deploy      `toml:"deploy"`
+	Diff        Diff        `toml:"diff"`
+	Lint        Lint        `toml:"lint"`
 }
 
 // IsURLAllowed checks if a URL is allowed based on the exclude rules.
 func (c *Config) IsURLAllowed(u *url.URL) bool {
-	return !c.Pages.Exclude.MatchAny(u.Path)
+	return !c.Pages.Exclude.MatchURL(u)
 }
 
 // IsExtractOnly checks if a URL should only have its links extracted without saving its content.
 func (c *Config) IsExtractOnly(u *url.URL) bool {
-	return c.Pages.ExtractOnly.MatchAny(u.Path)
+	if save := c.RuleFor(u).Save; save != "" {
+		return save == "extract_only"
+	}
+	return c.Pages.ExtractOnly.MatchURL(u)
 }
 
 func (c Config) Export() ([]byte, error) {
@@ -57,9 +216,17 @@ func NewDefaultConfig() *Config {
 		URL:          defaultURL,
 		Output:       "dist/",
 		WorkersCount: 10,
+		Server: Server{
+			Timeout: Duration(30 * time.Second),
+		},
+		Hooks: Hooks{
+			PreExport:   []string{},
+			PostExport:  []string{},
+			PostRewrite: []string{},
+		},
 		JS: JS{
 			Enabled:        false,
-			Wait:           2000,
+			Wait:           Duration(2 * time.Second),
 			MaxTabs:        1,
 			ExecutablePath: "",
 			Flags:          []string{},
@@ -69,28 +236,71 @@ func NewDefaultConfig() *Config {
 			ExtractOnly: url.Paths{},
 			Exclude:     url.Paths{},
 		},
+		Transform: Transform{
+			RemoveSelectors:  []string{},
+			RemoveAttributes: []string{},
+			DedupeScripts:    false,
+		},
+		Sitemap: Sitemap{
+			Enabled: false,
+			Robots:  false,
+			State:   ".bare/sitemap.json",
+		},
+		Search: Search{
+			Enabled:      false,
+			Dir:          "_search",
+			Languages:    []string{"en"},
+			Content:      []string{"main", "article", `[role="main"]`},
+			Exclude:      []string{"nav", "header", "footer", "aside", `[role="navigation"]`, "[data-search-ignore]"},
+			ExcludePages: url.Paths{},
+		},
+		Minify: Minify{
+			Enabled: false,
+			HTML:    true,
+			CSS:     true,
+			Exclude: url.Paths{},
+		},
+		Compress: Compress{
+			Enabled:     false,
+			Formats:     []string{"gzip", "br"},
+			GzipLevel:   9,
+			BrotliLevel: 11,
+			MinSize:     1024,
+		},
+		Fingerprint: Fingerprint{
+			Enabled:  false,
+			Exclude:  url.Paths{"/favicon.ico", "/robots.txt", "/sitemap.xml"},
+			Manifest: "manifest.json",
+		},
+		Deploy: Deploy{
+			S3: S3{
+				Region: "us-east-1",
+			},
+			Delete: true,
+		},
+		Diff: Diff{
+			IgnoreSelectors: []string{
+				`meta[name="csrf-token"]`,
+				`meta[name="csrf-param"]`,
+				`input[name="_token"]`,
+				`input[name="csrf_token"]`,
+				`input[name="authenticity_token"]`,
+				`input[name="csrfmiddlewaretoken"]`,
+			},
+			IgnoreAttributes: []string{"nonce"},
+			IgnorePatterns: []string{
+				`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+			},
+		},
+		Lint: Lint{
+			Output:  "report.html",
+			Disable: []string{},
+		},
 	}
 }
 
+// Get reads bare.toml, with the profile selected by BARE_PROFILE, if set.
 func Get() (*Config, error) {
-	contents, err := os.ReadFile("bare.toml")
-	if err != nil {
-		return nil, fmt.Errorf("could not read bare.toml: %w", err)
-	}
-
-	var c Config
-	err = toml.Unmarshal(contents, &c)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse bare.toml: %w", err)
-	}
-
-	if c.WorkersCount == 0 {
-		c.WorkersCount = 10
-	}
-
-	if len(c.Pages.Entrypoints) == 0 {
-		c.Pages.Entrypoints = []url.Path{"/"}
-	}
-
-	return &c, nil
+	c, _, err := Load(DefaultFile, os.Getenv(ProfileEnv))
+	return c, err
 }
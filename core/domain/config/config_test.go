@@ -3,7 +3,10 @@ package config
 import (
 	"testing"
 
+	"github.com/felixdorn/bare/core/domain/differ"
+	"github.com/felixdorn/bare/core/domain/search"
 	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/pelletier/go-toml/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -61,6 +64,28 @@ func TestConfig_IsURLAllowed(t *testing.T) {
 			urlPath:  "/",
 			expected: false,
 		},
+		{
+			name: "query excluded",
+			config: &Config{
+				URL: baseURL,
+				Pages: Pages{
+					Exclude: url.Paths{"/search?q"},
+				},
+			},
+			urlPath:  "/search?q=bare",
+			expected: false,
+		},
+		{
+			name: "negation keeps a page",
+			config: &Config{
+				URL: baseURL,
+				Pages: Pages{
+					Exclude: url.Paths{"/private/**", "!/private/press-kit"},
+				},
+			},
+			urlPath:  "/private/press-kit",
+			expected: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -72,3 +97,40 @@ func TestConfig_IsURLAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_Routes(t *testing.T) {
+	var c Config
+	err := toml.Unmarshal([]byte(`
+[[routes]]
+path = '/{lang}/blog/page/{page}'
+params.lang = { values = ['en', 'fr'] }
+params.page = { range = [1, 20] }
+
+[[routes]]
+path = '/products/{slug}'
+params.slug = { from = '/api/products.json', field = 'data.slug' }
+`), &c)
+	require.NoError(t, err)
+
+	require.Len(t, c.Routes, 2)
+	assert.Equal(t, []string{"en", "fr"}, c.Routes[0].Params["lang"].Values)
+	assert.Equal(t, []int{1, 20}, c.Routes[0].Params["page"].Range)
+	assert.Equal(t, RouteParam{From: "/api/products.json", Field: "data.slug"}, c.Routes[1].Params["slug"])
+}
+
+// The defaults are written out here so that config doesn't depend on the packages using them.
+func TestNewDefaultConfig_MatchesDomainDefaults(t *testing.T) {
+	c := NewDefaultConfig()
+
+	assert.Equal(t, search.DefaultMain, c.Search.Content)
+	assert.Equal(t, search.DefaultExclude, c.Search.Exclude)
+	assert.Equal(t, differ.DefaultIgnoreSelectors, c.Diff.IgnoreSelectors)
+	assert.Equal(t, differ.DefaultIgnoreAttributes, c.Diff.IgnoreAttributes)
+	assert.Equal(t, differ.DefaultIgnorePatterns, c.Diff.IgnorePatterns)
+
+	languages := make([]string, 0, len(search.Languages))
+	for code := range search.Languages {
+		languages = append(languages, code)
+	}
+	assert.ElementsMatch(t, languages, enums["search.languages"])
+}
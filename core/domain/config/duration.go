@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration is a duration written like "2s" or "500ms" in the config. For backward
+// compatibility, integers are accepted too, in the unit of the field's `unit` tag.
+type Duration time.Duration
+
+// UnmarshalText parses a duration such as "1m30s".
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, use e.g. \"2s\" or \"500ms\"", text)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText formats the duration, e.g. "2s".
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// units are the units of integer durations, by `unit` tag.
+var units = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
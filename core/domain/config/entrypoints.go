@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// AddEntrypoints appends paths to pages.entrypoints in a config file, and returns the
+// ones that were not listed yet. Only the entrypoints value is rewritten, the rest of
+// the file, comments included, is kept as is.
+func AddEntrypoints(file string, paths []string) ([]string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", file, err)
+	}
+
+	// Only the entrypoints are decoded: other values may only be valid once interpolated
+	var doc struct {
+		Pages struct {
+			Entrypoints url.Paths `toml:"entrypoints"`
+		} `toml:"pages"`
+	}
+	if err := toml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", file, err)
+	}
+	entrypoints := doc.Pages.Entrypoints
+	if len(entrypoints) == 0 {
+		entrypoints = url.Paths{"/"} // the default, which must keep being crawled
+	}
+
+	var added []string
+	for _, p := range paths {
+		listed := false
+		for _, e := range entrypoints {
+			if string(e) == p {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			entrypoints = append(entrypoints, url.Path(p))
+			added = append(added, p)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	quoted := make([]string, len(entrypoints))
+	for i, e := range entrypoints {
+		quoted[i] = quote(string(e))
+	}
+	value := "[" + strings.Join(quoted, ", ") + "]"
+
+	content = setKey(content, "pages", "entrypoints", value)
+	if err := os.WriteFile(file, content, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("could not write %s: %w", file, err)
+	}
+	return added, nil
+}
+
+// setKey replaces the array value of a key of a table, wherever the file defines it:
+// under the table's header, as a dotted key or in an inline table. The key, or the
+// table, is added if it is missing.
+func setKey(content []byte, table, key, value string) []byte {
+	replace := func(start int) []byte {
+		end := arrayEnd(content, start)
+		return []byte(string(content[:start]) + value + string(content[end:]))
+	}
+	insert := func(offset int, text string) []byte {
+		return []byte(string(content[:offset]) + text + string(content[offset:]))
+	}
+
+	var p unstable.Parser
+	p.Reset(content)
+	current := ""
+	header, dotted := -1, -1
+	for p.NextExpression() {
+		expr := p.Expression()
+		if expr.Kind != unstable.Table && expr.Kind != unstable.ArrayTable && expr.Kind != unstable.KeyValue {
+			continue
+		}
+
+		name, first, last := keyOf(expr)
+		if first == nil {
+			continue
+		}
+		if expr.Kind != unstable.KeyValue {
+			current = name
+			if name == table && header < 0 {
+				header = lineEnd(content, int(first.Raw.Offset))
+			}
+			continue
+		}
+		if current != "" {
+			name = current + "." + name
+		}
+
+		switch {
+		case name == table+"."+key:
+			return replace(valueStart(content, last))
+		case name == table && expr.Value().Kind == unstable.InlineTable:
+			inline := expr.Value()
+			empty := true
+			for it := inline.Children(); it.Next(); {
+				empty = false
+				if child, _, childLast := keyOf(it.Node()); child == key {
+					return replace(valueStart(content, childLast))
+				}
+			}
+			// Raw is the opening brace of the inline table
+			text := " " + key + " = " + value + ","
+			if empty {
+				text = " " + key + " = " + value + " "
+			}
+			return insert(int(inline.Raw.Offset)+1, text)
+		case current == "" && strings.HasPrefix(name, table+".") && dotted < 0:
+			dotted = lineStart(content, int(first.Raw.Offset))
+		}
+	}
+
+	switch {
+	case header >= 0:
+		return insert(header, key+" = "+value+"\n")
+	case dotted >= 0:
+		// The table is defined by dotted keys, a header would define it twice
+		return insert(dotted, table+"."+key+" = "+value+"\n")
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+	return append(content, "\n["+table+"]\n"+key+" = "+value+"\n"...)
+}
+
+// keyOf returns the dotted key of a table or key-value node, with its first and last parts.
+func keyOf(n *unstable.Node) (name string, first, last *unstable.Node) {
+	var parts []string
+	for it := n.Key(); it.Next(); {
+		if first == nil {
+			first = it.Node()
+		}
+		last = it.Node()
+		parts = append(parts, string(it.Node().Data))
+	}
+	return strings.Join(parts, "."), first, last
+}
+
+// valueStart returns the offset of the value following the last part of a key.
+func valueStart(content []byte, last *unstable.Node) int {
+	i := int(last.Raw.Offset + last.Raw.Length)
+	i += bytes.IndexByte(content[i:], '=') + 1
+	for i < len(content) && (content[i] == ' ' || content[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// arrayEnd returns the offset just after the array starting at content[start],
+// skipping the strings and comments it contains.
+func arrayEnd(content []byte, start int) int {
+	s := string(content)
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\'':
+			delim := s[i : i+1]
+			if strings.HasPrefix(s[i:], strings.Repeat(delim, 3)) {
+				delim = strings.Repeat(delim, 3)
+			}
+			i += len(delim)
+			for i < len(s) && !strings.HasPrefix(s[i:], delim) {
+				// Only basic strings have escapes
+				if c == '"' && s[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i += len(delim) - 1
+		case '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// lineStart returns the offset of the start of the line containing offset.
+func lineStart(content []byte, offset int) int {
+	return bytes.LastIndexByte(content[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset of the start of the line following offset.
+func lineEnd(content []byte, offset int) int {
+	if i := bytes.IndexByte(content[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(content)
+}
+
+// quote formats a string as a TOML literal string, or a basic string if it contains a quote.
+func quote(s string) string {
+	if strings.ContainsAny(s, "'\n") {
+		return strconv.Quote(s)
+	}
+	return "'" + s + "'"
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddEntrypoints(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   string
+		paths    []string
+		added    []string
+		expected string
+	}{
+		{
+			name: "appends to the list",
+			config: `url = "http://localhost:8000" # the origin
+
+[pages]
+entrypoints = ['/', '/hidden'] # pages without links
+exclude = []
+`,
+			paths: []string{"/hidden", "/img/logo.png"},
+			added: []string{"/img/logo.png"},
+			expected: `url = "http://localhost:8000" # the origin
+
+[pages]
+entrypoints = ['/', '/hidden', '/img/logo.png'] # pages without links
+exclude = []
+`,
+		},
+		{
+			name: "ignores values only valid once interpolated",
+			config: `url = "${ORIGIN:-http://localhost:8000}"
+workers_count = "${WORKERS}"
+
+[pages]
+entrypoints = ['/']
+`,
+			paths: []string{"/a"},
+			added: []string{"/a"},
+			expected: `url = "${ORIGIN:-http://localhost:8000}"
+workers_count = "${WORKERS}"
+
+[pages]
+entrypoints = ['/', '/a']
+`,
+		},
+		{
+			name: "replaces a multiline list",
+			config: `[pages]
+entrypoints = [
+  '/', # home
+  "/a[1]",
+]
+
+[js]
+enabled = false
+`,
+			paths: []string{"/b"},
+			added: []string{"/b"},
+			expected: `[pages]
+entrypoints = ['/', '/a[1]', '/b']
+
+[js]
+enabled = false
+`,
+		},
+		{
+			name: "dotted key",
+			config: `url = "http://localhost:8000"
+pages.entrypoints = ["/a\"]", # escaped quote
+  '/b']
+`,
+			paths: []string{"/c"},
+			added: []string{"/c"},
+			expected: `url = "http://localhost:8000"
+pages.entrypoints = ['/a"]', '/b', '/c']
+`,
+		},
+		{
+			name: "adds a dotted key",
+			config: `url = "http://localhost:8000"
+pages.exclude = ['/admin']
+`,
+			paths: []string{"/c"},
+			added: []string{"/c"},
+			expected: `url = "http://localhost:8000"
+pages.entrypoints = ['/', '/c']
+pages.exclude = ['/admin']
+`,
+		},
+		{
+			name:     "inline table",
+			config:   "pages = { exclude = [], entrypoints = ['/'] }\n",
+			paths:    []string{"/c"},
+			added:    []string{"/c"},
+			expected: "pages = { exclude = [], entrypoints = ['/', '/c'] }\n",
+		},
+		{
+			name:     "adds the key to an inline table",
+			config:   "pages = { exclude = [] }\n",
+			paths:    []string{"/c"},
+			added:    []string{"/c"},
+			expected: "pages = { entrypoints = ['/', '/c'], exclude = [] }\n",
+		},
+		{
+			name: "adds the key with the default entrypoint",
+			config: `[pages.extra]
+x = 1
+
+[pages]
+exclude = []
+`,
+			paths: []string{"/b"},
+			added: []string{"/b"},
+			expected: `[pages.extra]
+x = 1
+
+[pages]
+entrypoints = ['/', '/b']
+exclude = []
+`,
+		},
+		{
+			name:   "adds the table",
+			config: `url = "http://localhost:8000"`,
+			paths:  []string{"/b"},
+			added:  []string{"/b"},
+			expected: `url = "http://localhost:8000"
+
+[pages]
+entrypoints = ['/', '/b']
+`,
+		},
+		{
+			name:     "leaves the file untouched without new paths",
+			config:   "[pages]\nentrypoints = [ '/' ]\n",
+			paths:    []string{"/"},
+			expected: "[pages]\nentrypoints = [ '/' ]\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "bare.toml")
+			require.NoError(t, os.WriteFile(file, []byte(tc.config), 0644))
+
+			added, err := AddEntrypoints(file, tc.paths)
+			require.NoError(t, err)
+			assert.Equal(t, tc.added, added)
+
+			content, err := os.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(content))
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	// DefaultFile is the config file read when none is given.
+	DefaultFile = "bare.toml"
+	// ProfileEnv selects a profile when none is given.
+	ProfileEnv = "BARE_PROFILE"
+)
+
+// configType is the type config files are decoded in.
+var configType = reflect.TypeOf(Config{})
+
+// Sources maps dotted keys, such as "pages.entrypoints", to where their value comes from.
+type Sources map[string]string
+
+// Of returns the source of a key, or of the closest table or array of tables containing
+// it. Keys set nowhere come from "default".
+func (s Sources) Of(key string) string {
+	for key != "" {
+		if source, ok := s[key]; ok {
+			return source
+		}
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	return "default"
+}
+
+// Load reads a config file, applies the [profile.<name>] overlay of profile, if set,
+// then replaces ${VAR} and ${VAR:-default} in strings with environment variables,
+// except in the strings holding code, see verbatim.
+// It returns the config and where each value comes from.
+// Unknown keys, values of the wrong type and invalid values are returned as Errors,
+// with their line and column in the file.
+func Load(file, profile string) (*Config, Sources, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read %s: %w", file, err)
+	}
+
+	var doc map[string]any
+	if err := toml.Unmarshal(contents, &doc); err != nil {
+		return nil, nil, decodeError(file, err)
+	}
+
+	ps := keyPositions(contents)
+	var errs Errors
+	report := func(key, message string) {
+		pos := ps.of(key)
+		errs = append(errs, Error{File: file, Line: pos.line, Column: pos.column, Key: key, Message: message})
+	}
+	check := func(table map[string]any, prefix string) {
+		_, keyErrs := checkKeys(table, configType, prefix, "")
+		for _, e := range keyErrs {
+			report(e.key, e.message)
+		}
+	}
+
+	profiles, _ := doc["profile"].(map[string]any)
+	if _, ok := doc["profile"]; ok && profiles == nil {
+		report("profile", "expected a table of profiles, e.g. [profile.ci]")
+	}
+	delete(doc, "profile")
+	check(doc, "")
+	for name, overlay := range profiles {
+		if table, ok := overlay.(map[string]any); ok {
+			check(table, "profile."+name)
+		} else {
+			report("profile."+name, "expected a table, got "+describe(overlay))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs.sorted()
+	}
+
+	sources := make(Sources)
+	merge(nil, doc, "", sources, file)
+
+	profileSource := ""
+	if profile != "" {
+		overlay, ok := profiles[profile].(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("no profile %q in %s, available profiles: %s", profile, file, profileNames(profiles))
+		}
+		profileSource = fmt.Sprintf("%s [profile.%s]", file, profile)
+		merge(doc, overlay, "", sources, profileSource)
+	}
+
+	// Values set by the profile are reported at their position in the profile
+	reportValue := func(key, message string) {
+		if profileSource != "" && strings.HasPrefix(sources.Of(key), profileSource) {
+			key = "profile." + profile + "." + key
+		}
+		report(key, message)
+	}
+
+	if err := interpolate(doc, "", sources); err != nil {
+		var keyErr keyError
+		if errors.As(err, &keyErr) {
+			reportValue(keyErr.key, keyErr.message)
+			return nil, nil, errs
+		}
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	merged, err := toml.Marshal(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not merge %s: %w", file, err)
+	}
+	var c Config
+	if err := toml.Unmarshal(merged, &c); err != nil {
+		// Values checked above can only be invalid once environment variables are replaced
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			reportValue(strings.Join(decodeErr.Key(), "."), strings.TrimPrefix(decodeErr.Error(), "toml: "))
+			return nil, nil, errs
+		}
+		return nil, nil, fmt.Errorf("could not parse %s: %w", file, err)
+	}
+
+	for _, e := range c.Validate() {
+		reportValue(e.Key, e.Message)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs.sorted()
+	}
+
+	if c.WorkersCount == 0 {
+		c.WorkersCount = 10
+	}
+
+	if len(c.Pages.Entrypoints) == 0 {
+		c.Pages.Entrypoints = []url.Path{"/"}
+	}
+
+	if c.Lint.Output == "" {
+		c.Lint.Output = "report.html"
+	}
+
+	return &c, sources, nil
+}
+
+// merge copies the values of src into dst, merging tables and replacing other values,
+// and records source for every value copied. With a nil dst, it only records sources.
+func merge(dst, src map[string]any, prefix string, sources Sources, source string) {
+	for key, value := range src {
+		dotted := prefix + key
+		if table, ok := value.(map[string]any); ok {
+			if dst == nil {
+				merge(nil, table, dotted+".", sources, source)
+				continue
+			}
+			if existing, ok := dst[key].(map[string]any); ok {
+				merge(existing, table, dotted+".", sources, source)
+				continue
+			}
+			clearSources(sources, dotted)
+			dst[key] = table
+			merge(nil, table, dotted+".", sources, source)
+			continue
+		}
+
+		if dst != nil {
+			clearSources(sources, dotted)
+			dst[key] = value
+		}
+		sources[dotted] = source
+	}
+}
+
+// clearSources forgets the sources of a key and of the keys it contains, before it is replaced.
+func clearSources(sources Sources, key string) {
+	for k := range sources {
+		if k == key || strings.HasPrefix(k, key+".") {
+			delete(sources, k)
+		}
+	}
+}
+
+// envPattern matches $$, ${VAR} and ${VAR:-default}.
+var envPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// verbatim are the keys holding code, never interpolated: ${...} is a template literal in
+// JavaScript, and the shell expands the variables of commands itself, BARE_* included.
+var verbatim = map[string]bool{
+	"transform.inject.html":      true,
+	"js.interactions.steps.eval": true,
+	"server.command":             true,
+	"hooks.pre_export":           true,
+	"hooks.post_export":          true,
+	"hooks.post_rewrite":         true,
+}
+
+// interpolate replaces environment variables in the strings of a document, except in
+// verbatim keys, and adds the variables used to the sources of the values.
+func interpolate(doc map[string]any, prefix string, sources Sources) error {
+	for key, value := range doc {
+		dotted := prefix + key
+		if table, ok := value.(map[string]any); ok {
+			if err := interpolate(table, dotted+".", sources); err != nil {
+				return err
+			}
+			continue
+		}
+
+		replaced, vars, err := interpolateValue(value, dotted)
+		if err != nil {
+			return keyError{dotted, err.Error()}
+		}
+		doc[key] = replaced
+		if len(vars) > 0 {
+			sources[dotted] = sources.Of(dotted) + ", " + strings.Join(vars, ", ")
+		}
+	}
+	return nil
+}
+
+// interpolateValue replaces environment variables in a string, or in the strings of an
+// array or a table, and returns the variables used. key is the dotted key of the value,
+// the elements of arrays share the key of the array.
+func interpolateValue(value any, key string) (any, []string, error) {
+	if verbatim[key] {
+		return value, nil, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return interpolateString(v)
+	case []any:
+		var vars []string
+		for i, item := range v {
+			replaced, used, err := interpolateValue(item, key)
+			if err != nil {
+				return nil, nil, err
+			}
+			v[i] = replaced
+			vars = append(vars, used...)
+		}
+		return v, vars, nil
+	case map[string]any:
+		var vars []string
+		for k, item := range v {
+			replaced, used, err := interpolateValue(item, key+"."+k)
+			if err != nil {
+				return nil, nil, err
+			}
+			v[k] = replaced
+			vars = append(vars, used...)
+		}
+		return v, vars, nil
+	}
+	return value, nil, nil
+}
+
+func interpolateString(s string) (string, []string, error) {
+	var vars []string
+	var err error
+	replaced := envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		groups := envPattern.FindStringSubmatch(match)
+		name, hasDefault, fallback := groups[1], groups[2] != "", groups[3]
+		vars = append(vars, "${"+name+"}")
+
+		value, set := os.LookupEnv(name)
+		if (!set || value == "") && hasDefault {
+			return fallback
+		}
+		if !set && err == nil {
+			err = fmt.Errorf("environment variable %s is not set, use ${%s:-default} for a default", name, name)
+		}
+		return value
+	})
+	return replaced, vars, err
+}
+
+// profileNames lists the profiles of a config file, for error messages.
+func profileNames(profiles map[string]any) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profilesConfig = `url = "${ORIGIN:-http://127.0.0.1:8000}"
+output = "dist/"
+
+[pages]
+entrypoints = ["/", "/hidden"]
+exclude = ["/admin/**"]
+
+[sitemap]
+enabled = false
+
+[profile.ci]
+output = "${CI_OUTPUT}"
+public_url = "https://preview-${PR_NUMBER:-0}.example.com"
+
+[profile.ci.pages]
+entrypoints = ["/"]
+
+[profile.ci.sitemap]
+enabled = true
+`
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "site.toml")
+	require.NoError(t, os.WriteFile(file, []byte(profilesConfig), 0644))
+
+	t.Setenv("ORIGIN", "")
+	c, sources, err := Load(file, "")
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8000", c.URL.String())
+	assert.Equal(t, "dist/", c.Output)
+	assert.Equal(t, url.Paths{"/", "/hidden"}, c.Pages.Entrypoints)
+	assert.Nil(t, c.PublicURL)
+	assert.Equal(t, file+", ${ORIGIN}", sources.Of("url"))
+	assert.Equal(t, file, sources.Of("pages.entrypoints"))
+	assert.Equal(t, "default", sources.Of("js.enabled"))
+	assert.Equal(t, "report.html", c.Lint.Output)
+
+	t.Setenv("ORIGIN", "http://app.test")
+	t.Setenv("CI_OUTPUT", "build/")
+	t.Setenv("PR_NUMBER", "42")
+	c, sources, err = Load(file, "ci")
+	require.NoError(t, err)
+	assert.Equal(t, "http://app.test", c.URL.String())
+	assert.Equal(t, "build/", c.Output)
+	assert.Equal(t, "https://preview-42.example.com", c.PublicURL.String())
+	assert.Equal(t, url.Paths{"/"}, c.Pages.Entrypoints)
+	assert.Equal(t, url.Paths{"/admin/**"}, c.Pages.Exclude) // tables are merged
+	assert.True(t, c.Sitemap.Enabled)
+
+	profile := file + " [profile.ci]"
+	assert.Equal(t, profile+", ${CI_OUTPUT}", sources.Of("output"))
+	assert.Equal(t, profile, sources.Of("pages.entrypoints"))
+	assert.Equal(t, file, sources.Of("pages.exclude"))
+	assert.Equal(t, profile, sources.Of("sitemap.enabled"))
+}
+
+func TestLoad_Verbatim(t *testing.T) {
+	// Code keeps its ${...}: JavaScript template literals, and variables the shell expands
+	eval := "const title = document.title; console.log(`${title}`)"
+	html := "<script>el.textContent = `${count} items`</script>"
+	hook := "du -sh ${BARE_OUTPUT_DIR}"
+
+	file := filepath.Join(t.TempDir(), "site.toml")
+	require.NoError(t, os.WriteFile(file, []byte(`url = "${ORIGIN}"
+
+[[js.interactions]]
+paths = ["/**"]
+steps = [{ eval = '`+eval+`' }]
+
+[[transform.inject]]
+selector = "body"
+position = "append"
+html = '`+html+`'
+
+[hooks]
+post_export = ['`+hook+`']
+`), 0644))
+
+	t.Setenv("ORIGIN", "http://app.test")
+	c, sources, err := Load(file, "")
+	require.NoError(t, err)
+	assert.Equal(t, "http://app.test", c.URL.String())
+	assert.Equal(t, eval, c.JS.Interactions[0].Steps[0].Eval)
+	assert.Equal(t, html, c.Transform.Inject[0].HTML)
+	assert.Equal(t, []string{hook}, c.Hooks.PostExport)
+	assert.Equal(t, file, sources.Of("hooks.post_export"))
+}
+
+func TestLoad_Errors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "site.toml")
+	require.NoError(t, os.WriteFile(file, []byte(profilesConfig), 0644))
+
+	_, _, err := Load(file, "staging")
+	assert.EqualError(t, err, `no profile "staging" in `+file+`, available profiles: ci`)
+
+	_, _, err = Load(file, "ci")
+	assert.EqualError(t, err, file+":12:1: profile.ci.output: environment variable CI_OUTPUT is not set, use ${CI_OUTPUT:-default} for a default")
+
+	_, _, err = Load(filepath.Join(t.TempDir(), "missing.toml"), "")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestInterpolateString(t *testing.T) {
+	t.Setenv("NAME", "bare")
+	t.Setenv("EMPTY", "")
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"${NAME}", "bare"},
+		{"hello ${NAME}!", "hello bare!"},
+		{"${EMPTY:-fallback}", "fallback"},
+		{"${UNSET_FOR_TEST:-http://localhost:8000}", "http://localhost:8000"},
+		{"$${NAME} costs $5", "${NAME} costs $5"},
+		{"no variables", "no variables"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, _, err := interpolateString(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
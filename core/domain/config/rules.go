@@ -0,0 +1,140 @@
+package config
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// Rule overrides settings for the URLs matching Paths. When several rules match a URL,
+// the later ones take precedence.
+type Rule struct {
+	Paths        url.Paths         `toml:"paths"`
+	JS           *bool             `toml:"js,omitempty"` // render with Chrome, or fetch over HTTP
+	Wait         *Duration         `toml:"wait_for,omitempty" unit:"ms"`
+	WaitSelector string            `toml:"wait_for_selector,omitempty"` // element to wait for before wait_for, with JS
+	Headers      map[string]string `toml:"headers,omitempty"`           // request headers
+	Save         string            `toml:"save,omitempty"`              // "page", or "extract_only" to only follow links
+	Minify       *bool             `toml:"minify,omitempty"`
+	LintDisable  []string          `toml:"lint_disable,omitempty"` // IDs of the dalin rules to skip
+	LintEnable   []string          `toml:"lint_enable,omitempty"`  // IDs of the dalin rules disabled in [lint] to run again
+}
+
+// RuleFor merges the rules matching a URL, in order. Its Paths are empty.
+func (c *Config) RuleFor(u *url.URL) Rule {
+	return c.mergeRules(func(ps url.Paths) bool { return ps.MatchURL(u) })
+}
+
+// RuleForPath merges the rules matching a path, such as the path of an exported file.
+func (c *Config) RuleForPath(p string) Rule {
+	return c.mergeRules(func(ps url.Paths) bool { return ps.MatchAny(p) })
+}
+
+func (c *Config) mergeRules(matches func(url.Paths) bool) Rule {
+	var merged Rule
+	for _, r := range c.Rules {
+		if !matches(r.Paths) {
+			continue
+		}
+		if r.JS != nil {
+			merged.JS = r.JS
+		}
+		if r.Wait != nil {
+			merged.Wait = r.Wait
+		}
+		if r.WaitSelector != "" {
+			merged.WaitSelector = r.WaitSelector
+		}
+		for name, value := range r.Headers {
+			if merged.Headers == nil {
+				merged.Headers = make(map[string]string)
+			}
+			merged.Headers[name] = value
+		}
+		if r.Save != "" {
+			merged.Save = r.Save
+		}
+		if r.Minify != nil {
+			merged.Minify = r.Minify
+		}
+		// A rule enabling a lint undoes an earlier rule disabling it, and the other way around
+		for _, id := range r.LintDisable {
+			merged.LintEnable = slices.DeleteFunc(merged.LintEnable, func(e string) bool { return e == id })
+			merged.LintDisable = append(merged.LintDisable, id)
+		}
+		for _, id := range r.LintEnable {
+			merged.LintDisable = slices.DeleteFunc(merged.LintDisable, func(d string) bool { return d == id })
+			merged.LintEnable = append(merged.LintEnable, id)
+		}
+	}
+	return merged
+}
+
+// UsesJS reports whether any URL is rendered with Chrome, globally or by a rule.
+func (c *Config) UsesJS() bool {
+	if c.JS.Enabled {
+		return true
+	}
+	for _, r := range c.Rules {
+		if r.JS != nil && *r.JS {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch is how a single URL is fetched, from the rules matching it.
+type Fetch struct {
+	Header       http.Header   // request headers added to the fetch
+	Wait         time.Duration // time to wait for JS execution, 0 keeps js.wait_for
+	WaitSelector string        // CSS selector of an element to wait for before the wait, JS only
+}
+
+// FetchFor returns whether a URL is rendered with Chrome, and how it is fetched.
+func (c *Config) FetchFor(u *url.URL) (bool, Fetch) {
+	rule := c.RuleFor(u)
+
+	js := c.JS.Enabled
+	if rule.JS != nil {
+		js = *rule.JS
+	}
+
+	opts := Fetch{WaitSelector: rule.WaitSelector}
+	if rule.Wait != nil {
+		opts.Wait = time.Duration(*rule.Wait)
+	}
+	if len(rule.Headers) > 0 {
+		opts.Header = make(http.Header)
+		for name, value := range rule.Headers {
+			opts.Header.Set(name, value)
+		}
+	}
+	return js, opts
+}
+
+// Minifies reports whether the exported file or page at a path is minified.
+func (c *Config) Minifies(p string) bool {
+	if m := c.RuleForPath(p).Minify; m != nil {
+		return *m
+	}
+	return !c.Minify.Exclude.MatchAny(p)
+}
+
+// LintDisabled returns the IDs of the dalin rules skipped on a URL: the ones disabled
+// in [lint] and by the matching rules, but not enabled again by them.
+func (c *Config) LintDisabled(u *url.URL) map[string]bool {
+	rule := c.RuleFor(u)
+	disabled := make(map[string]bool)
+	for _, id := range c.Lint.Disable {
+		disabled[id] = true
+	}
+	for _, id := range rule.LintDisable {
+		disabled[id] = true
+	}
+	for _, id := range rule.LintEnable {
+		delete(disabled, id)
+	}
+	return disabled
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rulesConfig = `url = "http://127.0.0.1:8000"
+
+[js]
+enabled = false
+
+[pages]
+extract_only = ["/blog"]
+
+[minify]
+exclude = ["/legacy/**"]
+
+[lint]
+disable = ["tracking-parameters"]
+
+[[rules]]
+paths = ["/app/**"]
+js = true
+wait_for = 5000
+wait_for_selector = "#root"
+lint_disable = ["non-ascii-url"]
+
+[[rules]]
+paths = ["/app/admin/**", "/blog"]
+headers = { Authorization = "Bearer preview" }
+save = "page"
+minify = false
+lint_enable = ["tracking-parameters", "non-ascii-url"]
+
+[[rules]]
+paths = ["/legacy/new/**"]
+minify = true
+`
+
+func TestConfig_Rules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bare.toml")
+	require.NoError(t, os.WriteFile(file, []byte(rulesConfig), 0644))
+	c, _, err := Load(file, "")
+	require.NoError(t, err)
+
+	parse := func(p string) *url.URL {
+		u, err := url.Parse("http://127.0.0.1:8000" + p)
+		require.NoError(t, err)
+		return u
+	}
+
+	js, opts := c.FetchFor(parse("/about"))
+	assert.False(t, js)
+	assert.Empty(t, opts.Header)
+
+	js, opts = c.FetchFor(parse("/app/dashboard"))
+	assert.True(t, js)
+	assert.Equal(t, 5*time.Second, opts.Wait) // integers are milliseconds
+	assert.Equal(t, "#root", opts.WaitSelector)
+	assert.Empty(t, opts.Header)
+
+	js, opts = c.FetchFor(parse("/app/admin/users"))
+	assert.True(t, js, "rules are merged")
+	assert.Equal(t, "Bearer preview", opts.Header.Get("Authorization"))
+	assert.True(t, c.UsesJS())
+
+	assert.False(t, c.IsExtractOnly(parse("/blog")), "rules override pages.extract_only")
+	assert.False(t, c.IsExtractOnly(parse("/app")))
+
+	assert.True(t, c.Minifies("/index.html"))
+	assert.False(t, c.Minifies("/app/admin/index.html"))
+	assert.False(t, c.Minifies("/legacy/index.html"))
+	assert.True(t, c.Minifies("/legacy/new/index.html"))
+
+	assert.Equal(t, map[string]bool{"tracking-parameters": true}, c.LintDisabled(parse("/about")))
+	assert.Equal(t, map[string]bool{"tracking-parameters": true, "non-ascii-url": true}, c.LintDisabled(parse("/app/dashboard")))
+	assert.Empty(t, c.LintDisabled(parse("/app/admin/users")))
+}
+
+func TestConfig_Rules_Validation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bare.toml")
+	require.NoError(t, os.WriteFile(file, []byte(`[[rules]]
+js = true
+
+[[rules]]
+paths = ["/app/**"]
+save = "never"
+wait_for_selector = "#"
+`), 0644))
+
+	_, _, err := Load(file, "")
+	var errs Errors
+	require.ErrorAs(t, err, &errs)
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Key + ": " + e.Message
+	}
+	assert.ElementsMatch(t, []string{
+		"rules.paths: every rule needs paths to apply to",
+		`rules.save: unknown value "never", use page, extract_only`,
+		`rules.wait_for_selector: invalid selector "#": expected name, found EOF instead`,
+	}, messages)
+}
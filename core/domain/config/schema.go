@@ -0,0 +1,112 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaFile is the name of the JSON Schema shipped with bare, for editor completion.
+const SchemaFile = "bare.schema.json"
+
+// Schema returns a JSON Schema of the config file, generated from Config, so editors
+// can complete and check keys.
+func Schema() ([]byte, error) {
+	config := schemaOf(configType, "", "")
+	properties := map[string]any{
+		"profile": map[string]any{
+			"type":                 "object",
+			"description":          "Overlays applied with --profile or $" + ProfileEnv + ", e.g. [profile.ci]",
+			"additionalProperties": map[string]any{"$ref": "#/definitions/config"},
+		},
+	}
+	for key, value := range config["properties"].(map[string]any) {
+		properties[key] = value
+	}
+
+	schema := map[string]any{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "bare configuration",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+		"definitions":          map[string]any{"config": config},
+	}
+
+	content, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not generate schema: %w", err)
+	}
+	return append(content, '\n'), nil
+}
+
+// schemaOf describes a type decoded from the value of a key.
+func schemaOf(t reflect.Type, key, unit string) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		s := map[string]any{
+			"type":        []string{"string"},
+			"description": `A duration such as "2s" or "500ms"`,
+		}
+		if unit != "" {
+			s["type"] = []string{"string", "integer"}
+			s["description"] = `A duration such as "2s" or "500ms", or an integer in ` + unit
+		}
+		return s
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			properties[name] = schemaOf(field.Type, join(key, name), field.Tag.Get("unit"))
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": schemaOf(t.Elem(), key, unit),
+		}
+	case reflect.Slice:
+		return map[string]any{
+			"type":  "array",
+			"items": schemaOf(t.Elem(), key, unit),
+		}
+	case reflect.String:
+		s := map[string]any{"type": "string"}
+		if values, ok := enums[key]; ok {
+			s["enum"] = values
+		}
+		return s
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := map[string]any{"type": "integer"}
+		if b, ok := bounds[key]; ok {
+			s["minimum"], s["maximum"] = b[0], b[1]
+		}
+		return s
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
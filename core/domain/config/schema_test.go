@@ -0,0 +1,30 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	content, err := Schema()
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties map[string]struct {
+			Properties map[string]map[string]any `json:"properties"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(content, &schema))
+	assert.Contains(t, schema.Properties, "profile")
+	assert.Equal(t, []any{"string", "integer"}, schema.Properties["js"].Properties["wait_for"]["type"])
+	assert.Equal(t, []any{"desktop", "mobile"}, schema.Properties["js"].Properties["device"]["enum"])
+
+	shipped, err := os.ReadFile(filepath.Join("..", "..", "..", SchemaFile))
+	require.NoError(t, err)
+	assert.Equal(t, string(content), string(shipped), "%s is out of date, run `bare config schema > %s`", SchemaFile, SchemaFile)
+}
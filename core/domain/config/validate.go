@@ -0,0 +1,519 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// Error is a problem in a config file, with its position when it is known.
+type Error struct {
+	File    string
+	Line    int // 0 if unknown
+	Column  int
+	Key     string // dotted key, e.g. "pages.exclude"
+	Message string
+}
+
+func (e Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.File)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", e.Line, e.Column)
+	}
+	if e.Key != "" {
+		b.WriteString(": " + e.Key)
+	}
+	return b.String() + ": " + e.Message
+}
+
+// Errors are the problems found in a config file, sorted by position.
+type Errors []Error
+
+func (es Errors) Error() string {
+	lines := make([]string, len(es))
+	for i, e := range es {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sorted returns the errors sorted by position.
+func (es Errors) sorted() Errors {
+	sort.SliceStable(es, func(i, j int) bool {
+		if es[i].Line != es[j].Line {
+			return es[i].Line < es[j].Line
+		}
+		return es[i].Column < es[j].Column
+	})
+	return es
+}
+
+// position is the line and column of a key in a config file.
+type position struct{ line, column int }
+
+// positions indexes the position of the keys of a config file.
+type positions map[string]position
+
+// of returns the position of a key, or of the closest table containing it.
+func (ps positions) of(key string) position {
+	for key != "" {
+		if p, ok := ps[key]; ok {
+			return p
+		}
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	return position{}
+}
+
+// keyPositions returns the position of the first occurrence of every key and table of
+// a config file, by dotted key.
+func keyPositions(content []byte) positions {
+	ps := make(positions)
+	at := func(offset uint32) position {
+		before := content[:offset]
+		line := bytes.Count(before, []byte("\n")) + 1
+		return position{line: line, column: int(offset) - bytes.LastIndexByte(before, '\n')}
+	}
+
+	var p unstable.Parser
+	p.Reset(content)
+	table := ""
+	for p.NextExpression() {
+		expr := p.Expression()
+		if expr.Kind != unstable.Table && expr.Kind != unstable.ArrayTable && expr.Kind != unstable.KeyValue {
+			continue
+		}
+
+		var parts []string
+		var first *unstable.Node
+		for it := expr.Key(); it.Next(); {
+			if first == nil {
+				first = it.Node()
+			}
+			parts = append(parts, string(it.Node().Data))
+		}
+		if first == nil {
+			continue
+		}
+
+		key := strings.Join(parts, ".")
+		if expr.Kind == unstable.KeyValue && table != "" {
+			key = table + "." + key
+		} else if expr.Kind != unstable.KeyValue {
+			table = key
+		}
+		if _, ok := ps[key]; !ok {
+			ps[key] = at(first.Raw.Offset)
+		}
+	}
+	return ps
+}
+
+// decodeError converts a TOML syntax error to an Error.
+func decodeError(file string, err error) error {
+	var decodeErr *toml.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return fmt.Errorf("could not parse %s: %w", file, err)
+	}
+	line, column := decodeErr.Position()
+	return Errors{{File: file, Line: line, Column: column, Message: strings.TrimPrefix(decodeErr.Error(), "toml: ")}}
+}
+
+var (
+	durationType        = reflect.TypeOf(Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// checkKeys checks the keys and the types of the values of a document against the
+// type it is decoded in. Integer durations are converted to strings in the document,
+// in the unit of their field.
+func checkKeys(value any, t reflect.Type, key, unit string) (any, []keyError) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		if n, ok := value.(int64); ok {
+			factor, ok := units[unit]
+			if !ok {
+				return value, []keyError{{key, "expected a duration such as \"2s\", got an integer"}}
+			}
+			return time.Duration(n * int64(factor)).String(), nil
+		}
+		if !isString(value) {
+			return value, expect(value, "a duration such as \"2s\"", key, isString)
+		}
+		fallthrough
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		text, ok := value.(string)
+		if !ok {
+			return value, expect(value, "a string", key, isString)
+		}
+		// Strings with environment variables are checked once they are replaced
+		if strings.Contains(text, "$") {
+			return value, nil
+		}
+		if err := reflect.New(t).Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return value, []keyError{{key, err.Error()}}
+		}
+		return value, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		table, ok := value.(map[string]any)
+		if !ok {
+			return value, []keyError{{key, "expected a table, got " + describe(value)}}
+		}
+		var errs []keyError
+		for name, v := range table {
+			field, ok := fieldByTag(t, name)
+			if !ok {
+				errs = append(errs, keyError{join(key, name), "unknown key" + suggest(t, name)})
+				continue
+			}
+			converted, fieldErrs := checkKeys(v, field.Type, join(key, name), field.Tag.Get("unit"))
+			table[name] = converted
+			errs = append(errs, fieldErrs...)
+		}
+		return table, errs
+
+	case reflect.Map:
+		table, ok := value.(map[string]any)
+		if !ok {
+			return value, []keyError{{key, "expected a table, got " + describe(value)}}
+		}
+		var errs []keyError
+		for name, v := range table {
+			converted, itemErrs := checkKeys(v, t.Elem(), join(key, name), unit)
+			table[name] = converted
+			errs = append(errs, itemErrs...)
+		}
+		return table, errs
+
+	case reflect.Slice:
+		items, ok := value.([]any)
+		if !ok {
+			// Arrays of tables are decoded as []map[string]any
+			if tables, isTables := value.([]map[string]any); isTables {
+				items = make([]any, len(tables))
+				for i, table := range tables {
+					items[i] = table
+				}
+			} else {
+				return value, []keyError{{key, "expected an array, got " + describe(value)}}
+			}
+		}
+		var errs []keyError
+		for i, item := range items {
+			converted, itemErrs := checkKeys(item, t.Elem(), key, unit)
+			items[i] = converted
+			errs = append(errs, itemErrs...)
+		}
+		return items, errs
+
+	case reflect.String:
+		return value, expect(value, "a string", key, isString)
+	case reflect.Bool:
+		return value, expect(value, "a boolean", key, func(v any) bool { _, ok := v.(bool); return ok })
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value, expect(value, "an integer", key, func(v any) bool { _, ok := v.(int64); return ok })
+	case reflect.Float32, reflect.Float64:
+		return value, expect(value, "a number", key, func(v any) bool {
+			switch v.(type) {
+			case int64, float64:
+				return true
+			}
+			return false
+		})
+	}
+	return value, nil
+}
+
+// keyError is a problem with a key, before its position is known.
+type keyError struct {
+	key     string
+	message string
+}
+
+func (e keyError) Error() string { return e.key + ": " + e.message }
+
+func isString(v any) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+// expect checks a value with ok, and describes what was expected otherwise.
+func expect(value any, expected, key string, ok func(any) bool) []keyError {
+	if ok(value) {
+		return nil
+	}
+	return []keyError{{key, "expected " + expected + ", got " + describe(value)}}
+}
+
+// describe names the TOML type of a decoded value.
+func describe(value any) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case int64:
+		return "an integer"
+	case float64:
+		return "a float"
+	case bool:
+		return "a boolean"
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "a table"
+	}
+	return "a date"
+}
+
+// fieldByTag finds the field of a struct decoded from a key.
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if tag == name || (tag == "" && strings.EqualFold(field.Name, name)) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// suggest returns the key of a struct closest to an unknown one, e.g. extract_only for extract-only.
+func suggest(t reflect.Type, name string) string {
+	best, bestDistance := "", 3
+	normalized := strings.ReplaceAll(strings.ToLower(name), "-", "_")
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if d := distance(normalized, tag); d < bestDistance {
+			best, bestDistance = tag, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return ", did you mean " + best + "?"
+}
+
+// distance is the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Validate checks the values of the config: URLs, path patterns, selectors, regular
+// expressions, durations and the values of enumerations. Keys in the returned errors
+// are dotted keys, without positions.
+func (c *Config) Validate() Errors {
+	var errs Errors
+	add := func(key, format string, args ...any) {
+		errs = append(errs, Error{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	checkURL := func(key string, u *url.URL) {
+		if u == nil {
+			return
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(key, "%q must be an absolute http:// or https:// URL", u.String())
+		}
+	}
+	checkURL("url", c.URL)
+	checkURL("public_url", c.PublicURL)
+	if c.Server.ReadyURL != "" {
+		u, err := url.Parse(c.Server.ReadyURL)
+		if err != nil {
+			add("server.ready_url", "%v", err)
+		} else {
+			checkURL("server.ready_url", u)
+		}
+	}
+
+	checkPaths := func(key string, paths url.Paths) {
+		for _, p := range paths {
+			if err := p.Validate(); err != nil {
+				add(key, "%v", err)
+			}
+		}
+	}
+	checkPaths("pages.entrypoints", c.Pages.Entrypoints)
+	checkPaths("pages.extract_only", c.Pages.ExtractOnly)
+	checkPaths("pages.exclude", c.Pages.Exclude)
+	checkPaths("minify.exclude", c.Minify.Exclude)
+	checkPaths("fingerprint.exclude", c.Fingerprint.Exclude)
+	checkPaths("search.exclude_pages", c.Search.ExcludePages)
+	for _, rule := range c.Deploy.CacheControl {
+		checkPaths("deploy.cache_control.paths", rule.Paths)
+	}
+	for _, interaction := range c.JS.Interactions {
+		checkPaths("js.interactions.paths", interaction.Paths)
+		for _, step := range interaction.Steps {
+			if step.Click != "" {
+				checkSelector(add, "js.interactions.steps.click", step.Click)
+			}
+		}
+	}
+	for _, rule := range c.Rules {
+		if len(rule.Paths) == 0 {
+			add("rules.paths", "every rule needs paths to apply to")
+		}
+		checkPaths("rules.paths", rule.Paths)
+		if rule.Save != "" {
+			checkEnum(add, "rules.save", rule.Save)
+		}
+		if rule.Wait != nil && *rule.Wait < 0 {
+			add("rules.wait_for", "must not be negative")
+		}
+		if rule.WaitSelector != "" {
+			checkSelector(add, "rules.wait_for_selector", rule.WaitSelector)
+		}
+	}
+	for _, route := range c.Routes {
+		if err := url.Path(route.Path).Validate(); err != nil {
+			add("routes.path", "%v", err)
+		}
+	}
+
+	for key, selectors := range map[string][]string{
+		"transform.remove_selectors": c.Transform.RemoveSelectors,
+		"search.content":             c.Search.Content,
+		"search.exclude":             c.Search.Exclude,
+		"diff.ignore_selectors":      c.Diff.IgnoreSelectors,
+	} {
+		for _, s := range selectors {
+			checkSelector(add, key, s)
+		}
+	}
+	for _, snippet := range c.Transform.Inject {
+		checkSelector(add, "transform.inject.selector", snippet.Selector)
+		checkEnum(add, "transform.inject.position", snippet.Position)
+	}
+	for _, pattern := range c.Diff.IgnorePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			add("diff.ignore_patterns", "invalid regular expression %q: %v", pattern, err)
+		}
+	}
+
+	if c.JS.Wait < 0 {
+		add("js.wait_for", "must not be negative")
+	}
+	if c.Server.Timeout < 0 {
+		add("server.timeout", "must not be negative")
+	}
+	if c.JS.Device != "" {
+		checkEnum(add, "js.device", c.JS.Device)
+	}
+	if c.Lint.Device != "" {
+		checkEnum(add, "lint.device", c.Lint.Device)
+	}
+	for _, format := range c.Compress.Formats {
+		checkEnum(add, "compress.formats", format)
+	}
+	checkBounds(add, "compress.gzip_level", c.Compress.GzipLevel)
+	checkBounds(add, "compress.brotli_level", c.Compress.BrotliLevel)
+	for _, lang := range c.Search.Languages {
+		checkEnum(add, "search.languages", lang)
+	}
+
+	for key, p := range map[string]string{
+		"search.dir":           c.Search.Dir,
+		"fingerprint.manifest": c.Fingerprint.Manifest,
+	} {
+		// Empty keeps the default, anything else must name a path inside the output directory
+		if p == "" {
+			continue
+		}
+		if clean := filepath.Clean(filepath.FromSlash(p)); clean == "." || !filepath.IsLocal(clean) {
+			add(key, "%q must be a path inside the output directory", p)
+		}
+	}
+
+	return errs
+}
+
+func checkSelector(add func(key, format string, args ...any), key, selector string) {
+	if _, err := cascadia.ParseGroup(selector); err != nil {
+		add(key, "invalid selector %q: %v", selector, err)
+	}
+}
+
+// enums are the allowed values of keys, shared with the JSON Schema.
+var enums = map[string][]string{
+	"js.device":                 {"desktop", "mobile"},
+	"transform.inject.position": {"append", "prepend", "before", "after", "replace"},
+	"compress.formats":          {"gzip", "br"},
+	"lint.device":               {"desktop", "mobile", "both"},
+	"rules.save":                {"page", "extract_only"},
+	"search.languages":          {"de", "en", "es", "fr"}, // the built-in languages of the search package
+}
+
+func checkEnum(add func(key, format string, args ...any), key, value string) {
+	allowed := enums[key]
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	add(key, "unknown value %q, use %s", value, strings.Join(allowed, ", "))
+}
+
+// bounds are the minimum and maximum of integer keys, shared with the JSON Schema.
+var bounds = map[string][2]int{
+	"compress.gzip_level":   {0, 9},
+	"compress.brotli_level": {0, 11},
+}
+
+func checkBounds(add func(key, format string, args ...any), key string, value int) {
+	if b := bounds[key]; value < b[0] || value > b[1] {
+		add(key, "must be between %d and %d, got %d", b[0], b[1], value)
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Validation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name: "unknown keys",
+			config: `url = "http://localhost:8000"
+worker_count = 4
+
+[pages]
+entrypoints = ["/"]
+extract-only = ["/list"]
+
+[profile.ci.js]
+enabeld = true
+`,
+			expected: `bare.toml:2:1: worker_count: unknown key, did you mean workers_count?
+bare.toml:6:1: pages.extract-only: unknown key, did you mean extract_only?
+bare.toml:9:1: profile.ci.js.enabeld: unknown key, did you mean enabled?`,
+		},
+		{
+			name: "types",
+			config: `output = 5
+
+[js]
+wait_for = true
+
+[pages]
+exclude = "/admin"
+`,
+			expected: `bare.toml:1:1: output: expected a string, got an integer
+bare.toml:4:1: js.wait_for: expected a duration such as "2s", got a boolean
+bare.toml:7:1: pages.exclude: expected an array, got a string`,
+		},
+		{
+			name: "values",
+			config: `url = "localhost:8000"
+
+[js]
+wait_for = "2 seconds"
+device = "tablet"
+
+[pages]
+exclude = ["admin/**"]
+
+[diff]
+ignore_patterns = ["(unclosed"]
+ignore_selectors = ["div["]
+`,
+			expected: `bare.toml:4:1: js.wait_for: invalid duration "2 seconds", use e.g. "2s" or "500ms"`,
+		},
+		{
+			name: "values once decoded",
+			config: `url = "localhost:8000"
+
+[js]
+device = "tablet"
+
+[pages]
+exclude = ["admin/**"]
+
+[diff]
+ignore_patterns = ["(unclosed"]
+ignore_selectors = ["div["]
+
+[lint]
+device = "tablet"
+`,
+			expected: `bare.toml:1:1: url: "localhost:8000" must be an absolute http:// or https:// URL
+bare.toml:4:1: js.device: unknown value "tablet", use desktop, mobile
+bare.toml:7:1: pages.exclude: "admin/**" must start with /
+bare.toml:10:1: diff.ignore_patterns: invalid regular expression "(unclosed": error parsing regexp: missing closing ): ` + "`(unclosed`" + `
+bare.toml:11:1: diff.ignore_selectors: invalid selector "div[": expected identifier, found EOF instead
+bare.toml:14:1: lint.device: unknown value "tablet", use desktop, mobile, both`,
+		},
+		{
+			name: "paths inside the output directory",
+			config: `url = "http://localhost:8000"
+
+[search]
+dir = "./"
+
+[fingerprint]
+manifest = "assets/../../manifest.json"
+`,
+			expected: `bare.toml:4:1: search.dir: "./" must be a path inside the output directory
+bare.toml:7:1: fingerprint.manifest: "assets/../../manifest.json" must be a path inside the output directory`,
+		},
+		{
+			name:     "syntax",
+			config:   "[pages\nentrypoints = []\n",
+			expected: `bare.toml:1:7: expected character ]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "bare.toml")
+			require.NoError(t, os.WriteFile(file, []byte(tc.config), 0644))
+
+			_, _, err := Load(file, "")
+			require.Error(t, err)
+
+			var errs Errors
+			require.ErrorAs(t, err, &errs)
+			for i := range errs {
+				errs[i].File = "bare.toml"
+			}
+			assert.Equal(t, tc.expected, errs.Error())
+		})
+	}
+}
+
+func TestLoad_Durations(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bare.toml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+[server]
+timeout = 45
+
+[js]
+wait_for = 5000
+
+[[js.interactions]]
+paths = ["/"]
+steps = [{ scroll = 1, wait = 500 }, { eval = "go()", wait = "1s" }]
+
+[profile.slow.js]
+wait_for = "1m30s"
+`), 0644))
+
+	c, _, err := Load(file, "")
+	require.NoError(t, err)
+	assert.Equal(t, Duration(5*time.Second), c.JS.Wait) // integers are milliseconds, like before
+	assert.Equal(t, Duration(45*time.Second), c.Server.Timeout)
+	assert.Equal(t, Duration(500*time.Millisecond), c.JS.Interactions[0].Steps[0].Wait)
+	assert.Equal(t, Duration(time.Second), c.JS.Interactions[0].Steps[1].Wait)
+
+	c, _, err = Load(file, "slow")
+	require.NoError(t, err)
+	assert.Equal(t, Duration(90*time.Second), c.JS.Wait)
+
+	exported, err := c.Export()
+	require.NoError(t, err)
+	assert.Contains(t, string(exported), "wait_for = '1m30s'")
+}
+
+func TestNewDefaultConfig_IsValid(t *testing.T) {
+	assert.Empty(t, NewDefaultConfig().Validate())
+
+	exported, err := NewDefaultConfig().Export()
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "bare.toml")
+	require.NoError(t, os.WriteFile(file, exported, 0644))
+	_, _, err = Load(file, "")
+	assert.NoError(t, err)
+}
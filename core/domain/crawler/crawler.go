@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 
@@ -23,12 +24,16 @@ type Link struct {
 type Page struct {
 	URL           *url.URL
 	StatusCode    int
+	Header        http.Header
 	Body          []byte // raw HTML
 	Links         []Link // ALL links (internal + external)
 	Title         string
 	Description   string
 	Canonical     string
 	RedirectChain []Redirect
+	Layout        *Layout
+	Screenshot    []byte // full-page PNG, only set by a JSFetcher configured to take screenshots
+	DesktopBody   []byte // HTML rendered as desktop, only set when comparing devices
 }
 
 // Config holds the crawler configuration.
@@ -259,9 +264,13 @@ func (c *Crawler) fetchPage(ctx context.Context, pageURL *url.URL) (*Page, error
 	page := &Page{
 		URL:           pageURL,
 		StatusCode:    result.StatusCode,
+		Header:        result.Header,
 		Body:          result.Body,
 		Links:         []Link{},
 		RedirectChain: result.RedirectChain,
+		Layout:        result.Layout,
+		Screenshot:    result.Screenshot,
+		DesktopBody:   result.DesktopBody,
 	}
 
 	// Parse HTML for metadata and links
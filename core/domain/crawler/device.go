@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// Device describes the viewport and browser a page is rendered with.
+type Device struct {
+	Name              string
+	Width             int64
+	Height            int64
+	DeviceScaleFactor float64
+	UserAgent         string
+	Mobile            bool
+	Touch             bool
+}
+
+// Built-in device profiles.
+var (
+	Desktop = Device{
+		Name:              "desktop",
+		Width:             1920,
+		Height:            1080,
+		DeviceScaleFactor: 1,
+		UserAgent:         "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
+	}
+
+	Mobile = Device{
+		Name:              "mobile",
+		Width:             412,
+		Height:            915,
+		DeviceScaleFactor: 2.625,
+		UserAgent:         "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Mobile Safari/537.36",
+		Mobile:            true,
+		Touch:             true,
+	}
+)
+
+var devices = map[string]Device{
+	Desktop.Name: Desktop,
+	Mobile.Name:  Mobile,
+}
+
+// DeviceByName returns the built-in device profile with the given name.
+func DeviceByName(name string) (*Device, error) {
+	d, ok := devices[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(devices))
+		for n := range devices {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown device %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+	return &d, nil
+}
+
+// emulate returns the actions that make the current tab render like the device.
+func (d *Device) emulate() chromedp.Tasks {
+	tasks := chromedp.Tasks{
+		emulation.SetDeviceMetricsOverride(d.Width, d.Height, d.DeviceScaleFactor, d.Mobile),
+	}
+	if d.UserAgent != "" {
+		tasks = append(tasks, emulation.SetUserAgentOverride(d.UserAgent))
+	}
+	if d.Touch {
+		tasks = append(tasks, emulation.SetTouchEmulationEnabled(true))
+	}
+	return tasks
+}
+
+// Layout holds measurements of the rendered page, taken in the browser.
+type Layout struct {
+	Device          string   `json:"-"`
+	Mobile          bool     `json:"-"`
+	ViewportWidth   int      `json:"viewportWidth"`
+	ScrollWidth     int      `json:"scrollWidth"`
+	SmallTapTargets []string `json:"smallTapTargets"` // interactive elements smaller than 24x24 CSS pixels
+	SmallText       []string `json:"smallText"`       // text rendered below 12 CSS pixels
+}
+
+// measureLayoutScript collects the layout measurements used by the mobile-friendly rules.
+// Lists are capped so that pathological pages don't produce huge evidence.
+const measureLayoutScript = `(() => {
+	const limit = 20;
+	const describe = (el) => {
+		let s = el.tagName.toLowerCase();
+		if (el.id) s += '#' + el.id;
+		if (typeof el.className === 'string' && el.className.trim()) s += '.' + el.className.trim().split(/\s+/).join('.');
+		const text = (el.innerText || el.value || '').trim().slice(0, 40);
+		return text ? s + ' "' + text + '"' : s;
+	};
+	const result = {
+		viewportWidth: window.innerWidth,
+		scrollWidth: document.documentElement.scrollWidth,
+		smallTapTargets: [],
+		smallText: [],
+	};
+	document.querySelectorAll('a[href], button, input:not([type=hidden]), select, textarea, [role=button], [onclick]').forEach((el) => {
+		if (result.smallTapTargets.length >= limit) return;
+		const r = el.getBoundingClientRect();
+		if (r.width === 0 || r.height === 0) return;
+		if (r.width < 24 || r.height < 24) result.smallTapTargets.push(describe(el));
+	});
+	const walker = document.createTreeWalker(document.body || document.documentElement, NodeFilter.SHOW_TEXT);
+	const seen = new Set();
+	while (walker.nextNode() && result.smallText.length < limit) {
+		const el = walker.currentNode.parentElement;
+		if (!el || seen.has(el) || !walker.currentNode.textContent.trim()) continue;
+		seen.add(el);
+		const style = getComputedStyle(el);
+		if (style.display === 'none' || style.visibility === 'hidden') continue;
+		if (parseFloat(style.fontSize) < 12) result.smallText.push(describe(el));
+	}
+	return result;
+})()`
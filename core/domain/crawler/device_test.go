@@ -0,0 +1,26 @@
+package crawler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceByName(t *testing.T) {
+	d, err := DeviceByName("Mobile")
+	require.NoError(t, err)
+	assert.Equal(t, Mobile, *d)
+
+	d, err = DeviceByName("desktop")
+	require.NoError(t, err)
+	assert.Equal(t, Desktop, *d)
+
+	_, err = DeviceByName("tablet")
+	assert.ErrorContains(t, err, "desktop, mobile")
+}
+
+func TestDevice_Emulate(t *testing.T) {
+	assert.Len(t, Mobile.emulate(), 3, "mobile sets metrics, user agent and touch")
+	assert.Len(t, Desktop.emulate(), 2, "desktop sets metrics and user agent")
+}
@@ -19,8 +19,12 @@ type Redirect struct {
 // FetchResult contains the raw response from fetching a URL.
 type FetchResult struct {
 	StatusCode    int
+	Header        http.Header // Headers of the final response
 	Body          []byte
 	RedirectChain []Redirect // Ordered list of redirects (empty if no redirects)
+	Layout        *Layout    // Rendered layout measurements (nil unless a device is emulated)
+	Screenshot    []byte     // Full-page PNG screenshot (nil unless requested)
+	DesktopBody   []byte     // HTML rendered as desktop, when a page is also rendered on another device
 }
 
 // Fetcher abstracts how pages are fetched.
@@ -78,6 +82,9 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, u *url.URL) (*FetchResult, erro
 	if err != nil {
 		return nil, fmt.Errorf("could not create request: %w", err)
 	}
+	for name, values := range fetchOptions(ctx).Header {
+		req.Header[name] = values
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -92,6 +99,7 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, u *url.URL) (*FetchResult, erro
 
 	return &FetchResult{
 		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
 		Body:          body,
 		RedirectChain: chain,
 	}, nil
@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// defaultSettle is how long to wait after a scroll or click when the step doesn't specify a wait.
+const defaultSettle = 500 * time.Millisecond
+
+// Step is a single scripted interaction run in the page before its HTML is captured.
+// Exactly one of Scroll, Click or Eval is expected; a step with only Wait just pauses.
+type Step struct {
+	Scroll int    // scroll to the bottom of the page N times
+	Click  string // CSS selector of the element to click
+	Times  int    // how many times to click (default 1)
+	Eval   string // JavaScript snippet to evaluate
+	Wait   int    // milliseconds to wait after the step
+}
+
+// Interaction is a list of steps to run on pages matching Paths.
+type Interaction struct {
+	Paths url.Paths
+	Steps []Step
+}
+
+// Interactions is an ordered collection of Interaction.
+type Interactions []Interaction
+
+// StepsFor returns the steps of every interaction matching the given path, in order.
+func (is Interactions) StepsFor(path string) []Step {
+	var steps []Step
+	for _, i := range is {
+		if i.Paths.MatchAny(path) {
+			steps = append(steps, i.Steps...)
+		}
+	}
+	return steps
+}
+
+// tasks converts a step into chromedp actions.
+func (s Step) tasks() chromedp.Tasks {
+	settle := time.Duration(s.Wait) * time.Millisecond
+	if settle == 0 && (s.Scroll > 0 || s.Click != "") {
+		settle = defaultSettle
+	}
+
+	var tasks chromedp.Tasks
+
+	for i := 0; i < s.Scroll; i++ {
+		tasks = append(tasks,
+			chromedp.Evaluate(`window.scrollTo(0, document.documentElement.scrollHeight)`, nil),
+			chromedp.Sleep(settle),
+		)
+	}
+
+	if s.Click != "" {
+		times := s.Times
+		if times <= 0 {
+			times = 1
+		}
+		// Clicking through JS rather than chromedp.Click avoids blocking forever
+		// when the element is missing, e.g. once a "load more" button disappears.
+		script := fmt.Sprintf(`(() => { const el = document.querySelector(%s); if (el) el.click(); })()`, strconv.Quote(s.Click))
+		for i := 0; i < times; i++ {
+			tasks = append(tasks,
+				chromedp.Evaluate(script, nil),
+				chromedp.Sleep(settle),
+			)
+		}
+	}
+
+	if s.Eval != "" {
+		tasks = append(tasks, chromedp.Evaluate(s.Eval, nil))
+		if settle > 0 {
+			tasks = append(tasks, chromedp.Sleep(settle))
+		}
+	}
+
+	if s.Scroll == 0 && s.Click == "" && s.Eval == "" && settle > 0 {
+		tasks = append(tasks, chromedp.Sleep(settle))
+	}
+
+	return tasks
+}
@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInteractions_StepsFor(t *testing.T) {
+	interactions := Interactions{
+		{Paths: url.Paths{"/blog/**"}, Steps: []Step{{Scroll: 3}}},
+		{Paths: url.Paths{"/blog", "/products"}, Steps: []Step{{Click: ".load-more", Times: 2}}},
+		{Paths: url.Paths{"/about"}, Steps: []Step{{Eval: "openAllAccordions()"}}},
+	}
+
+	assert.Equal(t, []Step{{Scroll: 3}, {Click: ".load-more", Times: 2}}, interactions.StepsFor("/blog"))
+	assert.Equal(t, []Step{{Scroll: 3}}, interactions.StepsFor("/blog/post"))
+	assert.Equal(t, []Step{{Click: ".load-more", Times: 2}}, interactions.StepsFor("/products"))
+	assert.Empty(t, interactions.StepsFor("/contact"))
+}
+
+func TestStep_Tasks(t *testing.T) {
+	testCases := []struct {
+		name string
+		step Step
+		want int
+	}{
+		{name: "scroll three times", step: Step{Scroll: 3}, want: 6},
+		{name: "click defaults to once", step: Step{Click: "button"}, want: 2},
+		{name: "click repeatedly", step: Step{Click: "button", Times: 4}, want: 8},
+		{name: "eval without wait", step: Step{Eval: "1 + 1"}, want: 1},
+		{name: "eval with wait", step: Step{Eval: "1 + 1", Wait: 100}, want: 2},
+		{name: "wait only", step: Step{Wait: 100}, want: 1},
+		{name: "empty step", step: Step{}, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Len(t, tc.step.tasks(), tc.want)
+		})
+	}
+}
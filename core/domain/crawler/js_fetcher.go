@@ -2,22 +2,32 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/felixdorn/bare/core/domain/url"
 	"github.com/rs/zerolog"
 )
 
+// WaitSelectorTimeout bounds how long a fetch waits for FetchOptions.WaitSelector to appear.
+const WaitSelectorTimeout = 30 * time.Second
+
 // JSFetcherOptions configures the JSFetcher.
 type JSFetcherOptions struct {
-	Wait           int      // milliseconds to wait for JS execution
-	MaxTabs        int      // max parallel Chrome tabs (default 1 = sequential)
-	ExecutablePath string   // path to Chrome/Chromium executable
-	Flags          []string // additional Chrome flags
+	Wait           int          // milliseconds to wait for JS execution
+	MaxTabs        int          // max parallel Chrome tabs (default 1 = sequential)
+	ExecutablePath string       // path to Chrome/Chromium executable
+	Flags          []string     // additional Chrome flags
+	Interactions   Interactions // scripted steps run before the HTML is captured
+	Device         *Device      // device to emulate, nil keeps Chrome's default window
+	Screenshot     bool         // capture a full-page PNG screenshot of every page
 	Logger         zerolog.Logger
 }
 
@@ -82,18 +92,37 @@ func (f *JSFetcher) Fetch(ctx context.Context, u *url.URL) (*FetchResult, error)
 		browserOpts = append(browserOpts, chromedp.WithLogf(f.opts.Logger.Printf))
 	}
 
+	// The tab needs the allocator of allocCtx, and is closed as soon as ctx is done
 	taskCtx, cancel := chromedp.NewContext(f.allocCtx, browserOpts...)
 	defer cancel()
-
-	// Track redirects and final status code
-	var chain []Redirect
-	var statusCode int
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	// Track redirects and final status code. Events keep arriving on chromedp's goroutine
+	// after Run returns, and iframes load documents of their own: only the main frame counts.
+	var (
+		mu         sync.Mutex
+		mainFrame  cdp.FrameID
+		chain      []Redirect
+		statusCode int
+		header     = http.Header{}
+	)
 
 	chromedp.ListenTarget(taskCtx, func(ev interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+
 		switch e := ev.(type) {
 		case *network.EventRequestWillBeSent:
+			if e.Type != network.ResourceTypeDocument {
+				return
+			}
+			// The navigation is the first document requested by the tab
+			if mainFrame == "" {
+				mainFrame = e.FrameID
+			}
 			// Capture redirects - RedirectResponse is set when this request was triggered by a redirect
-			if e.RedirectResponse != nil && e.Type == network.ResourceTypeDocument {
+			if e.RedirectResponse != nil && e.FrameID == mainFrame {
 				chain = append(chain, Redirect{
 					URL:        e.RedirectResponse.URL,
 					StatusCode: int(e.RedirectResponse.Status),
@@ -101,33 +130,104 @@ func (f *JSFetcher) Fetch(ctx context.Context, u *url.URL) (*FetchResult, error)
 			}
 		case *network.EventResponseReceived:
 			// Track the main document response status
-			if e.Type == network.ResourceTypeDocument {
+			if e.Type == network.ResourceTypeDocument && e.FrameID == mainFrame {
 				statusCode = int(e.Response.Status)
+				header = http.Header{}
+				for name, value := range e.Response.Headers {
+					header.Set(name, fmt.Sprint(value))
+				}
 			}
 		}
 	})
 
+	fetchOpts := fetchOptions(ctx)
+	wait := time.Duration(f.opts.Wait) * time.Millisecond
+	if fetchOpts.Wait > 0 {
+		wait = fetchOpts.Wait
+	}
+
+	actions := chromedp.Tasks{network.Enable()}
+	if len(fetchOpts.Header) > 0 {
+		headers := network.Headers{}
+		for name := range fetchOpts.Header {
+			headers[name] = fetchOpts.Header.Get(name)
+		}
+		actions = append(actions, network.SetExtraHTTPHeaders(headers))
+	}
+	if f.opts.Device != nil {
+		actions = append(actions, f.opts.Device.emulate()...)
+	}
+	actions = append(actions, chromedp.Navigate(u.String()))
+	if fetchOpts.WaitSelector != "" {
+		actions = append(actions, waitReady(fetchOpts.WaitSelector, WaitSelectorTimeout))
+	}
+	actions = append(actions, chromedp.Sleep(wait))
+	// Interactions can match on the query string too
+	target := u.Path
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	for _, step := range f.opts.Interactions.StepsFor(target) {
+		actions = append(actions, step.tasks()...)
+	}
+
 	var html string
-	err := chromedp.Run(taskCtx,
-		network.Enable(),
-		chromedp.Navigate(u.String()),
-		chromedp.Sleep(time.Duration(f.opts.Wait)*time.Millisecond),
-		chromedp.Evaluate(`document.documentElement.outerHTML`, &html),
-	)
+	actions = append(actions, chromedp.Evaluate(`document.documentElement.outerHTML`, &html))
+
+	// Layout is only measured when emulating a device, since that's what the measurements are judged against
+	var layout *Layout
+	if f.opts.Device != nil {
+		layout = &Layout{Device: f.opts.Device.Name, Mobile: f.opts.Device.Mobile}
+		actions = append(actions, chromedp.Evaluate(measureLayoutScript, layout))
+	}
+
+	err := chromedp.Run(taskCtx, actions)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("chrome fetch failed for %s: %w", u, err)
 	}
 
-	// Default to 200 if we didn't capture a status
-	if statusCode == 0 {
-		statusCode = 200
+	var screenshot []byte
+	if f.opts.Screenshot {
+		if err := chromedp.Run(taskCtx, chromedp.FullScreenshot(&screenshot, 100)); err != nil {
+			return nil, fmt.Errorf("chrome screenshot failed for %s: %w", u, err)
+		}
 	}
 
-	return &FetchResult{
+	mu.Lock()
+	result := &FetchResult{
 		StatusCode:    statusCode,
+		Header:        header.Clone(),
 		Body:          []byte(html),
-		RedirectChain: chain,
-	}, nil
+		RedirectChain: append([]Redirect(nil), chain...),
+		Layout:        layout,
+		Screenshot:    screenshot,
+	}
+	mu.Unlock()
+
+	// Default to 200 if we didn't capture a status
+	if result.StatusCode == 0 {
+		result.StatusCode = 200
+	}
+
+	return result, nil
+}
+
+// waitReady waits for an element matching selector, failing after timeout: pages where
+// it never appears would otherwise block their tab forever.
+func waitReady(selector string, timeout time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err := chromedp.WaitReady(selector, chromedp.ByQuery).Do(ctx)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s did not appear within %s", selector, timeout)
+		}
+		return err
+	})
 }
 
 // Close shuts down the Chrome process.
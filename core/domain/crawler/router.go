@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// FetchOptions override how a single URL is fetched.
+type FetchOptions struct {
+	Header       http.Header   // request headers added to the fetch
+	Wait         time.Duration // time to wait for JS execution, 0 keeps the fetcher's
+	WaitSelector string        // CSS selector of an element to wait for before the wait, JS only
+}
+
+type fetchOptionsKey struct{}
+
+// WithFetchOptions returns a context carrying options for the fetches made with it.
+func WithFetchOptions(ctx context.Context, opts FetchOptions) context.Context {
+	return context.WithValue(ctx, fetchOptionsKey{}, opts)
+}
+
+func fetchOptions(ctx context.Context) FetchOptions {
+	opts, _ := ctx.Value(fetchOptionsKey{}).(FetchOptions)
+	return opts
+}
+
+// Router is a Fetcher sending each URL to the fetcher chosen by a route function,
+// with the options it returns.
+type Router struct {
+	route    func(u *url.URL) (Fetcher, FetchOptions)
+	fetchers []Fetcher
+}
+
+// NewRouter creates a Router. The fetchers are closed with the router.
+func NewRouter(route func(u *url.URL) (Fetcher, FetchOptions), fetchers ...Fetcher) *Router {
+	return &Router{route: route, fetchers: fetchers}
+}
+
+// Fetch fetches the URL with the fetcher chosen for it.
+func (r *Router) Fetch(ctx context.Context, u *url.URL) (*FetchResult, error) {
+	fetcher, opts := r.route(u)
+	return fetcher.Fetch(WithFetchOptions(ctx, opts), u)
+}
+
+// Close closes every fetcher of the router.
+func (r *Router) Close() error {
+	var errs []error
+	for _, f := range r.fetchers {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
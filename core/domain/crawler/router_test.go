@@ -0,0 +1,37 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Preview")))
+	}))
+	defer server.Close()
+
+	httpFetcher := NewHTTPFetcher(nil)
+	router := NewRouter(func(u *url.URL) (Fetcher, FetchOptions) {
+		if u.Path == "/preview" {
+			return httpFetcher, FetchOptions{Header: http.Header{"X-Preview": {"1"}}}
+		}
+		return httpFetcher, FetchOptions{}
+	}, httpFetcher)
+	defer router.Close()
+
+	for path, expected := range map[string]string{"/preview": "1", "/": ""} {
+		u, err := url.Parse(server.URL + path)
+		require.NoError(t, err)
+
+		result, err := router.Fetch(context.Background(), u)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(result.Body), path)
+	}
+}
@@ -0,0 +1,137 @@
+package deploy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Archive writes the files of dir to an archive at path, in the format of the target.
+// Entries are sorted and relative to dir, so the archive extracts to the site's root.
+func Archive(dir string, target Target) error {
+	var write func(dir string, w io.Writer) error
+	switch target.Kind {
+	case TarGz:
+		write = WriteTarGz
+	case Zip:
+		write = WriteZip
+	default:
+		return fmt.Errorf("%s is not an archive", target)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target.Path), 0755); err != nil {
+		return fmt.Errorf("could not create directory for %s: %w", target.Path, err)
+	}
+
+	file, err := os.Create(target.Path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", target.Path, err)
+	}
+
+	if err := write(dir, file); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write %s: %w", target.Path, err)
+	}
+	return file.Close()
+}
+
+// WriteTarGz writes the files of dir as a gzipped tarball.
+func WriteTarGz(dir string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := walkFiles(dir, func(rel string, info os.FileInfo, path string) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    rel,
+			Mode:    0644,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}); err != nil {
+			return err
+		}
+		return copyFile(tw, path)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// WriteZip writes the files of dir as a zip archive.
+func WriteZip(dir string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	err := walkFiles(dir, func(rel string, info os.FileInfo, path string) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		header.Method = zip.Deflate
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return copyFile(fw, path)
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// walkFiles calls fn for every regular file of dir, in lexical order,
+// with its slash-separated path relative to dir.
+func walkFiles(dir string, fn func(rel string, info os.FileInfo, path string) error) error {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if err := fn(filepath.ToSlash(rel), info, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
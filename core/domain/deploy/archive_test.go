@@ -0,0 +1,100 @@
+package deploy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTarget(t *testing.T) {
+	testCases := []struct {
+		output   string
+		expected Target
+	}{
+		{"dist/", Target{Kind: Directory, Path: "dist/"}},
+		{"site.tar.gz", Target{Kind: TarGz, Path: "site.tar.gz"}},
+		{"build/site.TGZ", Target{Kind: TarGz, Path: "build/site.TGZ"}},
+		{"site.zip", Target{Kind: Zip, Path: "site.zip"}},
+		{"s3://bucket", Target{Kind: S3, Bucket: "bucket"}},
+		{"s3://bucket/www/site/", Target{Kind: S3, Bucket: "bucket", Prefix: "www/site"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			target, err := ParseTarget(tc.output)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, target)
+		})
+	}
+
+	_, err := ParseTarget("s3:///prefix")
+	assert.Error(t, err)
+}
+
+func exportDir(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "about"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("home"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "about", "index.html"), []byte("about"), 0644))
+	return dir
+}
+
+func TestArchive_TarGz(t *testing.T) {
+	dir := exportDir(t)
+	target := Target{Kind: TarGz, Path: filepath.Join(t.TempDir(), "out", "site.tar.gz")}
+	require.NoError(t, Archive(dir, target))
+
+	f, err := os.Open(target.Path)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+
+	assert.Equal(t, map[string]string{"index.html": "home", "about/index.html": "about"}, files)
+}
+
+func TestArchive_Zip(t *testing.T) {
+	dir := exportDir(t)
+	target := Target{Kind: Zip, Path: filepath.Join(t.TempDir(), "site.zip")}
+	require.NoError(t, Archive(dir, target))
+
+	zr, err := zip.OpenReader(target.Path)
+	require.NoError(t, err)
+	defer zr.Close()
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+
+	assert.Equal(t, map[string]string{"index.html": "home", "about/index.html": "about"}, files)
+}
+
+func TestArchive_NotAnArchive(t *testing.T) {
+	assert.Error(t, Archive(t.TempDir(), Target{Kind: Directory, Path: "dist"}))
+}
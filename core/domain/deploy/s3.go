@@ -0,0 +1,327 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config holds the connection settings of an S3-compatible service.
+type S3Config struct {
+	Endpoint        string // e.g. http://127.0.0.1:9000 for MinIO, defaults to AWS
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	PathStyle       bool // address buckets as endpoint/bucket instead of bucket.endpoint, required by MinIO
+}
+
+// S3Client is a minimal S3 client supporting the calls needed to sync a directory,
+// authenticated with AWS Signature Version 4.
+type S3Client struct {
+	conf   S3Config
+	bucket string
+	http   *http.Client
+	now    func() time.Time
+}
+
+// Object is a key listed in a bucket.
+type Object struct {
+	Key  string
+	ETag string // without quotes, the MD5 of the content for objects uploaded in a single part
+	Size int64
+}
+
+// NewS3Client creates a client for a bucket.
+func NewS3Client(conf S3Config, bucket string) *S3Client {
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+	if conf.Endpoint == "" {
+		conf.Endpoint = "https://s3." + conf.Region + ".amazonaws.com"
+	}
+
+	return &S3Client{
+		conf:   conf,
+		bucket: bucket,
+		http:   &http.Client{Timeout: 60 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// List returns every object whose key starts with prefix.
+func (c *S3Client) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key  string `xml:"Key"`
+				ETag string `xml:"ETag"`
+				Size int64  `xml:"Size"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(resp, &result); err != nil {
+			return nil, fmt.Errorf("could not parse bucket listing: %w", err)
+		}
+
+		for _, o := range result.Contents {
+			objects = append(objects, Object{Key: o.Key, ETag: strings.Trim(o.ETag, `"`), Size: o.Size})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Put uploads an object.
+func (c *S3Client) Put(ctx context.Context, key string, body []byte, contentType, cacheControl string) error {
+	sum := md5.Sum(body)
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	if cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+
+	_, err := c.do(ctx, http.MethodPut, key, nil, header, body)
+	return err
+}
+
+// Delete deletes objects, in batches of 1000 keys.
+func (c *S3Client) Delete(ctx context.Context, keys []string) error {
+	for len(keys) > 0 {
+		batch := keys[:min(len(keys), 1000)]
+		keys = keys[len(batch):]
+
+		type object struct {
+			Key string `xml:"Key"`
+		}
+		payload := struct {
+			XMLName xml.Name `xml:"Delete"`
+			Quiet   bool     `xml:"Quiet"`
+			Objects []object `xml:"Object"`
+		}{Quiet: true}
+		for _, key := range batch {
+			payload.Objects = append(payload.Objects, object{Key: key})
+		}
+
+		body, err := xml.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		// DeleteObjects requires a Content-MD5 header
+		sum := md5.Sum(body)
+		header := http.Header{}
+		header.Set("Content-Type", "application/xml")
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+
+		resp, err := c.do(ctx, http.MethodPost, "", url.Values{"delete": {""}}, header, body)
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			Errors []struct {
+				Key     string `xml:"Key"`
+				Message string `xml:"Message"`
+			} `xml:"Error"`
+		}
+		if err := xml.Unmarshal(resp, &result); err == nil && len(result.Errors) > 0 {
+			return fmt.Errorf("could not delete %s: %s", result.Errors[0].Key, result.Errors[0].Message)
+		}
+	}
+	return nil
+}
+
+// do sends a signed request and returns the response body, or an error for non-2xx responses.
+func (c *S3Client) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) ([]byte, error) {
+	endpoint, err := url.Parse(c.conf.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+
+	u := *endpoint
+	if c.conf.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.bucket + "/" + key
+	} else {
+		u.Host = c.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawQuery = encodeQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	// Escape the path ourselves, it has to match the signed canonical URI
+	req.URL.RawPath = encodePath(u.Path)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	c.sign(req, body, c.now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not reach %s: %w", endpoint.Host, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read S3 response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if xml.Unmarshal(respBody, &s3Err) == nil && s3Err.Code != "" {
+			return nil, fmt.Errorf("%s %s: %s: %s", method, u.Path, s3Err.Code, s3Err.Message)
+		}
+		return nil, fmt.Errorf("%s %s: unexpected status %d", method, u.Path, resp.StatusCode)
+	}
+
+	return respBody, nil
+}
+
+// sign adds AWS Signature Version 4 headers to a request.
+// Every header already set on the request is signed, along with the host.
+func (c *S3Client) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.conf.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.conf.SessionToken)
+	}
+
+	names := []string{"host"}
+	values := map[string]string{"host": req.URL.Host}
+	for name, v := range req.Header {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		values[lower] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.conf.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.conf.SecretAccessKey), date)
+	key = hmacSHA256(key, c.conf.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.conf.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// encodeQuery encodes query parameters sorted by name, with the strict
+// RFC 3986 escaping SigV4 expects ("%20" rather than "+").
+func encodeQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodePath escapes every segment of a path.
+func encodePath(p string) string {
+	return uriEncode(p, false)
+}
+
+// uriEncode escapes everything but unreserved characters, and slashes unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,163 @@
+package deploy
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// CacheRule sets the Cache-Control of the files matching its paths.
+type CacheRule struct {
+	Paths url.Paths
+	Value string
+}
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	CacheControl []CacheRule // the first matching rule wins, files matching none get no Cache-Control
+	Delete       bool        // delete keys under the prefix that are not part of the export
+	Manifest     string      // fingerprint manifest, fingerprinted files that exist remotely are never re-uploaded
+	DryRun       bool
+}
+
+// SyncStats summarizes a sync.
+type SyncStats struct {
+	Uploaded  int
+	Unchanged int
+	Deleted   int
+	Bytes     int64 // uploaded bytes
+}
+
+// Sync uploads the files of dir that are new or changed to the target bucket, and
+// optionally deletes the keys that no longer exist locally.
+// Files are compared by MD5 with the ETag of the remote objects.
+func Sync(ctx context.Context, dir string, target Target, client *S3Client, opts SyncOptions) (*SyncStats, error) {
+	prefix := ""
+	if target.Prefix != "" {
+		prefix = target.Prefix + "/"
+	}
+
+	remote, err := client.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not list %s: %w", target, err)
+	}
+	etags := make(map[string]string, len(remote))
+	for _, o := range remote {
+		etags[o.Key] = o.ETag
+	}
+
+	immutable, err := Fingerprinted(dir, opts.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SyncStats{}
+	local := make(map[string]bool)
+
+	err = walkFiles(dir, func(rel string, info os.FileInfo, p string) error {
+		key := prefix + rel
+		local[key] = true
+
+		etag, exists := etags[key]
+		if exists && immutable["/"+rel] {
+			stats.Unchanged++
+			return nil
+		}
+
+		body, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(body)
+		if exists && etag == hex.EncodeToString(sum[:]) {
+			stats.Unchanged++
+			return nil
+		}
+
+		stats.Uploaded++
+		stats.Bytes += int64(len(body))
+		if opts.DryRun {
+			return nil
+		}
+		if err := client.Put(ctx, key, body, contentType(rel), CacheControl(opts.CacheControl, rel)); err != nil {
+			return fmt.Errorf("could not upload %s: %w", rel, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Delete {
+		var stale []string
+		for _, o := range remote {
+			if !local[o.Key] {
+				stale = append(stale, o.Key)
+			}
+		}
+		stats.Deleted = len(stale)
+		if !opts.DryRun && len(stale) > 0 {
+			if err := client.Delete(ctx, stale); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// Fingerprinted returns the paths of the fingerprinted files listed in a manifest.
+// Their name changes with their content, so an existing key never needs to be re-uploaded.
+func Fingerprinted(dir, manifest string) (map[string]bool, error) {
+	paths := make(map[string]bool)
+	if manifest == "" {
+		return paths, nil
+	}
+
+	byt, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(manifest)))
+	if os.IsNotExist(err) {
+		return paths, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+
+	var entries map[string]string
+	if err := json.Unmarshal(byt, &entries); err != nil {
+		return nil, fmt.Errorf("could not parse manifest: %w", err)
+	}
+	for _, fingerprinted := range entries {
+		paths[fingerprinted] = true
+	}
+	return paths, nil
+}
+
+// contentType returns the Content-Type of a file from its extension.
+func contentType(rel string) string {
+	if t := mime.TypeByExtension(path.Ext(rel)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// CacheControl returns the Cache-Control of the first rule matching a file.
+// Rules match the file path, or the page path for index.html files.
+func CacheControl(rules []CacheRule, rel string) string {
+	filePath := "/" + rel
+	pagePath := strings.TrimSuffix(filePath, "index.html")
+	for _, rule := range rules {
+		if rule.Paths.MatchAny(filePath) || rule.Paths.MatchAny(pagePath) {
+			return rule.Value
+		}
+	}
+	return ""
+}
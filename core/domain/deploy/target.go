@@ -0,0 +1,56 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind is the type of an export target.
+type Kind int
+
+const (
+	Directory Kind = iota
+	TarGz
+	Zip
+	S3
+)
+
+// Target is where an export is written: a directory, an archive or an S3 bucket.
+type Target struct {
+	Kind   Kind
+	Path   string // directory or archive path
+	Bucket string // S3 bucket
+	Prefix string // S3 key prefix, without leading or trailing slashes
+}
+
+// ParseTarget parses an output flag or setting:
+// "dist/", "site.tar.gz", "site.tgz", "site.zip" or "s3://bucket/prefix".
+func ParseTarget(output string) (Target, error) {
+	if rest, ok := strings.CutPrefix(output, "s3://"); ok {
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		if bucket == "" {
+			return Target{}, fmt.Errorf("invalid S3 target %q: missing bucket", output)
+		}
+		return Target{Kind: S3, Bucket: bucket, Prefix: strings.Trim(prefix, "/")}, nil
+	}
+
+	lower := strings.ToLower(output)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return Target{Kind: TarGz, Path: output}, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return Target{Kind: Zip, Path: output}, nil
+	}
+
+	return Target{Kind: Directory, Path: output}, nil
+}
+
+func (t Target) String() string {
+	if t.Kind == S3 {
+		if t.Prefix == "" {
+			return "s3://" + t.Bucket
+		}
+		return "s3://" + t.Bucket + "/" + t.Prefix
+	}
+	return t.Path
+}
@@ -0,0 +1,248 @@
+package differ
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/felixdorn/bare/core/domain/compressor"
+)
+
+// DefaultIgnoreSelectors match elements holding per-request tokens, removed before comparing HTML.
+var DefaultIgnoreSelectors = []string{
+	`meta[name="csrf-token"]`,
+	`meta[name="csrf-param"]`,
+	`input[name="_token"]`,
+	`input[name="csrf_token"]`,
+	`input[name="authenticity_token"]`,
+	`input[name="csrfmiddlewaretoken"]`,
+}
+
+// DefaultIgnorePatterns match volatile text, masked before comparing any text file.
+var DefaultIgnorePatterns = []string{
+	// ISO 8601 timestamps, e.g. 2024-05-01T10:00:00Z or 2024-05-01 10:00:00
+	`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+}
+
+// DefaultIgnoreAttributes are attributes removed before comparing HTML.
+var DefaultIgnoreAttributes = []string{"nonce"}
+
+// Masked replaces the text matched by an ignore pattern.
+const Masked = "[ignored]"
+
+// contextLines is the number of unchanged lines shown around a change.
+const contextLines = 3
+
+// Status is how a file changed between two exports.
+type Status string
+
+const (
+	Added    Status = "added"
+	Removed  Status = "removed"
+	Modified Status = "modified"
+)
+
+// Options configures the comparison.
+type Options struct {
+	IgnoreSelectors  []string         // elements removed from HTML pages
+	IgnoreAttributes []string         // attributes removed from HTML elements
+	IgnorePatterns   []*regexp.Regexp // text masked in every text file
+}
+
+// Line is a line of a hunk. Op is "+" for an added line, "-" for a removed one
+// and " " for context.
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Hunk is a group of nearby changed lines with their context.
+type Hunk struct {
+	OldStart int    `json:"old_start"` // 1-based
+	NewStart int    `json:"new_start"`
+	Lines    []Line `json:"lines"`
+}
+
+// Change is a file that differs between the two exports.
+type Change struct {
+	Path   string `json:"path"` // relative to the export, with slashes
+	Status Status `json:"status"`
+	Binary bool   `json:"binary,omitempty"`
+	Hunks  []Hunk `json:"hunks,omitempty"` // only for modified text files
+}
+
+// Result is the comparison of two exports.
+type Result struct {
+	Old       string   `json:"old"`
+	New       string   `json:"new"`
+	Added     int      `json:"added"`
+	Removed   int      `json:"removed"`
+	Modified  int      `json:"modified"`
+	Unchanged int      `json:"unchanged"`
+	Changes   []Change `json:"changes"`
+}
+
+// CompilePatterns compiles ignore patterns, reporting the one that is invalid.
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Compare classifies the files of two export directories as added, removed or modified.
+// Modified text files come with the hunks that changed once volatile content is ignored,
+// and files whose only changes are ignored count as unchanged.
+func Compare(oldDir, newDir string, opts Options) (*Result, error) {
+	oldFiles, err := listFiles(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Old: oldDir, New: newDir, Changes: []Change{}}
+
+	paths := make([]string, 0, len(oldFiles)+len(newFiles))
+	for p := range oldFiles {
+		paths = append(paths, p)
+	}
+	for p := range newFiles {
+		if !oldFiles[p] {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		// Precompressed sidecars follow the file they compress
+		if compressor.IsSidecar(p, oldFiles) || compressor.IsSidecar(p, newFiles) {
+			continue
+		}
+
+		switch {
+		case !oldFiles[p]:
+			result.Added++
+			result.Changes = append(result.Changes, Change{Path: p, Status: Added})
+		case !newFiles[p]:
+			result.Removed++
+			result.Changes = append(result.Changes, Change{Path: p, Status: Removed})
+		default:
+			change, err := compareFile(oldDir, newDir, p, opts)
+			if err != nil {
+				return nil, err
+			}
+			if change == nil {
+				result.Unchanged++
+				continue
+			}
+			result.Modified++
+			result.Changes = append(result.Changes, *change)
+		}
+	}
+
+	return result, nil
+}
+
+// compareFile compares a file present in both exports, nil means it is unchanged.
+func compareFile(oldDir, newDir, p string, opts Options) (*Change, error) {
+	oldContent, err := os.ReadFile(filepath.Join(oldDir, filepath.FromSlash(p)))
+	if err != nil {
+		return nil, err
+	}
+	newContent, err := os.ReadFile(filepath.Join(newDir, filepath.FromSlash(p)))
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(oldContent, newContent) {
+		return nil, nil
+	}
+	if !isText(oldContent) || !isText(newContent) {
+		return &Change{Path: p, Status: Modified, Binary: true}, nil
+	}
+
+	oldLines, err := Normalize(oldContent, p, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", filepath.Join(oldDir, p), err)
+	}
+	newLines, err := Normalize(newContent, p, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", filepath.Join(newDir, p), err)
+	}
+
+	hunks := hunks(diffLines(oldLines, newLines), contextLines)
+	if len(hunks) == 0 {
+		return nil, nil
+	}
+	return &Change{Path: p, Status: Modified, Hunks: hunks}, nil
+}
+
+// Normalize returns the lines of a text file to compare, with volatile content masked.
+// The extension of p tells HTML pages apart.
+func Normalize(content []byte, p string, opts Options) ([]string, error) {
+	var lines []string
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".html", ".htm":
+		var err error
+		lines, err = normalizeHTML(content, opts.IgnoreSelectors, opts.IgnoreAttributes)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		lines = strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	}
+
+	for i, line := range lines {
+		for _, re := range opts.IgnorePatterns {
+			line = re.ReplaceAllString(line, Masked)
+		}
+		lines[i] = line
+	}
+	return lines, nil
+}
+
+// listFiles returns the set of files in dir, relative to it, with slashes.
+func listFiles(dir string) (map[string]bool, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+// isText reports whether content looks like text rather than binary data.
+func isText(content []byte) bool {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return !bytes.Contains(head, []byte{0}) && utf8.Valid(content)
+}
@@ -0,0 +1,147 @@
+package differ
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func defaultOptions(t *testing.T) Options {
+	patterns, err := CompilePatterns(DefaultIgnorePatterns)
+	require.NoError(t, err)
+	return Options{
+		IgnoreSelectors:  DefaultIgnoreSelectors,
+		IgnoreAttributes: DefaultIgnoreAttributes,
+		IgnorePatterns:   patterns,
+	}
+}
+
+func TestCompare(t *testing.T) {
+	oldDir := testutil.WriteSite(t, map[string]string{
+		"index.html": `<html><head><meta name="csrf-token" content="abc"><script nonce="n1">init()</script></head>
+<body><h1>Home</h1><p>Updated 2024-05-01T10:00:00Z</p></body></html>`,
+		"about/index.html": `<html><body><h1>About</h1><p>We are a team.</p></body></html>`,
+		"old.html":         `<p>Gone</p>`,
+		"css/style.css":    "body {\n  color: red;\n}\n",
+		"css/style.css.gz": "\x1f\x8b\x00",
+		"img/logo.png":     "\x89PNG\x00\x01",
+		"robots.txt":       "User-agent: *\n",
+	})
+	newDir := testutil.WriteSite(t, map[string]string{
+		// Only the token, the nonce, the timestamp and the formatting changed
+		"index.html": `<html>
+  <head>
+    <meta name="csrf-token" content="xyz">
+    <script nonce="n2">init()</script>
+  </head>
+  <body>
+    <h1>Home</h1>
+    <p>Updated 2024-05-02T11:30:00Z</p>
+  </body>
+</html>`,
+		"about/index.html": `<html><body><h1>About</h1><p>We are a small team.</p></body></html>`,
+		"new.html":         `<p>New</p>`,
+		"css/style.css":    "body {\n  color: blue;\n}\n",
+		"css/style.css.gz": "\x1f\x8b\x01",
+		"img/logo.png":     "\x89PNG\x00\x02",
+		"robots.txt":       "User-agent: *\n",
+	})
+
+	result, err := Compare(oldDir, newDir, defaultOptions(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.Added)
+	assert.Equal(t, 1, result.Removed)
+	assert.Equal(t, 3, result.Modified)
+	assert.Equal(t, 2, result.Unchanged)
+
+	assert.Equal(t, []Change{
+		{Path: "about/index.html", Status: Modified, Hunks: []Hunk{{
+			OldStart: 4, NewStart: 4, Lines: []Line{
+				{" ", "    <h1>"},
+				{" ", "      About"},
+				{" ", "    <p>"},
+				{"-", "      We are a team."},
+				{"+", "      We are a small team."},
+			},
+		}}},
+		{Path: "css/style.css", Status: Modified, Hunks: []Hunk{{
+			OldStart: 1, NewStart: 1, Lines: []Line{
+				{" ", "body {"},
+				{"-", "  color: red;"},
+				{"+", "  color: blue;"},
+				{" ", "}"},
+			},
+		}}},
+		{Path: "img/logo.png", Status: Modified, Binary: true},
+		{Path: "new.html", Status: Added},
+		{Path: "old.html", Status: Removed},
+	}, result.Changes)
+}
+
+func TestDiffLines(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{name: "equal", a: "a b c", b: "a b c", expected: " a  b  c"},
+		{name: "insert", a: "a c", b: "a b c", expected: " a +b  c"},
+		{name: "delete", a: "a b c", b: "a c", expected: " a -b  c"},
+		{name: "replace", a: "a b c", b: "a x c", expected: " a -b +x  c"},
+		{name: "from empty", a: "", b: "a b", expected: "+a +b"},
+		{name: "to empty", a: "a b", b: "", expected: "-a -b"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ops []string
+			for _, l := range diffLines(strings.Fields(tc.a), strings.Fields(tc.b)) {
+				ops = append(ops, l.Op+l.Text)
+			}
+			assert.Equal(t, tc.expected, strings.Join(ops, " "))
+		})
+	}
+}
+
+func TestHunks(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		line := strings.Repeat("x", i)
+		a = append(a, line)
+		if i != 2 && i != 15 {
+			b = append(b, line)
+		}
+	}
+
+	hs := hunks(diffLines(a, b), 3)
+	require.Len(t, hs, 2)
+	assert.Equal(t, 1, hs[0].OldStart)
+	assert.Len(t, hs[0].Lines, 5) // 1 line before, 3 after
+	assert.Equal(t, 12, hs[1].OldStart)
+	assert.Equal(t, 11, hs[1].NewStart)
+	assert.Len(t, hs[1].Lines, 7)
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteText(&buf, &Result{
+		Added: 1, Modified: 1,
+		Changes: []Change{
+			{Path: "new.html", Status: Added},
+			{Path: "style.css", Status: Modified, Hunks: []Hunk{{OldStart: 4, NewStart: 4, Lines: []Line{{"-", "a"}, {"+", "b"}}}}},
+		},
+	}))
+
+	assert.Equal(t, `added      new.html
+modified   style.css
+  @@ -4 +4 @@
+  - a
+  + b
+1 added, 0 removed, 1 modified, 0 unchanged
+`, buf.String())
+}
@@ -0,0 +1,84 @@
+package differ
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// normalizeHTML renders a page as one line per element and text node, indented by depth,
+// so that formatting changes don't show up. Ignored elements and attributes are removed,
+// and attributes are sorted.
+func normalizeHTML(content []byte, ignoreSelectors, ignoreAttributes []string) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	for _, sel := range ignoreSelectors {
+		doc.Find(sel).Remove()
+	}
+
+	ignored := make(map[string]bool, len(ignoreAttributes))
+	for _, a := range ignoreAttributes {
+		ignored[strings.ToLower(a)] = true
+	}
+
+	var lines []string
+	for _, n := range doc.Nodes {
+		lines = renderNode(n, 0, ignored, lines)
+	}
+	return lines, nil
+}
+
+func renderNode(n *html.Node, depth int, ignored map[string]bool, lines []string) []string {
+	indent := strings.Repeat("  ", depth)
+
+	switch n.Type {
+	case html.DoctypeNode:
+		return append(lines, "<!DOCTYPE "+n.Data+">")
+
+	case html.TextNode:
+		// Scripts and styles keep their lines, other text is collapsed
+		if n.Parent != nil && (n.Parent.Data == "script" || n.Parent.Data == "style") {
+			for _, line := range strings.Split(n.Data, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					lines = append(lines, indent+line)
+				}
+			}
+			return lines
+		}
+		if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+			lines = append(lines, indent+text)
+		}
+		return lines
+
+	case html.ElementNode:
+		var attrs []string
+		for _, a := range n.Attr {
+			if ignored[strings.ToLower(a.Key)] {
+				continue
+			}
+			attrs = append(attrs, a.Key+`="`+strings.Join(strings.Fields(a.Val), " ")+`"`)
+		}
+		sort.Strings(attrs)
+
+		tag := "<" + n.Data
+		if len(attrs) > 0 {
+			tag += " " + strings.Join(attrs, " ")
+		}
+		lines = append(lines, indent+tag+">")
+		depth++
+
+	case html.DocumentNode:
+	default:
+		return lines // comments
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		lines = renderNode(c, depth, ignored, lines)
+	}
+	return lines
+}
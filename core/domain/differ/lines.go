@@ -0,0 +1,128 @@
+package differ
+
+// maxEdits bounds the work spent on a single file. Files further apart than this
+// are shown as entirely replaced.
+const maxEdits = 4000
+
+// diffLines returns the shortest edit script turning a into b, using Myers' algorithm.
+func diffLines(a, b []string) []Line {
+	n, m := len(a), len(b)
+	total := n + m
+	if total == 0 {
+		return nil
+	}
+
+	offset := total + 1
+	v := make([]int, 2*total+3)
+	// trace[d] holds v for k in [-d, d] after d edits
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= total && d <= maxEdits && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // insertion
+			} else {
+				x = v[offset+k-1] + 1 // deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+	}
+
+	if !found {
+		lines := make([]Line, 0, n+m)
+		for _, l := range a {
+			lines = append(lines, Line{Op: "-", Text: l})
+		}
+		for _, l := range b {
+			lines = append(lines, Line{Op: "+", Text: l})
+		}
+		return lines
+	}
+
+	// Walk back from the end to recover the edits, in reverse
+	var reversed []Line
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Op: " ", Text: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, Line{Op: "+", Text: b[y-1]})
+		} else {
+			reversed = append(reversed, Line{Op: "-", Text: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, Line{Op: " ", Text: a[x-1]})
+		x--
+		y--
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, l := range reversed {
+		lines[len(reversed)-1-i] = l
+	}
+	return lines
+}
+
+// hunks groups changed lines with up to context unchanged lines around them.
+// Changes whose context overlaps share a hunk.
+func hunks(lines []Line, context int) []Hunk {
+	var ranges [][2]int
+	for i, l := range lines {
+		if l.Op == " " {
+			continue
+		}
+		start, end := max(0, i-context), min(len(lines), i+context+1)
+		if len(ranges) > 0 && start <= ranges[len(ranges)-1][1] {
+			ranges[len(ranges)-1][1] = end
+			continue
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+
+	result := make([]Hunk, 0, len(ranges))
+	for _, r := range ranges {
+		h := Hunk{OldStart: 1, NewStart: 1, Lines: lines[r[0]:r[1]]}
+		for _, l := range lines[:r[0]] {
+			if l.Op != "+" {
+				h.OldStart++
+			}
+			if l.Op != "-" {
+				h.NewStart++
+			}
+		}
+		result = append(result, h)
+	}
+	return result
+}
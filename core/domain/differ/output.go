@@ -0,0 +1,61 @@
+package differ
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// WriteText writes the result as a summary followed by unified hunks, for a terminal.
+func WriteText(w io.Writer, r *Result) error {
+	for _, c := range r.Changes {
+		label := string(c.Status)
+		if c.Binary {
+			label += ", binary"
+		}
+		if _, err := fmt.Fprintf(w, "%-10s %s\n", label, c.Path); err != nil {
+			return err
+		}
+
+		for _, h := range c.Hunks {
+			if _, err := fmt.Fprintf(w, "  @@ -%d +%d @@\n", h.OldStart, h.NewStart); err != nil {
+				return err
+			}
+			for _, l := range h.Lines {
+				if _, err := fmt.Fprintf(w, "  %s %s\n", l.Op, l.Text); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d added, %d removed, %d modified, %d unchanged\n", r.Added, r.Removed, r.Modified, r.Unchanged)
+	return err
+}
+
+// WriteJSON writes the result as indented JSON.
+func WriteJSON(w io.Writer, r *Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
+
+// WriteHTML writes the result as a standalone HTML page.
+func WriteHTML(w io.Writer, r *Result) error {
+	tmpl, err := template.ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return err
+	}
+
+	return tmpl.ExecuteTemplate(w, "diff.html", struct {
+		*Result
+		GeneratedAt time.Time
+	}{r, time.Now()})
+}
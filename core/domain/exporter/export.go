@@ -6,11 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/felixdorn/bare/core/domain/config"
 	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/sitemap"
 	"github.com/felixdorn/bare/core/domain/url"
 	"github.com/rs/zerolog"
 )
@@ -23,18 +27,23 @@ var (
 
 // Export manages the exporting process using the crawler.
 type Export struct {
-	Conf    *config.Config
-	log     zerolog.Logger
-	fetcher crawler.Fetcher
+	Conf        *config.Config
+	log         zerolog.Logger
+	fetcher     crawler.Fetcher
+	transformer *Transformer
+
+	pageCount      int
+	sitemapEntries []sitemap.Entry
 }
 
 // NewExport creates a new Export instance.
 // The fetcher parameter is optional; if nil, a default HTTPFetcher will be used.
 func NewExport(conf *config.Config, log zerolog.Logger, fetcher crawler.Fetcher) *Export {
 	return &Export{
-		Conf:    conf,
-		log:     log,
-		fetcher: fetcher,
+		Conf:        conf,
+		log:         log,
+		fetcher:     fetcher,
+		transformer: NewTransformer(conf.Transform),
 	}
 }
 
@@ -46,6 +55,16 @@ func (e *Export) Run(ctx context.Context) error {
 		entrypoints[i] = string(ep)
 	}
 
+	// Templated routes reach pages that no link points to
+	if len(e.Conf.Routes) > 0 {
+		routes, err := ExpandRoutes(ctx, e.Conf.URL, e.Conf.Routes, &http.Client{Timeout: 30 * time.Second})
+		if err != nil {
+			return err
+		}
+		e.log.Info().Int("count", len(routes)).Msg("Expanded routes into entrypoints")
+		entrypoints = append(entrypoints, routes...)
+	}
+
 	c := crawler.New(crawler.Config{
 		BaseURL:     e.Conf.URL,
 		WorkerCount: e.Conf.WorkersCount,
@@ -80,8 +99,17 @@ func (e *Export) Run(ctx context.Context) error {
 			}
 
 			// Save the page to disk
-			if err := e.savePage(page); err != nil {
+			saved, err := e.savePage(page)
+			if err != nil {
 				e.log.Error().Err(err).Str("url", page.URL.String()).Msg("Failed to save page")
+				return
+			}
+
+			if isCrawlable(page.URL) {
+				e.pageCount++
+				if entry, ok := e.sitemapEntry(page, saved); ok {
+					e.sitemapEntries = append(e.sitemapEntries, entry)
+				}
 			}
 		},
 	})
@@ -101,27 +129,47 @@ func (e *Export) Run(ctx context.Context) error {
 	return nil
 }
 
-// savePage writes a page's content to disk.
-func (e *Export) savePage(page *crawler.Page) error {
+// PageCount returns the number of HTML pages saved.
+func (e *Export) PageCount() int {
+	return e.pageCount
+}
+
+// savePage writes a page's content to disk and returns what was written.
+func (e *Export) savePage(page *crawler.Page) ([]byte, error) {
 	path := page.URL.ToPath(e.Conf.Output)
 
+	body := page.Body
+	if isHTML(page.Header) {
+		transformed, err := e.transformer.Apply(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform %s: %w", page.URL, err)
+		}
+		body = transformed
+	}
+
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
 	}
 
 	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", path, err)
+		return nil, fmt.Errorf("failed to create file %s: %w", path, err)
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, bytes.NewReader(page.Body))
+	_, err = io.Copy(file, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to write file %s: %w", path, err)
+		return nil, fmt.Errorf("failed to write file %s: %w", path, err)
 	}
 
 	e.log.Info().Str("url", page.URL.String()).Str("path", path).Msg("Exported page")
-	return nil
+	return body, nil
+}
+
+// isHTML reports whether a response is an HTML document, the only kind transformed.
+func isHTML(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType == "text/html"
 }
 
 // isCrawlable checks if a URL should be crawled for more links.
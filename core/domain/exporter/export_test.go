@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/felixdorn/bare/core/domain/config"
 	"github.com/felixdorn/bare/core/domain/crawler"
@@ -78,3 +79,87 @@ func TestExport_Run(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, string(aboutContent), `<h1>About Us</h1>`, "About.html content is not as expected")
 }
+
+func TestExport_SitemapEntries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Last-Modified", "Fri, 01 Mar 2024 12:00:00 GMT")
+			fmt.Fprintln(w, `<html><head><link rel="canonical" href="https://example.com/"></head><body>
+				<a href="/noindex">a</a><a href="/header-noindex">b</a><a href="/duplicate">c</a><a href="/missing">d</a><a href="/style.css">e</a>
+			</body></html>`)
+		case "/noindex":
+			fmt.Fprintln(w, `<html><head><meta name="robots" content="noindex, follow"></head></html>`)
+		case "/header-noindex":
+			w.Header().Set("X-Robots-Tag", "noindex")
+			fmt.Fprintln(w, `<html></html>`)
+		case "/duplicate":
+			fmt.Fprintln(w, `<html><head><link rel="canonical" href="/"></head></html>`)
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprintln(w, `body { color: blue; }`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	publicURL, err := url.Parse("https://example.com")
+	require.NoError(t, err)
+
+	conf := config.NewDefaultConfig()
+	conf.URL = serverURL
+	conf.PublicURL = publicURL
+	conf.Output = t.TempDir()
+
+	export := NewExport(conf, zerolog.Nop(), crawler.NewHTTPFetcher(server.Client()))
+	require.NoError(t, export.Run(context.Background()))
+
+	entries := export.SitemapEntries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "/", entries[0].Path)
+	assert.Equal(t, "2024-03-01T12:00:00Z", entries[0].LastModified.UTC().Format(time.RFC3339))
+	assert.NotEmpty(t, entries[0].Hash)
+}
+
+func TestExport_Run_TransformsOnlyHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><script>x()</script><a href="/api/data">Data</a></body></html>`)
+		case "/api/data":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"a":1}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	conf := config.NewDefaultConfig()
+	conf.URL = serverURL
+	conf.Output = t.TempDir()
+	conf.Transform.RemoveSelectors = []string{"script"}
+
+	export := NewExport(conf, zerolog.Nop(), crawler.NewHTTPFetcher(server.Client()))
+	require.NoError(t, export.Run(context.Background()))
+
+	home, err := os.ReadFile(filepath.Join(conf.Output, "index.html"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(home), "<script>")
+
+	data, err := url.Parse(server.URL + "/api/data")
+	require.NoError(t, err)
+	content, err := os.ReadFile(data.ToPath(conf.Output))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(content))
+
+	_, body, err := export.Render(context.Background(), "/api/data", nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(body))
+}
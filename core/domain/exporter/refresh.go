@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// Finish turns the body of a page into the content of its exported file, the way the
+// stages following the crawl would, e.g. by rewriting, minifying and fingerprinting it.
+// rel is the slash-separated path of the file, relative to the output directory.
+type Finish func(rel string, body []byte) ([]byte, error)
+
+// Refresh fetches already exported pages again and saves the ones whose content changed.
+// Each body goes through the transformer then through finish, if set, before being
+// compared with the file on disk, so unchanged pages are never written.
+// It returns the paths of the pages that changed.
+func (e *Export) Refresh(ctx context.Context, paths []string, finish Finish) ([]string, error) {
+	var changed []string
+
+	for _, p := range paths {
+		if ctx.Err() != nil {
+			return changed, ctx.Err()
+		}
+
+		status, body, err := e.Render(ctx, p, finish)
+		if err != nil {
+			e.log.Warn().Err(err).Str("path", p).Msg("Failed to refresh page")
+			continue
+		}
+		if status != http.StatusOK {
+			e.log.Warn().Int("status", status).Str("path", p).Msg("Page no longer exported")
+			continue
+		}
+
+		path, err := e.File(p)
+		if err != nil {
+			return changed, err
+		}
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, body) {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return changed, fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, body, 0644); err != nil {
+			return changed, fmt.Errorf("failed to write file %s: %w", path, err)
+		}
+
+		e.log.Info().Str("path", p).Str("file", path).Msg("Refreshed page")
+		changed = append(changed, p)
+	}
+
+	return changed, nil
+}
+
+// Render fetches a page from the origin and returns its status and the content the
+// export would contain for it, once transformed and passed through finish, if set.
+// The body is only returned with a 200 status.
+func (e *Export) Render(ctx context.Context, path string, finish Finish) (int, []byte, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	u := e.Conf.URL.ResolveReference(ref)
+
+	result, err := e.fetcher.Fetch(ctx, u)
+	if err != nil {
+		return 0, nil, err
+	}
+	if result.StatusCode != http.StatusOK {
+		return result.StatusCode, nil, nil
+	}
+
+	body := result.Body
+	if isHTML(result.Header) {
+		if body, err = e.transformer.Apply(body); err != nil {
+			return 0, nil, fmt.Errorf("failed to transform %s: %w", u, err)
+		}
+	}
+	if finish != nil {
+		rel, err := filepath.Rel(e.Conf.Output, u.ToPath(e.Conf.Output))
+		if err != nil {
+			return 0, nil, err
+		}
+		if body, err = finish(filepath.ToSlash(rel), body); err != nil {
+			return 0, nil, fmt.Errorf("failed to finish %s: %w", u, err)
+		}
+	}
+	return http.StatusOK, body, nil
+}
+
+// File returns the path of the file a page is exported to.
+func (e *Export) File(path string) (string, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	return e.Conf.URL.ResolveReference(ref).ToPath(e.Conf.Output), nil
+}
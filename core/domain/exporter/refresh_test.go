@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport_Refresh(t *testing.T) {
+	version := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<html><body>home %s</body></html>`, version)
+		case "/about":
+			fmt.Fprint(w, `<html><body>about</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	conf := config.NewDefaultConfig()
+	conf.URL = serverURL
+	conf.Output = t.TempDir()
+
+	export := NewExport(conf, zerolog.Nop(), crawler.NewHTTPFetcher(server.Client()))
+	require.NoError(t, export.Run(context.Background()))
+
+	var files []string
+	finish := func(rel string, b []byte) ([]byte, error) {
+		files = append(files, rel)
+		return bytes.ReplaceAll(b, []byte("<body>"), []byte("<body class=\"rewritten\">")), nil
+	}
+
+	// The first refresh rewrites every page
+	changed, err := export.Refresh(context.Background(), []string{"/", "/about", "/gone"}, finish)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/", "/about"}, changed)
+	assert.Equal(t, []string{"index.html", "about/index.html"}, files, "pages are finished from the path of their file")
+
+	// Only the page that changed on the origin is written again
+	version = "v2"
+	changed, err = export.Refresh(context.Background(), []string{"/", "/about"}, finish)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/"}, changed)
+
+	content, err := os.ReadFile(filepath.Join(conf.Output, "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, `<html><body class="rewritten">home v2</body></html>`, string(content))
+}
@@ -0,0 +1,201 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// maxRouteExpansion guards against a typo in a range producing millions of entrypoints.
+const maxRouteExpansion = 100000
+
+var placeholderRegex = regexp.MustCompile(`\{([a-zA-Z0-9_-]+)\}`)
+
+// ExpandRoutes expands templated routes into paths, with one path per combination
+// of placeholder values. JSON endpoints are fetched from baseURL with client.
+func ExpandRoutes(ctx context.Context, baseURL *url.URL, routes []config.Route, client *http.Client) ([]string, error) {
+	var paths []string
+
+	for _, route := range routes {
+		expanded := []string{route.Path}
+
+		// A placeholder used twice takes the same value in both places
+		seen := make(map[string]bool)
+		for _, m := range placeholderRegex.FindAllStringSubmatch(route.Path, -1) {
+			name := m[1]
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			param, ok := route.Params[name]
+			if !ok {
+				return nil, fmt.Errorf("route %s: no values for {%s}", route.Path, name)
+			}
+
+			values, err := paramValues(ctx, baseURL, param, client)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: {%s}: %w", route.Path, name, err)
+			}
+			if len(expanded)*len(values) > maxRouteExpansion {
+				return nil, fmt.Errorf("route %s expands to more than %d paths", route.Path, maxRouteExpansion)
+			}
+
+			next := make([]string, 0, len(expanded)*len(values))
+			for _, p := range expanded {
+				for _, v := range values {
+					next = append(next, strings.ReplaceAll(p, m[0], neturl.PathEscape(v)))
+				}
+			}
+			expanded = next
+		}
+
+		paths = append(paths, expanded...)
+	}
+
+	return paths, nil
+}
+
+// paramValues returns the values of a placeholder from its source.
+func paramValues(ctx context.Context, baseURL *url.URL, param config.RouteParam, client *http.Client) ([]string, error) {
+	sources := 0
+	for _, set := range []bool{len(param.Values) > 0, len(param.Range) > 0, param.From != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("exactly one of values, range or from must be set")
+	}
+
+	switch {
+	case len(param.Values) > 0:
+		return param.Values, nil
+	case len(param.Range) > 0:
+		return rangeValues(param.Range)
+	default:
+		return fetchValues(ctx, baseURL, param.From, param.Field, client)
+	}
+}
+
+// rangeValues returns the numbers of an inclusive [from, to] or [from, to, step] range.
+func rangeValues(r []int) ([]string, error) {
+	if len(r) != 2 && len(r) != 3 {
+		return nil, fmt.Errorf("range must be [from, to] or [from, to, step]")
+	}
+
+	from, to, step := r[0], r[1], 1
+	if len(r) == 3 {
+		step = r[2]
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("range step must be positive")
+	}
+	if to < from {
+		return nil, fmt.Errorf("range ends before it starts")
+	}
+	if (to-from)/step+1 > maxRouteExpansion {
+		return nil, fmt.Errorf("range has more than %d values", maxRouteExpansion)
+	}
+
+	var values []string
+	for i := from; i <= to; i += step {
+		values = append(values, strconv.Itoa(i))
+	}
+	return values, nil
+}
+
+// fetchValues fetches a JSON document from the origin and extracts the values at field.
+func fetchValues(ctx context.Context, baseURL *url.URL, from, field string, client *http.Client) ([]string, error) {
+	ref, err := url.Parse(from)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := baseURL.ResolveReference(ref)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not reach %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", endpoint, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s did not return JSON: %w", endpoint, err)
+	}
+
+	var parts []string
+	if field != "" {
+		parts = strings.Split(field, ".")
+	}
+	values, err := jsonValues(doc, parts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", endpoint, err)
+	}
+	return values, nil
+}
+
+// jsonValues walks a dotted path into a JSON value. Arrays met along the way are
+// flattened, so "data.slug" reads the slug of every item of {"data": [...]}.
+func jsonValues(node any, path []string) ([]string, error) {
+	switch v := node.(type) {
+	case []any:
+		var values []string
+		for _, item := range v {
+			itemValues, err := jsonValues(item, path)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, itemValues...)
+		}
+		return values, nil
+
+	case map[string]any:
+		if len(path) == 0 {
+			return nil, fmt.Errorf("found an object where a value was expected, set field to select one of its keys")
+		}
+		child, ok := v[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("no %q key", path[0])
+		}
+		return jsonValues(child, path[1:])
+
+	case string:
+		return []string{v}, nil
+	case json.Number:
+		return []string{v.String()}, nil
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case nil:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unexpected JSON value %v", node)
+}
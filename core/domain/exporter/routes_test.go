@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandRoutes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags.json":
+			fmt.Fprint(w, `["go", "web dev"]`)
+		case "/api/posts.json":
+			fmt.Fprint(w, `{"data": [{"slug": "hello"}, {"slug": "world"}], "total": 2}`)
+		case "/api/ids.json":
+			fmt.Fprint(w, `[1, 2, 30]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		route    config.Route
+		expected []string
+		err      string
+	}{
+		{
+			name:     "static values",
+			route:    config.Route{Path: "/{lang}/about", Params: map[string]config.RouteParam{"lang": {Values: []string{"en", "fr"}}}},
+			expected: []string{"/en/about", "/fr/about"},
+		},
+		{
+			name:     "range",
+			route:    config.Route{Path: "/blog/page/{page}", Params: map[string]config.RouteParam{"page": {Range: []int{2, 4}}}},
+			expected: []string{"/blog/page/2", "/blog/page/3", "/blog/page/4"},
+		},
+		{
+			name:     "range with step",
+			route:    config.Route{Path: "/archive/{year}", Params: map[string]config.RouteParam{"year": {Range: []int{2000, 2010, 5}}}},
+			expected: []string{"/archive/2000", "/archive/2005", "/archive/2010"},
+		},
+		{
+			name: "combinations",
+			route: config.Route{Path: "/{lang}/page/{page}", Params: map[string]config.RouteParam{
+				"lang": {Values: []string{"en", "fr"}},
+				"page": {Range: []int{1, 2}},
+			}},
+			expected: []string{"/en/page/1", "/en/page/2", "/fr/page/1", "/fr/page/2"},
+		},
+		{
+			name:     "repeated placeholder",
+			route:    config.Route{Path: "/{lang}/docs/{lang}", Params: map[string]config.RouteParam{"lang": {Values: []string{"en", "fr"}}}},
+			expected: []string{"/en/docs/en", "/fr/docs/fr"},
+		},
+		{
+			name:     "JSON array, values are escaped",
+			route:    config.Route{Path: "/tags/{tag}", Params: map[string]config.RouteParam{"tag": {From: "/api/tags.json"}}},
+			expected: []string{"/tags/go", "/tags/web%20dev"},
+		},
+		{
+			name:     "JSON field",
+			route:    config.Route{Path: "/posts/{slug}", Params: map[string]config.RouteParam{"slug": {From: "/api/posts.json", Field: "data.slug"}}},
+			expected: []string{"/posts/hello", "/posts/world"},
+		},
+		{
+			name:     "JSON numbers",
+			route:    config.Route{Path: "/items/{id}", Params: map[string]config.RouteParam{"id": {From: server.URL + "/api/ids.json"}}},
+			expected: []string{"/items/1", "/items/2", "/items/30"},
+		},
+		{
+			name:  "missing param",
+			route: config.Route{Path: "/posts/{slug}"},
+			err:   "no values for {slug}",
+		},
+		{
+			name:  "several sources",
+			route: config.Route{Path: "/{p}", Params: map[string]config.RouteParam{"p": {Values: []string{"a"}, Range: []int{1, 2}}}},
+			err:   "exactly one of values, range or from must be set",
+		},
+		{
+			name:  "invalid range",
+			route: config.Route{Path: "/{p}", Params: map[string]config.RouteParam{"p": {Range: []int{5, 1}}}},
+			err:   "range ends before it starts",
+		},
+		{
+			name:  "object without field",
+			route: config.Route{Path: "/{p}", Params: map[string]config.RouteParam{"p": {From: "/api/posts.json"}}},
+			err:   "set field",
+		},
+		{
+			name:  "endpoint error",
+			route: config.Route{Path: "/{p}", Params: map[string]config.RouteParam{"p": {From: "/api/missing.json"}}},
+			err:   "returned status 404",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, err := ExpandRoutes(context.Background(), baseURL, []config.Route{tc.route}, server.Client())
+			if tc.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+}
+
+func TestExport_Run_Routes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/blog/page/2", "/blog/page/3":
+			fmt.Fprintf(w, `<html><body>%s</body></html>`, r.URL.Path)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	conf := config.NewDefaultConfig()
+	conf.URL = serverURL
+	conf.Output = t.TempDir()
+	conf.Routes = []config.Route{
+		{Path: "/blog/page/{page}", Params: map[string]config.RouteParam{"page": {Range: []int{2, 3}}}},
+	}
+
+	export := NewExport(conf, zerolog.Nop(), crawler.NewHTTPFetcher(server.Client()))
+	require.NoError(t, export.Run(context.Background()))
+
+	// Nothing links to the paginated pages, the routes reach them
+	for _, page := range []string{"2", "3"} {
+		content, err := os.ReadFile(filepath.Join(conf.Output, "blog", "page", page, "index.html"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "/blog/page/"+page)
+	}
+}
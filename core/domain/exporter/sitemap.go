@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/linter"
+	"github.com/felixdorn/bare/core/domain/sitemap"
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// SitemapEntries returns the saved pages that belong in the sitemap.
+func (e *Export) SitemapEntries() []sitemap.Entry {
+	return e.sitemapEntries
+}
+
+// sitemapEntry returns the sitemap entry for a saved page, if it should be listed:
+// non-200, noindex and non-self-canonical pages are left out.
+func (e *Export) sitemapEntry(page *crawler.Page, saved []byte) (sitemap.Entry, bool) {
+	if page.StatusCode != http.StatusOK {
+		return sitemap.Entry{}, false
+	}
+
+	if strings.Contains(strings.ToLower(page.Header.Get("X-Robots-Tag")), "noindex") || linter.IsNoindexHTML(page.Body) {
+		return sitemap.Entry{}, false
+	}
+
+	if !e.isSelfCanonical(page) {
+		return sitemap.Entry{}, false
+	}
+
+	entry := sitemap.Entry{Path: page.URL.Path}
+	if entry.Path == "" {
+		entry.Path = "/"
+	}
+	if lastModified, err := http.ParseTime(page.Header.Get("Last-Modified")); err == nil {
+		entry.LastModified = lastModified
+	}
+	sum := sha256.Sum256(saved)
+	entry.Hash = hex.EncodeToString(sum[:])
+
+	return entry, true
+}
+
+// isSelfCanonical checks that a page has no canonical link, or one pointing to itself.
+// The canonical URL may use the origin's host or the public URL's host.
+func (e *Export) isSelfCanonical(page *crawler.Page) bool {
+	if page.Canonical == "" {
+		return true
+	}
+
+	ref, err := url.Parse(page.Canonical)
+	if err != nil {
+		return false
+	}
+	canonical := page.URL.ResolveReference(ref)
+
+	host := canonical.Hostname()
+	if host != page.URL.Hostname() && (e.Conf.PublicURL == nil || host != e.Conf.PublicURL.Hostname()) {
+		return false
+	}
+
+	trim := func(p string) string {
+		if p == "" {
+			return "/"
+		}
+		if len(p) > 1 {
+			return strings.TrimSuffix(p, "/")
+		}
+		return p
+	}
+
+	path := canonical.Path
+	if e.Conf.PublicURL != nil && host == e.Conf.PublicURL.Hostname() {
+		// A public URL deployed under a subpath prefixes every canonical path
+		path = strings.TrimPrefix(path, strings.TrimSuffix(e.Conf.PublicURL.Path, "/"))
+	}
+
+	return trim(path) == trim(page.URL.Path) && canonical.RawQuery == page.URL.RawQuery
+}
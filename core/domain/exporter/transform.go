@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/felixdorn/bare/core/domain/config"
+	"golang.org/x/net/html"
+)
+
+// Transformer applies DOM transforms to HTML pages before they are saved.
+// It is used to strip runtime-injected markup (hydration attributes,
+// CSS-in-JS style tags, duplicated scripts) from rendered exports.
+type Transformer struct {
+	conf config.Transform
+}
+
+// NewTransformer creates a new Transformer from the transform configuration.
+func NewTransformer(conf config.Transform) *Transformer {
+	return &Transformer{conf: conf}
+}
+
+// Enabled reports whether any transform is configured.
+func (t *Transformer) Enabled() bool {
+	return len(t.conf.RemoveSelectors) > 0 ||
+		len(t.conf.RemoveAttributes) > 0 ||
+		t.conf.DedupeScripts ||
+		len(t.conf.Inject) > 0
+}
+
+// Apply runs the configured transforms on an HTML document and returns the result.
+// The body is returned unchanged when no transform is configured.
+func (t *Transformer) Apply(body []byte) ([]byte, error) {
+	if !t.Enabled() {
+		return body, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse HTML: %w", err)
+	}
+
+	for _, selector := range t.conf.RemoveSelectors {
+		doc.Find(selector).Remove()
+	}
+
+	if len(t.conf.RemoveAttributes) > 0 {
+		doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+			t.removeAttributes(s.Get(0))
+		})
+	}
+
+	if t.conf.DedupeScripts {
+		dedupeScripts(doc)
+	}
+
+	for _, snippet := range t.conf.Inject {
+		if err := inject(doc, snippet); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc.Get(0)); err != nil {
+		return nil, fmt.Errorf("could not render HTML: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// removeAttributes drops every attribute of n whose name matches one of the configured patterns.
+func (t *Transformer) removeAttributes(n *html.Node) {
+	kept := n.Attr[:0]
+	for _, attr := range n.Attr {
+		if !t.matchesAttribute(attr.Key) {
+			kept = append(kept, attr)
+		}
+	}
+	n.Attr = kept
+}
+
+func (t *Transformer) matchesAttribute(name string) bool {
+	for _, pattern := range t.conf.RemoveAttributes {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeScripts removes script tags that load the same src, or contain the same inline code,
+// as an earlier script tag.
+func dedupeScripts(doc *goquery.Document) {
+	seen := make(map[string]bool)
+	doc.Find("script").Each(func(_ int, s *goquery.Selection) {
+		key := "inline:" + strings.TrimSpace(s.Text())
+		if src, ok := s.Attr("src"); ok {
+			key = "src:" + src
+		}
+
+		if seen[key] {
+			s.Remove()
+			return
+		}
+		seen[key] = true
+	})
+}
+
+// inject inserts or replaces markup at every element matching the snippet's selector.
+func inject(doc *goquery.Document, snippet config.Snippet) error {
+	target := doc.Find(snippet.Selector)
+
+	switch snippet.Position {
+	case "", "append":
+		target.AppendHtml(snippet.HTML)
+	case "prepend":
+		target.PrependHtml(snippet.HTML)
+	case "before":
+		target.BeforeHtml(snippet.HTML)
+	case "after":
+		target.AfterHtml(snippet.HTML)
+	case "replace":
+		target.ReplaceWithHtml(snippet.HTML)
+	default:
+		return fmt.Errorf("unknown inject position %q for selector %q", snippet.Position, snippet.Selector)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformer_Apply(t *testing.T) {
+	body := []byte(`<html><head>
+<style data-emotion="css 1x2y3z">.css-1x2y3z{color:red}</style>
+<script src="/app.js"></script>
+</head><body>
+<div data-v-7ba5bd90="" class="card" data-reactroot="">Hello</div>
+<script src="/app.js"></script>
+<script>window.__STATE__ = {}</script>
+<script>window.__STATE__ = {}</script>
+</body></html>`)
+
+	tr := NewTransformer(config.Transform{
+		RemoveSelectors:  []string{"style[data-emotion]"},
+		RemoveAttributes: []string{"data-v-*", "data-reactroot"},
+		DedupeScripts:    true,
+		Inject: []config.Snippet{
+			{Selector: "head", Position: "append", HTML: `<meta name="generator" content="bare">`},
+			{Selector: "div.card", Position: "replace", HTML: `<p>Replaced</p>`},
+		},
+	})
+
+	out, err := tr.Apply(body)
+	require.NoError(t, err)
+	result := string(out)
+
+	assert.NotContains(t, result, "data-emotion")
+	assert.NotContains(t, result, "data-v-7ba5bd90")
+	assert.NotContains(t, result, "data-reactroot")
+	assert.Equal(t, 1, strings.Count(result, `src="/app.js"`), "duplicate script src should be removed")
+	assert.Equal(t, 1, strings.Count(result, `window.__STATE__`), "duplicate inline script should be removed")
+	assert.Contains(t, result, `<meta name="generator" content="bare"/>`)
+	assert.Contains(t, result, `<p>Replaced</p>`)
+	assert.NotContains(t, result, `class="card"`)
+}
+
+func TestTransformer_NoopWhenDisabled(t *testing.T) {
+	body := []byte(`<html><body data-v-1="">untouched   markup</body></html>`)
+
+	out, err := NewTransformer(config.Transform{}).Apply(body)
+	require.NoError(t, err)
+	assert.Equal(t, body, out)
+}
+
+func TestTransformer_UnknownPosition(t *testing.T) {
+	tr := NewTransformer(config.Transform{
+		Inject: []config.Snippet{{Selector: "body", Position: "sideways", HTML: "<p></p>"}},
+	})
+
+	_, err := tr.Apply([]byte(`<html><body></body></html>`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,369 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/rewriter"
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// hashLength is the number of hex characters of the content hash kept in file names.
+const hashLength = 8
+
+// DefaultExtensions are the asset types fingerprinted when none are configured.
+var DefaultExtensions = []string{
+	".css", ".js", ".mjs",
+	".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".svg",
+	".woff", ".woff2", ".ttf", ".otf", ".eot",
+}
+
+// Options configures the Fingerprinter.
+type Options struct {
+	Extensions []string  // file extensions to fingerprint, defaults to DefaultExtensions
+	Exclude    url.Paths // URL paths that keep their name
+	Manifest   string    // manifest file name, relative to the output directory
+}
+
+// Manifest maps original root-relative paths to their fingerprinted paths.
+type Manifest map[string]string
+
+// Fingerprinter renames assets to content-hashed names (app.3f9a1c2b.css) and
+// rewrites every reference to them in HTML, CSS and JavaScript files.
+type Fingerprinter struct {
+	OutputDir  string
+	opts       Options
+	extensions map[string]bool
+	renamed    map[string]string // original file path -> fingerprinted base name
+}
+
+// New creates a new Fingerprinter instance.
+func New(outputDir string, opts Options) *Fingerprinter {
+	if len(opts.Extensions) == 0 {
+		opts.Extensions = DefaultExtensions
+	}
+	if opts.Manifest == "" {
+		opts.Manifest = "manifest.json"
+	}
+
+	extensions := make(map[string]bool, len(opts.Extensions))
+	for _, ext := range opts.Extensions {
+		extensions[strings.ToLower(ext)] = true
+	}
+
+	return &Fingerprinter{
+		OutputDir:  outputDir,
+		opts:       opts,
+		extensions: extensions,
+	}
+}
+
+// Run fingerprints the assets in the output directory and writes the manifest.
+// The manifest of a previous run into the same directory is kept up to date, the
+// files it lists are not fingerprinted again, and those replaced by a new hash are removed.
+func (f *Fingerprinter) Run() (Manifest, error) {
+	manifestPath := filepath.Join(f.OutputDir, filepath.FromSlash(f.opts.Manifest))
+	previous, err := readManifest(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s already exists in the export, choose another manifest name: %w", f.opts.Manifest, err)
+	}
+	fingerprinted := make(map[string]bool, len(previous))
+	for _, hashed := range previous {
+		fingerprinted[filepath.Join(f.OutputDir, filepath.FromSlash(hashed))] = true
+	}
+
+	var assets, documents []string
+	err = filepath.WalkDir(f.OutputDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || p == manifestPath || fingerprinted[p] {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(p))
+		switch {
+		case f.extensions[ext] && !f.opts.Exclude.MatchAny(f.urlPath(p)):
+			assets = append(assets, p)
+		case ext == ".html" || ext == ".htm" || hasReferences(ext):
+			// Pages, and stylesheets or scripts keeping their name, only have their references rewritten
+			documents = append(documents, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]bool, len(assets))
+	for _, p := range assets {
+		pending[p] = true
+	}
+
+	manifest := make(Manifest, len(previous)+len(assets))
+	for original, hashed := range previous {
+		if _, err := os.Stat(filepath.Join(f.OutputDir, filepath.FromSlash(hashed))); err == nil {
+			manifest[original] = hashed
+		}
+	}
+	// Pages exported again may reference assets fingerprinted by the previous run
+	f.renamed = renamedFrom(f.OutputDir, manifest)
+
+	// Stylesheets and scripts reference other assets, so their references are rewritten
+	// before they are hashed, which in turn requires their dependencies to be hashed first.
+	var visit func(p string, stack []string) error
+	visit = func(p string, stack []string) error {
+		if !pending[p] {
+			return nil
+		}
+		for i, s := range stack {
+			if s == p {
+				return &cycleError{files: stack[i:]}
+			}
+		}
+		stack = append(stack, p)
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		ext := strings.ToLower(filepath.Ext(p))
+		if hasReferences(ext) {
+			for _, ref := range rewriter.FindReferences(content, ext) {
+				if target, ok := f.resolve(p, ref.URL); ok {
+					if err := visit(target, stack); err != nil {
+						return err
+					}
+				}
+			}
+			content = f.rewrite(p, content, ext)
+		}
+
+		sum := sha256.Sum256(content)
+		base := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p)) + "." + hex.EncodeToString(sum[:])[:hashLength] + filepath.Ext(p)
+		target := filepath.Join(filepath.Dir(p), base)
+
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+
+		delete(pending, p)
+		f.renamed[p] = base
+		manifest[f.urlPath(p)] = f.urlPath(target)
+		return nil
+	}
+
+	sort.Strings(assets)
+	for _, p := range assets {
+		for {
+			err := visit(p, nil)
+			// Modules importing each other can't be hashed one before the other, so they keep
+			// their names and are rewritten like pages, then the visit starts over.
+			var cycle *cycleError
+			if errors.As(err, &cycle) {
+				for _, file := range cycle.files {
+					delete(pending, file)
+					documents = append(documents, file)
+				}
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	for _, p := range documents {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if ext == ".htm" {
+			ext = ".html"
+		}
+		rewritten := f.rewrite(p, content, ext)
+		if string(rewritten) != string(content) {
+			if err := os.WriteFile(p, rewritten, 0644); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Assets whose content changed since the previous run leave their old hashed file behind
+	current := make(map[string]bool, len(manifest))
+	for _, hashed := range manifest {
+		current[hashed] = true
+	}
+	for _, hashed := range previous {
+		if current[hashed] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(f.OutputDir, filepath.FromSlash(hashed))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	byt, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(manifestPath, byt, 0644); err != nil {
+		return nil, fmt.Errorf("could not write manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Manifest returns the manifest written by the last run, empty if there was none.
+func (f *Fingerprinter) Manifest() (Manifest, error) {
+	manifest, err := readManifest(filepath.Join(f.OutputDir, filepath.FromSlash(f.opts.Manifest)))
+	if manifest == nil && err == nil {
+		manifest = Manifest{}
+	}
+	return manifest, err
+}
+
+// Rewrite returns content with its references to the assets of the manifest replaced,
+// the content of the file at the slash-separated path rel, e.g. a page exported again
+// after Run. It is safe for concurrent use.
+func (f *Fingerprinter) Rewrite(rel string, content []byte) ([]byte, error) {
+	ext := strings.ToLower(path.Ext(rel))
+	if ext == ".htm" {
+		ext = ".html"
+	}
+	if ext != ".html" && !hasReferences(ext) {
+		return content, nil
+	}
+
+	manifest, err := f.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	run := *f
+	run.renamed = renamedFrom(f.OutputDir, manifest)
+	return run.rewrite(filepath.Join(f.OutputDir, filepath.FromSlash(rel)), content, ext), nil
+}
+
+// renamedFrom maps the original file paths of the assets in a manifest to their
+// fingerprinted base names.
+func renamedFrom(outputDir string, manifest Manifest) map[string]string {
+	renamed := make(map[string]string, len(manifest))
+	for original, hashed := range manifest {
+		renamed[filepath.Join(outputDir, filepath.FromSlash(original))] = path.Base(hashed)
+	}
+	return renamed
+}
+
+// cycleError reports assets referencing each other, in reference order.
+type cycleError struct {
+	files []string
+}
+
+func (e *cycleError) Error() string {
+	return fmt.Sprintf("circular reference involving %s", strings.Join(e.files, ", "))
+}
+
+// hasReferences reports whether files with the extension reference other assets.
+func hasReferences(ext string) bool {
+	return ext == ".css" || ext == ".js" || ext == ".mjs"
+}
+
+// readManifest reads the manifest written by a previous run, if any.
+func readManifest(p string) (Manifest, error) {
+	byt, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(byt, &manifest); err != nil {
+		return nil, fmt.Errorf("not a manifest: %w", err)
+	}
+	for original, hashed := range manifest {
+		if !strings.HasPrefix(original, "/") || !isFingerprintOf(hashed, original) {
+			return nil, fmt.Errorf("not a manifest: %q is not a fingerprint of %q", hashed, original)
+		}
+	}
+	return manifest, nil
+}
+
+// isFingerprintOf reports whether hashed is the fingerprinted path of original.
+func isFingerprintOf(hashed, original string) bool {
+	ext := path.Ext(original)
+	prefix := strings.TrimSuffix(original, ext) + "."
+	if len(hashed) != len(prefix)+hashLength+len(ext) || !strings.HasPrefix(hashed, prefix) || !strings.HasSuffix(hashed, ext) {
+		return false
+	}
+	_, err := hex.DecodeString(hashed[len(prefix) : len(prefix)+hashLength])
+	return err == nil
+}
+
+// rewrite replaces references to renamed assets in the content of file p.
+// Only the last path segment of a reference changes, so relative and
+// root-relative references keep their form, along with query and fragment.
+func (f *Fingerprinter) rewrite(p string, content []byte, ext string) []byte {
+	return rewriter.ReplaceReferences(content, ext, func(ref string) string {
+		target, ok := f.resolve(p, ref)
+		if !ok {
+			return ref
+		}
+		base, ok := f.renamed[target]
+		if !ok {
+			return ref
+		}
+
+		end := len(ref)
+		if i := strings.IndexAny(ref, "?#"); i >= 0 {
+			end = i
+		}
+		start := strings.LastIndex(ref[:end], "/") + 1
+		return ref[:start] + base + ref[end:]
+	})
+}
+
+// resolve maps a reference found in file p to the exported file it points to.
+// External references, and references to files that don't exist, don't resolve.
+func (f *Fingerprinter) resolve(p, ref string) (string, bool) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+
+	target := u.Path
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(path.Dir(f.urlPath(p)), target)
+	}
+
+	// Renamed assets no longer exist under their original name
+	file := filepath.Join(f.OutputDir, filepath.FromSlash(target))
+	if _, ok := f.renamed[file]; ok {
+		return file, true
+	}
+
+	return rewriter.ResolvePath(f.OutputDir, target)
+}
+
+// urlPath returns the root-relative URL path of a file in the output directory.
+func (f *Fingerprinter) urlPath(p string) string {
+	rel, err := filepath.Rel(f.OutputDir, p)
+	if err != nil {
+		return ""
+	}
+	return "/" + filepath.ToSlash(rel)
+}
@@ -0,0 +1,196 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprinter_Run(t *testing.T) {
+	tmpDir := testutil.WriteSite(t, map[string]string{
+		"index.html":       `<link href="/css/app.css" rel="stylesheet"><script src="/js/app.js?v=1"></script><img src="img/logo.png"><a href="/about/">About</a>`,
+		"about/index.html": `<img src="../img/logo.png#frag"><link href="/favicon.ico" rel="icon">`,
+		"css/app.css":      `@import "reset.css"; body { background: url('../img/logo.png'); }`,
+		"css/reset.css":    `* { margin: 0 }`,
+		"js/app.js":        `console.log("app")`,
+		"img/logo.png":     `png`,
+		"favicon.ico":      `ico`,
+		"robots.txt":       `User-agent: *`,
+	})
+
+	manifest, err := New(tmpDir, Options{Exclude: url.Paths{"/favicon.ico"}}).Run()
+	require.NoError(t, err)
+
+	hashed := regexp.MustCompile(`^/[a-z/]+\.[0-9a-f]{8}\.[a-z]+$`)
+	for _, original := range []string{"/css/app.css", "/css/reset.css", "/js/app.js", "/img/logo.png"} {
+		require.Contains(t, manifest, original)
+		assert.Regexp(t, hashed, manifest[original])
+		assert.NoFileExists(t, filepath.Join(tmpDir, filepath.FromSlash(original)))
+		assert.FileExists(t, filepath.Join(tmpDir, filepath.FromSlash(manifest[original])))
+	}
+	assert.NotContains(t, manifest, "/favicon.ico")
+	assert.NotContains(t, manifest, "/robots.txt")
+	assert.FileExists(t, filepath.Join(tmpDir, "favicon.ico"))
+
+	base := func(p string) string { return filepath.Base(p) }
+
+	index, err := os.ReadFile(filepath.Join(tmpDir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), `href="`+manifest["/css/app.css"]+`"`)
+	assert.Contains(t, string(index), `src="`+manifest["/js/app.js"]+`?v=1"`)
+	assert.Contains(t, string(index), `src="img/`+base(manifest["/img/logo.png"])+`"`)
+	assert.Contains(t, string(index), `href="/about/"`)
+
+	about, err := os.ReadFile(filepath.Join(tmpDir, "about", "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(about), `src="../img/`+base(manifest["/img/logo.png"])+`#frag"`)
+	assert.Contains(t, string(about), `href="/favicon.ico"`)
+
+	css, err := os.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(manifest["/css/app.css"])))
+	require.NoError(t, err)
+	assert.Contains(t, string(css), `@import "`+base(manifest["/css/reset.css"])+`"`)
+	assert.Contains(t, string(css), `url('../img/`+base(manifest["/img/logo.png"])+`')`)
+
+	var written Manifest
+	byt, err := os.ReadFile(filepath.Join(tmpDir, "manifest.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(byt, &written))
+	assert.Equal(t, manifest, written)
+}
+
+func TestFingerprinter_ContentHashIsStable(t *testing.T) {
+	run := func() Manifest {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "app.css"), []byte(`body{}`), 0644))
+		m, err := New(tmpDir, Options{}).Run()
+		require.NoError(t, err)
+		return m
+	}
+
+	assert.Equal(t, run()["/app.css"], run()["/app.css"])
+}
+
+func TestFingerprinter_ExistingManifest(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "manifest.json"), []byte(`{"name":"PWA"}`), 0644))
+
+	_, err := New(tmpDir, Options{}).Run()
+	assert.ErrorContains(t, err, "manifest.json already exists")
+
+	_, err = New(tmpDir, Options{Manifest: "assets-manifest.json"}).Run()
+	assert.NoError(t, err)
+}
+
+func TestFingerprinter_Run_Twice(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Each export saves the assets under their original names again, in the same directory
+	export := func() {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "index.html"), []byte(`<link href="/app.css" rel="stylesheet">`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "app.css"), []byte(`body{}`), 0644))
+	}
+
+	export()
+	first, err := New(tmpDir, Options{}).Run()
+	require.NoError(t, err)
+
+	export()
+	second, err := New(tmpDir, Options{}).Run()
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"index.html", "manifest.json", filepath.Base(first["/app.css"])}, names)
+}
+
+func TestFingerprinter_Run_Changed(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	export := func(css string) {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "index.html"), []byte(`<link href="/app.css" rel="stylesheet">`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "app.css"), []byte(css), 0644))
+	}
+
+	export(`body{}`)
+	first, err := New(tmpDir, Options{}).Run()
+	require.NoError(t, err)
+
+	export(`body{color:red}`)
+	second, err := New(tmpDir, Options{}).Run()
+	require.NoError(t, err)
+	require.NotEqual(t, first["/app.css"], second["/app.css"])
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, filepath.FromSlash(first["/app.css"])))
+	assert.FileExists(t, filepath.Join(tmpDir, filepath.FromSlash(second["/app.css"])))
+}
+
+func TestFingerprinter_Run_JavaScript(t *testing.T) {
+	tmpDir := testutil.WriteSite(t, map[string]string{
+		"index.html": `<meta property="og:image" content="/og.png"><script type="module" src="/js/main.js"></script>`,
+		"js/main.js": `import { dep } from "./dep.js";
+import "./cycle-a.js";
+import React from "react";
+const lazy = () => import("/js/lazy.js");`,
+		"js/dep.js":     `export const dep = new URL("../og.png", import.meta.url);`,
+		"js/lazy.js":    `export default 1;`,
+		"js/cycle-a.js": `import "./cycle-b.js";`,
+		"js/cycle-b.js": `import "./cycle-a.js"; import "./dep.js";`,
+		"og.png":        `png`,
+	})
+
+	manifest, err := New(tmpDir, Options{}).Run()
+	require.NoError(t, err)
+
+	base := func(p string) string { return filepath.Base(p) }
+
+	main, err := os.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(manifest["/js/main.js"])))
+	require.NoError(t, err)
+	assert.Contains(t, string(main), `from "./`+base(manifest["/js/dep.js"])+`"`)
+	assert.Contains(t, string(main), `import("`+manifest["/js/lazy.js"]+`")`)
+	assert.Contains(t, string(main), `import "./cycle-a.js"`, "modules importing each other keep their names")
+	assert.Contains(t, string(main), `from "react"`)
+
+	dep, err := os.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(manifest["/js/dep.js"])))
+	require.NoError(t, err)
+	assert.Contains(t, string(dep), `new URL("../`+base(manifest["/og.png"])+`"`)
+
+	cycle, err := os.ReadFile(filepath.Join(tmpDir, "js", "cycle-b.js"))
+	require.NoError(t, err)
+	assert.Equal(t, `import "./cycle-a.js"; import "./`+base(manifest["/js/dep.js"])+`";`, string(cycle))
+	assert.NotContains(t, manifest, "/js/cycle-a.js")
+
+	index, err := os.ReadFile(filepath.Join(tmpDir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), `content="`+manifest["/og.png"]+`"`)
+	assert.Contains(t, string(index), `src="`+manifest["/js/main.js"]+`"`)
+}
+
+func TestFingerprinter_Rewrite(t *testing.T) {
+	tmpDir := testutil.WriteSite(t, map[string]string{"css/app.css": `body{}`})
+
+	f := New(tmpDir, Options{})
+	manifest, err := f.Run()
+	require.NoError(t, err)
+
+	// A page exported again still references the asset by its original name
+	page, err := f.Rewrite("blog/index.html", []byte(`<link href="/css/app.css" rel="stylesheet"><link href="../css/app.css?v=2">`))
+	require.NoError(t, err)
+	base := filepath.Base(manifest["/css/app.css"])
+	assert.Equal(t, `<link href="/css/`+base+`" rel="stylesheet"><link href="../css/`+base+`?v=2">`, string(page))
+
+	txt, err := f.Rewrite("notes.txt", []byte(`/css/app.css`))
+	require.NoError(t, err)
+	assert.Equal(t, `/css/app.css`, string(txt))
+}
@@ -0,0 +1,56 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// Hook names, in the order they run.
+const (
+	PreExport   = "pre_export"
+	PostExport  = "post_export"
+	PostRewrite = "post_rewrite"
+)
+
+// HookEnv describes the export to hook commands through environment variables.
+type HookEnv struct {
+	URL       string // BARE_URL
+	OutputDir string // BARE_OUTPUT_DIR, made absolute
+	PageCount int    // BARE_PAGE_COUNT, 0 before the export
+}
+
+// Environ returns the environment of a hook command: the current environment
+// and the BARE_* variables.
+func (e HookEnv) Environ(hook string) []string {
+	outputDir, err := filepath.Abs(e.OutputDir)
+	if err != nil {
+		outputDir = e.OutputDir
+	}
+
+	return append(os.Environ(),
+		"BARE_HOOK="+hook,
+		"BARE_URL="+e.URL,
+		"BARE_OUTPUT_DIR="+outputDir,
+		"BARE_PAGE_COUNT="+strconv.Itoa(e.PageCount),
+	)
+}
+
+// RunHook runs the commands of a hook in order with sh -c, and stops at the first failure.
+func RunHook(ctx context.Context, hook string, commands []string, env HookEnv, stdout, stderr io.Writer) error {
+	for _, command := range commands {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Env = env.Environ(hook)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", hook, command, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHook(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+
+	env := HookEnv{URL: "http://127.0.0.1:8000", OutputDir: dir, PageCount: 12}
+	err := RunHook(context.Background(), PostExport, []string{
+		`echo "$BARE_HOOK $BARE_URL $BARE_PAGE_COUNT"`,
+		`echo "$BARE_OUTPUT_DIR"`,
+	}, env, &stdout, &stdout)
+	require.NoError(t, err)
+
+	assert.Equal(t, "post_export http://127.0.0.1:8000 12\n"+dir+"\n", stdout.String())
+}
+
+func TestRunHook_StopsAtFirstFailure(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+
+	err := RunHook(context.Background(), PreExport, []string{"exit 3", "touch " + marker}, HookEnv{}, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `pre_export hook "exit 3" failed`)
+	assert.NoFileExists(t, marker)
+}
+
+// freePort returns a port nothing listens on.
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+	return port
+}
+
+func TestStartServer(t *testing.T) {
+	python := ""
+	for _, name := range []string{"python3", "python"} {
+		if path, err := exec.LookPath(name); err == nil {
+			python = path
+			break
+		}
+	}
+	if python == "" {
+		t.Skip("requires python to run a server")
+	}
+
+	port := freePort(t)
+	server, err := StartServer(context.Background(), ServerOptions{
+		// The server only starts listening after a delay, like a framework booting
+		Command:  fmt.Sprintf("sleep 0.5 && exec %s -m http.server %d --bind 127.0.0.1", python, port),
+		Dir:      t.TempDir(),
+		ReadyURL: fmt.Sprintf("http://127.0.0.1:%d/", port),
+		Timeout:  10 * time.Second,
+	})
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	conn.Close()
+
+	server.Stop()
+	server.Stop()
+
+	_, err = net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", port), time.Second)
+	assert.Error(t, err, "the server should be stopped")
+}
+
+func TestStartServer_ExitsEarly(t *testing.T) {
+	_, err := StartServer(context.Background(), ServerOptions{
+		Command:  "echo 'port already in use' && exit 1",
+		ReadyURL: fmt.Sprintf("http://127.0.0.1:%d/", freePort(t)),
+		Timeout:  5 * time.Second,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server exited before")
+	assert.Contains(t, err.Error(), "port already in use")
+}
+
+func TestStartServer_Timeout(t *testing.T) {
+	start := time.Now()
+	_, err := StartServer(context.Background(), ServerOptions{
+		Command:  "sleep 30",
+		ReadyURL: fmt.Sprintf("http://127.0.0.1:%d/", freePort(t)),
+		Timeout:  time.Second,
+	})
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "did not respond"), err.Error())
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package lifecycle
+
+import (
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func terminate(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
+
+func kill(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
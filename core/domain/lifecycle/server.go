@@ -0,0 +1,159 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerOptions configures the origin server started around an export.
+type ServerOptions struct {
+	Command  string        // run with sh -c, e.g. "php artisan serve --port 8000"
+	Dir      string        // working directory, defaults to the current one
+	ReadyURL string        // polled until it responds with a status below 500
+	Timeout  time.Duration // how long to wait for ReadyURL, defaults to 30s
+	Output   io.Writer     // receives the server's output, discarded if nil
+}
+
+// Server is a running origin server.
+type Server struct {
+	cmd  *exec.Cmd
+	tail *tailWriter
+	done chan struct{}
+	err  error // exit error, set once done is closed
+
+	stopOnce sync.Once
+}
+
+// pollInterval is the delay between two readiness checks.
+const pollInterval = 250 * time.Millisecond
+
+// StartServer starts the server command and waits for its readiness URL to respond.
+// The server is stopped if it doesn't become ready in time.
+func StartServer(ctx context.Context, opts ServerOptions) (*Server, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+
+	s := &Server{
+		tail: &tailWriter{max: 4096},
+		done: make(chan struct{}),
+	}
+
+	s.cmd = exec.Command("sh", "-c", opts.Command)
+	s.cmd.Dir = opts.Dir
+	s.cmd.Env = os.Environ()
+	s.cmd.Stdout = io.MultiWriter(opts.Output, s.tail)
+	s.cmd.Stderr = s.cmd.Stdout
+	setProcessGroup(s.cmd)
+
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start server: %w", err)
+	}
+	go func() {
+		s.err = s.cmd.Wait()
+		close(s.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	client := &http.Client{Timeout: pollInterval * 4}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if ready(ctx, client, opts.ReadyURL) {
+			return s, nil
+		}
+
+		select {
+		case <-s.done:
+			return nil, s.failure(fmt.Errorf("server exited before %s responded: %v", opts.ReadyURL, s.err))
+		case <-ctx.Done():
+			s.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, s.failure(fmt.Errorf("server did not respond on %s within %s", opts.ReadyURL, opts.Timeout))
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ready checks if the URL responds with a status below 500.
+func ready(ctx context.Context, client *http.Client, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < 500
+}
+
+// Stop terminates the server and the processes it started, politely first.
+// It is safe to call Stop more than once.
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		terminate(s.cmd)
+		select {
+		case <-s.done:
+		case <-time.After(5 * time.Second):
+			kill(s.cmd)
+			<-s.done
+		}
+	})
+}
+
+// failure adds the last lines the server printed to an error.
+func (s *Server) failure(err error) error {
+	output := strings.TrimSpace(s.tail.String())
+	if output == "" {
+		return err
+	}
+	return fmt.Errorf("%w\n%s", err, output)
+}
+
+// tailWriter keeps the last max bytes written to it.
+type tailWriter struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > w.max {
+		w.buf = w.buf[len(w.buf)-w.max:]
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return string(w.buf)
+}
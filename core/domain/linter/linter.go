@@ -13,6 +13,8 @@ import (
 type CheckOptions struct {
 	StatusCode    int
 	RedirectChain []crawler.Redirect
+	Layout        *crawler.Layout
+	DesktopBody   []byte // desktop rendering of the page, when crawling with both device profiles
 }
 
 // NewContext creates a new linting context from raw page data
@@ -29,6 +31,8 @@ func NewContext(body []byte, pageURL *url.URL, analysis *analyzer.Analysis, opts
 		Analysis:      analysis,
 		StatusCode:    opts.StatusCode,
 		RedirectChain: opts.RedirectChain,
+		Layout:        opts.Layout,
+		DesktopBody:   opts.DesktopBody,
 	}, nil
 }
 
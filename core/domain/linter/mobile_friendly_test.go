@@ -0,0 +1,99 @@
+package linter_test
+
+import (
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/linter"
+	_ "github.com/felixdorn/bare/core/domain/linter/rules"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mobilePage = `<!DOCTYPE html>
+<html>
+<head><title>Page Title</title></head>
+<body><h1>Hello</h1><p>Content</p></body>
+</html>`
+
+func TestLinter_HorizontalOverflow(t *testing.T) {
+	pageURL, _ := url.Parse("http://example.com/")
+	lints, err := linter.Check([]byte(mobilePage), pageURL, nil, linter.CheckOptions{
+		Layout: &crawler.Layout{Mobile: true, ViewportWidth: 412, ScrollWidth: 980},
+	})
+	require.NoError(t, err)
+
+	found := findLint(lints, "horizontal-overflow")
+	require.NotNil(t, found, "expected horizontal-overflow lint")
+	assert.Equal(t, linter.MobileFriendly, found.Category)
+	assert.Contains(t, found.Evidence, "980px")
+}
+
+func TestLinter_LayoutRules_OnlyForMobile(t *testing.T) {
+	pageURL, _ := url.Parse("http://example.com/")
+	lints, err := linter.Check([]byte(mobilePage), pageURL, nil, linter.CheckOptions{
+		Layout: &crawler.Layout{
+			Mobile:          false,
+			ViewportWidth:   1920,
+			ScrollWidth:     2000,
+			SmallTapTargets: []string{"a.icon"},
+			SmallText:       []string{"small.legal"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Nil(t, findLint(lints, "horizontal-overflow"))
+	assert.Nil(t, findLint(lints, "small-tap-targets"))
+	assert.Nil(t, findLint(lints, "small-text"))
+}
+
+func TestLinter_SmallTapTargetsAndText(t *testing.T) {
+	pageURL, _ := url.Parse("http://example.com/")
+	lints, err := linter.Check([]byte(mobilePage), pageURL, nil, linter.CheckOptions{
+		Layout: &crawler.Layout{
+			Mobile:          true,
+			ViewportWidth:   412,
+			ScrollWidth:     412,
+			SmallTapTargets: []string{`a.icon "x"`, "button#close"},
+			SmallText:       []string{"small.legal"},
+		},
+	})
+	require.NoError(t, err)
+
+	count := 0
+	for _, l := range lints {
+		if l.Rule == "small-tap-targets" {
+			count++
+		}
+	}
+	assert.Equal(t, 2, count)
+
+	found := findLint(lints, "small-text")
+	require.NotNil(t, found)
+	assert.Equal(t, "small.legal", found.Evidence)
+	assert.Nil(t, findLint(lints, "horizontal-overflow"))
+}
+
+func TestLinter_MobileDesktopMismatch(t *testing.T) {
+	desktop := []byte(`<!DOCTYPE html>
+<html>
+<head><title>Page Title</title></head>
+<body><h1>Welcome</h1><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a></body>
+</html>`)
+
+	pageURL, _ := url.Parse("http://example.com/")
+	lints, err := linter.Check([]byte(mobilePage), pageURL, nil, linter.CheckOptions{
+		DesktopBody: desktop,
+	})
+	require.NoError(t, err)
+
+	var evidence []string
+	for _, l := range lints {
+		if l.Rule == "mobile-desktop-mismatch" {
+			evidence = append(evidence, l.Evidence)
+		}
+	}
+	assert.Contains(t, evidence, `H1: "Hello" on mobile, "Welcome" on desktop`)
+	assert.Contains(t, evidence, "0 links on mobile, 3 on desktop")
+}
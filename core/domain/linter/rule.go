@@ -15,6 +15,8 @@ type Context struct {
 	Analysis      *analyzer.Analysis
 	StatusCode    int
 	RedirectChain []crawler.Redirect
+	Layout        *crawler.Layout
+	DesktopBody   []byte
 }
 
 // Lint is a single issue found by a rule
@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/felixdorn/bare/core/domain/linter"
+)
+
+func init() {
+	// Layout rules judge the rendered page and only run when it was
+	// rendered with a mobile device profile.
+	horizontalOverflow := &linter.Rule{
+		ID:       "horizontal-overflow",
+		Name:     "Content is wider than the mobile viewport",
+		Severity: linter.High,
+		Category: linter.MobileFriendly,
+		Tag:      linter.Issue,
+	}
+	horizontalOverflow.Check = func(ctx *linter.Context) []linter.Lint {
+		if ctx.Layout == nil || !ctx.Layout.Mobile {
+			return nil
+		}
+		if ctx.Layout.ScrollWidth > ctx.Layout.ViewportWidth {
+			return []linter.Lint{horizontalOverflow.Emit(fmt.Sprintf(
+				"Page is %dpx wide, viewport is %dpx", ctx.Layout.ScrollWidth, ctx.Layout.ViewportWidth,
+			))}
+		}
+		return nil
+	}
+	linter.Register(horizontalOverflow)
+
+	smallTapTargets := &linter.Rule{
+		ID:       "small-tap-targets",
+		Name:     "Tap targets are too small",
+		Severity: linter.Medium,
+		Category: linter.MobileFriendly,
+		Tag:      linter.Issue,
+	}
+	smallTapTargets.Check = func(ctx *linter.Context) []linter.Lint {
+		if ctx.Layout == nil || !ctx.Layout.Mobile {
+			return nil
+		}
+		var lints []linter.Lint
+		for _, target := range ctx.Layout.SmallTapTargets {
+			lints = append(lints, smallTapTargets.Emit(target))
+		}
+		return lints
+	}
+	linter.Register(smallTapTargets)
+
+	smallText := &linter.Rule{
+		ID:       "small-text",
+		Name:     "Text is too small to read on mobile",
+		Severity: linter.Medium,
+		Category: linter.MobileFriendly,
+		Tag:      linter.Issue,
+	}
+	smallText.Check = func(ctx *linter.Context) []linter.Lint {
+		if ctx.Layout == nil || !ctx.Layout.Mobile {
+			return nil
+		}
+		var lints []linter.Lint
+		for _, text := range ctx.Layout.SmallText {
+			lints = append(lints, smallText.Emit(text))
+		}
+		return lints
+	}
+	linter.Register(smallText)
+
+	contentMismatch := &linter.Rule{
+		ID:       "mobile-desktop-mismatch",
+		Name:     "Mobile and desktop versions differ",
+		Severity: linter.High,
+		Category: linter.MobileFriendly,
+		Tag:      linter.PotentialIssue,
+	}
+	contentMismatch.Check = func(ctx *linter.Context) []linter.Lint {
+		if len(ctx.DesktopBody) == 0 {
+			return nil
+		}
+		desktop, err := goquery.NewDocumentFromReader(bytes.NewReader(ctx.DesktopBody))
+		if err != nil {
+			return nil
+		}
+
+		var lints []linter.Lint
+
+		mobileTitle := strings.TrimSpace(ctx.Doc.Find("head title").First().Text())
+		desktopTitle := strings.TrimSpace(desktop.Find("head title").First().Text())
+		if mobileTitle != desktopTitle {
+			lints = append(lints, contentMismatch.Emit(fmt.Sprintf("Title: %q on mobile, %q on desktop", mobileTitle, desktopTitle)))
+		}
+
+		mobileH1 := strings.TrimSpace(ctx.Doc.Find("h1").First().Text())
+		desktopH1 := strings.TrimSpace(desktop.Find("h1").First().Text())
+		if mobileH1 != desktopH1 {
+			lints = append(lints, contentMismatch.Emit(fmt.Sprintf("H1: %q on mobile, %q on desktop", mobileH1, desktopH1)))
+		}
+
+		// Mobile-first indexing only sees the mobile version, so losing a large
+		// share of the links or text there hides it from search engines.
+		mobileLinks := ctx.Doc.Find("a[href]").Length()
+		desktopLinks := desktop.Find("a[href]").Length()
+		if desktopLinks > 0 && mobileLinks*2 < desktopLinks {
+			lints = append(lints, contentMismatch.Emit(fmt.Sprintf("%d links on mobile, %d on desktop", mobileLinks, desktopLinks)))
+		}
+
+		mobileWords := len(strings.Fields(ctx.Doc.Find("body").Text()))
+		desktopWords := len(strings.Fields(desktop.Find("body").Text()))
+		if desktopWords > 0 && mobileWords*2 < desktopWords {
+			lints = append(lints, contentMismatch.Emit(fmt.Sprintf("%d words on mobile, %d on desktop", mobileWords, desktopWords)))
+		}
+
+		return lints
+	}
+	linter.Register(contentMismatch)
+}
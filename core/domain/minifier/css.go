@@ -0,0 +1,127 @@
+package minifier
+
+import (
+	"strings"
+)
+
+// CSS minifies a stylesheet: comments are removed (except /*! license comments),
+// whitespace is collapsed, and dropped entirely around characters where it is never significant.
+// Strings are copied verbatim.
+func CSS(src []byte) []byte {
+	var out strings.Builder
+	out.Grow(len(src))
+
+	s := string(src)
+	pendingSpace := false
+	// A semicolon is written with the next token, the last one of a block is dropped
+	pendingSemicolon := false
+
+	// last returns the last byte written, or 0.
+	last := func() byte {
+		str := out.String()
+		if len(str) == 0 {
+			return 0
+		}
+		return str[len(str)-1]
+	}
+
+	flushSemicolon := func() {
+		if pendingSemicolon {
+			out.WriteByte(';')
+			pendingSemicolon = false
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				end = len(s) - i - 2
+			}
+			comment := s[i : i+2+end]
+			if strings.HasPrefix(comment, "/*!") {
+				flushSemicolon()
+				flushSpace(&out, &pendingSpace, last(), '/')
+				out.WriteString(comment)
+				out.WriteString("*/")
+				// Skip the whitespace following the comment
+				for i+2+end+2 < len(s) && isSpace(s[i+2+end+2]) {
+					end++
+				}
+			} else {
+				// A comment separates tokens, "a/**/b" must not become "ab"
+				pendingSpace = true
+			}
+			i += 2 + end + 1
+			continue
+
+		case c == '"' || c == '\'':
+			flushSemicolon()
+			flushSpace(&out, &pendingSpace, last(), c)
+			j := i + 1
+			for j < len(s) && s[j] != c {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				j = len(s) - 1
+			}
+			out.WriteString(s[i : j+1])
+			i = j
+			continue
+
+		case isSpace(c):
+			pendingSpace = true
+			continue
+
+		case c == ';':
+			flushSemicolon()
+			pendingSemicolon = true
+			pendingSpace = false
+			continue
+
+		case c == '}':
+			// The last declaration of a block doesn't need its semicolon
+			pendingSemicolon = false
+		}
+
+		flushSemicolon()
+		flushSpace(&out, &pendingSpace, last(), c)
+		out.WriteByte(c)
+	}
+
+	flushSemicolon()
+	return []byte(strings.TrimSpace(out.String()))
+}
+
+// flushSpace writes a pending space unless it is insignificant between prev and next.
+func flushSpace(out *strings.Builder, pending *bool, prev, next byte) {
+	if !*pending {
+		return
+	}
+	*pending = false
+	if prev == 0 || isPunctuation(prev) || isPunctuation(next) || prev == ':' {
+		return
+	}
+	out.WriteByte(' ')
+}
+
+// isPunctuation reports whether whitespace around c is never significant.
+// ':' is not included since "a :hover" and "a:hover" are different selectors,
+// nor are '+' and '-' since calc() requires spaces around them.
+func isPunctuation(c byte) bool {
+	switch c {
+	case '{', '}', ';', ',', '>':
+		return true
+	}
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,104 @@
+package minifier
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var whitespaceRun = regexp.MustCompile(`[ \t\n\r\f]+`)
+
+// HTML minifies an HTML document: whitespace runs in text are collapsed to a single space,
+// comments are stripped except conditional comments, and inline <style> blocks are minified.
+// Tags are copied verbatim, and the content of <pre>, <textarea>, <script> and <style>
+// is never collapsed.
+func HTML(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+
+	z := html.NewTokenizer(bytes.NewReader(src))
+	preserve := 0 // depth of <pre> and <textarea> elements
+	var rawTag string
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+
+		switch tt {
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "pre", "textarea":
+				preserve++
+			case "script", "style":
+				rawTag = string(name)
+			}
+			out.Write(raw)
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "pre", "textarea":
+				if preserve > 0 {
+					preserve--
+				}
+			case "script", "style":
+				rawTag = ""
+			}
+			out.Write(raw)
+
+		case html.CommentToken:
+			if isConditionalComment(raw) {
+				out.Write(raw)
+			}
+
+		case html.TextToken:
+			switch {
+			case rawTag == "style":
+				out.Write(CSS(raw))
+			case rawTag != "" || preserve > 0:
+				out.Write(raw)
+			default:
+				out.Write(whitespaceRun.ReplaceAll(raw, []byte(" ")))
+			}
+
+		default:
+			out.Write(raw)
+		}
+	}
+
+	return collapseBetweenTags(out.Bytes())
+}
+
+// isConditionalComment checks for Internet Explorer conditional comments,
+// which carry markup and must survive minification.
+func isConditionalComment(raw []byte) bool {
+	s := string(raw)
+	return strings.HasPrefix(s, "<!--[if") || strings.HasPrefix(s, "<![endif]") || strings.HasSuffix(s, "<![endif]-->")
+}
+
+// blockTags are elements around which whitespace never renders.
+var blockTags = regexp.MustCompile(`(?i)\s*(</?(?:html|head|body|title|meta|link|base|div|p|ul|ol|li|dl|dt|dd|table|thead|tbody|tfoot|tr|td|th|header|footer|main|nav|section|article|aside|h[1-6]|form|fieldset|figure|figcaption|blockquote|hr|br|noscript|!doctype)\b[^>]*>)\s*`)
+
+// protectedElements are elements whose content is never collapsed.
+var protectedElements = regexp.MustCompile(`(?is)<(pre|textarea|script|style)\b.*?</(?:pre|textarea|script|style)\s*>`)
+
+// collapseBetweenTags drops the remaining single spaces around block-level tags.
+// Content inside <pre>, <textarea>, <script> and <style> is left alone.
+func collapseBetweenTags(doc []byte) []byte {
+	var out bytes.Buffer
+	last := 0
+	for _, loc := range protectedElements.FindAllIndex(doc, -1) {
+		out.Write(blockTags.ReplaceAll(doc[last:loc[0]], []byte("$1")))
+		out.Write(doc[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.Write(blockTags.ReplaceAll(doc[last:], []byte("$1")))
+
+	return bytes.TrimSpace(out.Bytes())
+}
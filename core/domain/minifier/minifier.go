@@ -0,0 +1,131 @@
+package minifier
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// Options configures the Minifier.
+type Options struct {
+	HTML    bool
+	CSS     bool
+	Exclude url.Paths // URL paths that are left untouched
+	// Skip, if set, reports whether a URL path is left untouched, in addition to Exclude.
+	Skip func(path string) bool
+}
+
+// Stats summarizes a minification run.
+type Stats struct {
+	Files       int
+	BytesBefore int64
+	BytesAfter  int64
+}
+
+// Saved returns the fraction of bytes saved, between 0 and 1.
+func (s *Stats) Saved() float64 {
+	if s.BytesBefore == 0 {
+		return 0
+	}
+	return 1 - float64(s.BytesAfter)/float64(s.BytesBefore)
+}
+
+// Minifier walks a directory of exported files and minifies HTML and CSS files in place.
+type Minifier struct {
+	OutputDir string
+	opts      Options
+}
+
+// New creates a new Minifier instance.
+func New(outputDir string, opts Options) *Minifier {
+	return &Minifier{
+		OutputDir: outputDir,
+		opts:      opts,
+	}
+}
+
+// Run minifies every eligible file in the output directory.
+func (m *Minifier) Run() (*Stats, error) {
+	stats := &Stats{}
+
+	err := filepath.WalkDir(m.OutputDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.OutputDir, path)
+		if err != nil {
+			return err
+		}
+		minify := m.minifier(filepath.ToSlash(rel))
+		if minify == nil {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		minified := minify(content)
+
+		stats.Files++
+		stats.BytesBefore += int64(len(content))
+		stats.BytesAfter += int64(len(minified))
+
+		if len(minified) == len(content) {
+			return nil
+		}
+		return os.WriteFile(path, minified, 0644)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Minify returns content minified, the content of the file at the slash-separated path
+// rel in the output directory, e.g. a page exported again. Files Run would leave
+// untouched are returned as is.
+func (m *Minifier) Minify(rel string, content []byte) []byte {
+	if minify := m.minifier(rel); minify != nil {
+		return minify(content)
+	}
+	return content
+}
+
+// minifier returns the function minifying the file at the slash-separated path rel,
+// or nil if the file is left untouched.
+func (m *Minifier) minifier(rel string) func([]byte) []byte {
+	var minify func([]byte) []byte
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".html", ".htm":
+		if m.opts.HTML {
+			minify = HTML
+		}
+	case ".css":
+		if m.opts.CSS {
+			minify = CSS
+		}
+	}
+	if minify == nil {
+		return nil
+	}
+
+	// Pages are excluded by their URL, so /about matches about/index.html too
+	filePath := "/" + strings.TrimPrefix(rel, "/")
+	pagePath := strings.TrimSuffix(filePath, "index.html")
+	if m.opts.Exclude.MatchAny(filePath) || m.opts.Exclude.MatchAny(pagePath) {
+		return nil
+	}
+	if m.opts.Skip != nil && (m.opts.Skip(filePath) || m.opts.Skip(pagePath)) {
+		return nil
+	}
+	return minify
+}
@@ -0,0 +1,154 @@
+package minifier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTML(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "collapses whitespace between blocks",
+			in:   "<!DOCTYPE html>\n<html>\n  <head>\n    <title>  Hello   World </title>\n  </head>\n  <body>\n    <p>Some   <b>bold</b>\n text</p>\n  </body>\n</html>\n",
+			want: "<!DOCTYPE html><html><head><title>Hello World</title></head><body><p>Some <b>bold</b> text</p></body></html>",
+		},
+		{
+			name: "strips comments",
+			in:   "<p>a</p><!-- build: 1234 --><p>b</p>",
+			want: "<p>a</p><p>b</p>",
+		},
+		{
+			name: "keeps conditional comments",
+			in:   "<head><!--[if lt IE 9]><script src=\"html5shiv.js\"></script><![endif]--></head>",
+			want: "<head><!--[if lt IE 9]><script src=\"html5shiv.js\"></script><![endif]--></head>",
+		},
+		{
+			name: "keeps pre and textarea intact",
+			in:   "<div>\n  <pre>  line 1\n    line 2</pre>\n  <textarea>\n  keep   me\n</textarea>\n</div>",
+			want: "<div><pre>  line 1\n    line 2</pre> <textarea>\n  keep   me\n</textarea></div>",
+		},
+		{
+			name: "keeps scripts intact",
+			in:   "<script>\n  var a  =  1; // comment\n</script>",
+			want: "<script>\n  var a  =  1; // comment\n</script>",
+		},
+		{
+			name: "minifies inline styles",
+			in:   "<style>\n  body {\n    color : red;\n  }\n</style>",
+			want: "<style>body{color :red}</style>",
+		},
+		{
+			name: "keeps attributes verbatim",
+			in:   `<a   href="/a  b"   class="x">link</a>`,
+			want: `<a   href="/a  b"   class="x">link</a>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, string(HTML([]byte(tc.in))))
+		})
+	}
+}
+
+func TestCSS(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "removes whitespace and comments",
+			in:   "/* header */\nbody {\n  margin: 0;\n  padding: 0 ;\n}\n\nh1,\nh2 > span {\n  color: red;\n}\n",
+			want: "body{margin:0;padding:0}h1,h2>span{color:red}",
+		},
+		{
+			name: "keeps license comments",
+			in:   "/*! MIT License */\na { color: red }",
+			want: "/*! MIT License */a{color:red}",
+		},
+		{
+			name: "keeps strings",
+			in:   `a::before { content: "  {  ;  }  "; }`,
+			want: `a::before{content:"  {  ;  }  "}`,
+		},
+		{
+			name: "keeps significant spaces",
+			in:   "div :hover { width: calc(100% - 2px); margin: 0 auto; }",
+			want: "div :hover{width:calc(100% - 2px);margin:0 auto}",
+		},
+		{
+			name: "comments separate tokens",
+			in:   "a/**/b { color: red }",
+			want: "a b{color:red}",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, string(CSS([]byte(tc.in))))
+		})
+	}
+}
+
+func TestMinifier_Run(t *testing.T) {
+	tmpDir := testutil.WriteSite(t, map[string]string{
+		"index.html":        "<html>\n  <body>\n    <p>Hello</p>\n  </body>\n</html>\n",
+		"legacy/index.html": "<html>\n  <body>\n    <p>Legacy</p>\n  </body>\n</html>\n",
+		"style.css":         "body {\n  color: red;\n}\n",
+		"app.js":            "var a  =  1;\n",
+	})
+
+	stats, err := New(tmpDir, Options{HTML: true, CSS: true, Exclude: url.Paths{"/legacy"}}).Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, stats.Files)
+	assert.Less(t, stats.BytesAfter, stats.BytesBefore)
+	assert.Greater(t, stats.Saved(), 0.0)
+
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	assert.Equal(t, "<html><body><p>Hello</p></body></html>", read("index.html"))
+	assert.Equal(t, "body{color:red}", read("style.css"))
+	assert.Equal(t, "<html>\n  <body>\n    <p>Legacy</p>\n  </body>\n</html>\n", read("legacy/index.html"), "excluded pages are untouched")
+	assert.Equal(t, "var a  =  1;\n", read("app.js"), "JavaScript is not minified")
+}
+
+func TestMinifier_Run_Skip(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "app"), 0755))
+	page := "<html>\n  <body></body>\n</html>\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "index.html"), []byte(page), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "app", "index.html"), []byte(page), 0644))
+
+	stats, err := New(tmpDir, Options{HTML: true, Skip: func(p string) bool { return p == "/app/" }}).Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, stats.Files)
+	content, err := os.ReadFile(filepath.Join(tmpDir, "app", "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, page, string(content), "skipped pages are untouched")
+}
+
+func TestMinifier_Minify(t *testing.T) {
+	m := New(t.TempDir(), Options{HTML: true, Exclude: url.Paths{"/legacy"}})
+	page := []byte("<html>\n  <body></body>\n</html>\n")
+
+	assert.Equal(t, "<html><body></body></html>", string(m.Minify("about/index.html", page)))
+	assert.Equal(t, string(page), string(m.Minify("legacy/index.html", page)), "excluded pages are untouched")
+	assert.Equal(t, "body {\n}\n", string(m.Minify("style.css", []byte("body {\n}\n"))), "CSS is not enabled")
+}
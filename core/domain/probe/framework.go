@@ -0,0 +1,193 @@
+package probe
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// Framework is the framework or CMS a site is built with.
+type Framework struct {
+	Name     string
+	Evidence string    // what gave it away, e.g. "laravel_session cookie"
+	Exclude  url.Paths // paths of the framework that are never part of an export
+	Command  string    // command serving the site locally, if the framework has one
+}
+
+// detector recognizes a framework from the headers and the markup of the home page.
+type detector struct {
+	framework Framework
+	detect    func(header http.Header, body string) string // returns the evidence, if detected
+}
+
+// detectors are tried in order, more specific frameworks first.
+var detectors = []detector{
+	{
+		framework: Framework{Name: "WordPress", Exclude: url.Paths{"/wp-admin/**", "/wp-json/**", "/wp-login.php", "/xmlrpc.php"}},
+		detect: func(header http.Header, body string) string {
+			switch {
+			case strings.Contains(header.Get("Link"), "api.w.org"):
+				return "api.w.org Link header"
+			case hasGenerator(body, "WordPress"):
+				return "generator meta tag"
+			case strings.Contains(body, "/wp-content/"):
+				return "/wp-content/ assets"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Drupal", Exclude: url.Paths{"/user/**", "/admin/**", "/node/add/**"}},
+		detect: func(header http.Header, body string) string {
+			switch {
+			case strings.Contains(header.Get("X-Generator"), "Drupal"):
+				return "X-Generator header"
+			case hasGenerator(body, "Drupal"):
+				return "generator meta tag"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Laravel", Exclude: url.Paths{"/admin/**", "/api/**", "/nova/**", "/horizon/**", "/telescope/**"}, Command: "php artisan serve --port 8000"},
+		detect: func(header http.Header, body string) string {
+			if hasCookie(header, "laravel_session") {
+				return "laravel_session cookie"
+			}
+			if hasCookie(header, "XSRF-TOKEN") && strings.Contains(body, `name="csrf-token"`) {
+				return "XSRF-TOKEN cookie and csrf-token meta tag"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Django", Exclude: url.Paths{"/admin/**", "/api/**"}, Command: "python manage.py runserver 8000"},
+		detect: func(header http.Header, body string) string {
+			switch {
+			case hasCookie(header, "csrftoken"):
+				return "csrftoken cookie"
+			case strings.Contains(body, "csrfmiddlewaretoken"):
+				return "csrfmiddlewaretoken form field"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Ruby on Rails", Exclude: url.Paths{"/admin/**", "/api/**", "/rails/**"}, Command: "bin/rails server -p 8000"},
+		detect: func(header http.Header, body string) string {
+			if strings.Contains(body, `name="csrf-param" content="authenticity_token"`) {
+				return "authenticity_token csrf-param meta tag"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Next.js", Exclude: url.Paths{"/api/**"}, Command: "npm run dev -- --port 8000"},
+		detect: func(header http.Header, body string) string {
+			switch {
+			case strings.Contains(header.Get("X-Powered-By"), "Next.js"):
+				return "X-Powered-By header"
+			case strings.Contains(body, "__NEXT_DATA__"), strings.Contains(body, "/_next/"):
+				return "/_next/ assets"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Nuxt", Exclude: url.Paths{"/api/**"}, Command: "npm run dev -- --port 8000"},
+		detect: func(header http.Header, body string) string {
+			if strings.Contains(body, "__NUXT__") || strings.Contains(body, "/_nuxt/") {
+				return "/_nuxt/ assets"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "SvelteKit", Exclude: url.Paths{"/api/**"}},
+		detect: func(header http.Header, body string) string {
+			if strings.Contains(body, "__sveltekit") || strings.Contains(body, "/_app/immutable/") {
+				return "/_app/immutable/ assets"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Gatsby"},
+		detect: func(header http.Header, body string) string {
+			if strings.Contains(body, `id="___gatsby"`) {
+				return "___gatsby root element"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Astro"},
+		detect: func(header http.Header, body string) string {
+			switch {
+			case hasGenerator(body, "Astro"):
+				return "generator meta tag"
+			case strings.Contains(body, "<astro-island"):
+				return "astro-island elements"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Hugo"},
+		detect: func(header http.Header, body string) string {
+			if hasGenerator(body, "Hugo") {
+				return "generator meta tag"
+			}
+			return ""
+		},
+	},
+	{
+		framework: Framework{Name: "Express", Exclude: url.Paths{"/api/**"}},
+		detect: func(header http.Header, body string) string {
+			if header.Get("X-Powered-By") == "Express" {
+				return "X-Powered-By header"
+			}
+			return ""
+		},
+	},
+}
+
+// detectFramework returns the first framework detected, or nil.
+func detectFramework(header http.Header, body string) *Framework {
+	for _, d := range detectors {
+		if evidence := d.detect(header, body); evidence != "" {
+			f := d.framework
+			f.Evidence = evidence
+			return &f
+		}
+	}
+	return nil
+}
+
+func hasGenerator(body, name string) bool {
+	lower := strings.ToLower(body)
+	i := strings.Index(lower, `name="generator"`)
+	if i == -1 {
+		return false
+	}
+	end := strings.Index(lower[i:], ">")
+	if end == -1 {
+		end = len(lower) - i
+	}
+	// The content attribute can come before the name attribute
+	start := strings.LastIndex(lower[:i], "<meta")
+	if start == -1 {
+		start = i
+	}
+	return strings.Contains(lower[start:i+end], strings.ToLower(name))
+}
+
+func hasCookie(header http.Header, name string) bool {
+	for _, cookie := range header.Values("Set-Cookie") {
+		if strings.HasPrefix(cookie, name+"=") {
+			return true
+		}
+	}
+	return false
+}
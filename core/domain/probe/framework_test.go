@@ -0,0 +1,64 @@
+package probe
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectFramework(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   http.Header
+		body     string
+		expected string
+		evidence string
+	}{
+		{
+			name:     "WordPress from its Link header",
+			header:   http.Header{"Link": {`<https://example.com/wp-json/>; rel="https://api.w.org/"`}},
+			expected: "WordPress",
+			evidence: "api.w.org Link header",
+		},
+		{
+			name:     "generator with content first",
+			body:     `<meta content="Hugo 0.120.4" name="generator">`,
+			expected: "Hugo",
+			evidence: "generator meta tag",
+		},
+		{
+			name:     "Django from its form field",
+			body:     `<form><input type="hidden" name="csrfmiddlewaretoken" value="x"></form>`,
+			expected: "Django",
+			evidence: "csrfmiddlewaretoken form field",
+		},
+		{
+			name:     "Next.js from its assets",
+			body:     `<script src="/_next/static/chunks/main.js"></script>`,
+			expected: "Next.js",
+			evidence: "/_next/ assets",
+		},
+		{
+			name: "generator of another page element",
+			body: `<meta name="generator" content="Jekyll"><p>Built with Hugo</p>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.header == nil {
+				tc.header = http.Header{}
+			}
+			f := detectFramework(tc.header, tc.body)
+			if tc.expected == "" {
+				assert.Nil(t, f)
+				return
+			}
+			if assert.NotNil(t, f) {
+				assert.Equal(t, tc.expected, f.Name)
+				assert.Equal(t, tc.evidence, f.Evidence)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/felixdorn/bare/core/domain/analyzer"
+	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// sitemapPaths are the usual locations of sitemaps, tried when robots.txt lists none.
+var sitemapPaths = []string{"/sitemap.xml", "/sitemap_index.xml", "/wp-sitemap.xml"}
+
+// privatePaths are sections of a site that are excluded from an export when they exist.
+var privatePaths = []string{"/admin", "/api", "/login", "/logout", "/register", "/account", "/cart", "/checkout"}
+
+// Options configures the Probe.
+type Options struct {
+	Fetcher crawler.Fetcher // fetches over HTTP, defaults to an HTTPFetcher
+	// Render, if set, returns the HTML of a page rendered with JavaScript, which is
+	// compared to its HTML over HTTP to recommend js.enabled.
+	Render func(ctx context.Context, u *url.URL) ([]byte, error)
+}
+
+// Probe inspects a running site to propose a configuration.
+type Probe struct {
+	URL  *url.URL
+	opts Options
+}
+
+// Report is what the probe found out about a site.
+type Report struct {
+	Framework   *Framework // nil if none was detected
+	Robots      bool       // whether the site has a robots.txt
+	Disallowed  []string   // paths disallowed to every user agent in robots.txt
+	Sitemaps    []string   // paths of the sitemaps
+	Entrypoints url.Paths
+	Exclude     url.Paths
+	JS          JSVerdict
+	PublicURL   *url.URL // origin of the canonical URL of the home page, if it's another one
+	AssetHosts  []string // other hosts serving assets of the home page, which are not exported
+}
+
+// JSVerdict is the recommendation for js.enabled.
+type JSVerdict struct {
+	Recommended bool
+	Reason      string
+	Rendered    bool // whether the home page was rendered with JavaScript to decide
+}
+
+// New creates a new Probe for the site at u.
+func New(u *url.URL, opts Options) *Probe {
+	if opts.Fetcher == nil {
+		opts.Fetcher = crawler.NewHTTPFetcher(nil)
+	}
+	return &Probe{URL: u, opts: opts}
+}
+
+// Run inspects the home page, robots.txt and the sitemaps of the site.
+func (p *Probe) Run(ctx context.Context) (*Report, error) {
+	home, err := p.opts.Fetcher.Fetch(ctx, p.URL)
+	if err != nil {
+		return nil, err
+	}
+	if home.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s responded with status %d", p.URL, home.StatusCode)
+	}
+
+	report := &Report{
+		Framework:   detectFramework(home.Header, string(home.Body)),
+		Entrypoints: url.Paths{"/"},
+	}
+
+	p.readRobots(ctx, report)
+	p.findSitemaps(ctx, report)
+	for _, sitemap := range report.Sitemaps {
+		report.Entrypoints = append(report.Entrypoints, url.Path(sitemap))
+	}
+	if report.Robots {
+		report.Entrypoints = append(report.Entrypoints, "/robots.txt")
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(home.Body))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", p.URL, err)
+	}
+	p.proposeExcludes(ctx, report)
+	p.findHosts(doc, report)
+	report.JS = p.judgeJS(ctx, home.Body, doc)
+
+	return report, nil
+}
+
+// fetch returns the response to a path of the site, or nil if it can't be reached.
+func (p *Probe) fetch(ctx context.Context, path string) *crawler.FetchResult {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil
+	}
+	result, err := p.opts.Fetcher.Fetch(ctx, p.URL.ResolveReference(u))
+	if err != nil {
+		return nil
+	}
+	return result
+}
+
+// readRobots reads the sitemaps and the paths disallowed to every user agent from robots.txt.
+func (p *Probe) readRobots(ctx context.Context, report *Report) {
+	robots := p.fetch(ctx, "/robots.txt")
+	if robots == nil || robots.StatusCode != http.StatusOK {
+		return
+	}
+	report.Robots = true
+
+	everyone := false
+	scanner := bufio.NewScanner(bytes.NewReader(robots.Body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "user-agent":
+			everyone = value == "*"
+		case "disallow":
+			if everyone && value != "" {
+				report.Disallowed = append(report.Disallowed, value)
+			}
+		case "sitemap":
+			if path, ok := p.sameSitePath(value); ok {
+				report.Sitemaps = append(report.Sitemaps, path)
+			}
+		}
+	}
+}
+
+// findSitemaps tries the usual locations of sitemaps when robots.txt lists none.
+func (p *Probe) findSitemaps(ctx context.Context, report *Report) {
+	if len(report.Sitemaps) > 0 {
+		return
+	}
+	for _, path := range sitemapPaths {
+		result := p.fetch(ctx, path)
+		if result == nil || result.StatusCode != http.StatusOK {
+			continue
+		}
+		if bytes.Contains(result.Body, []byte("<urlset")) || bytes.Contains(result.Body, []byte("<sitemapindex")) {
+			report.Sitemaps = append(report.Sitemaps, path)
+			return
+		}
+	}
+}
+
+// proposeExcludes proposes excluding the paths disallowed in robots.txt, the private
+// paths of the framework and the usual private sections that exist on the site.
+func (p *Probe) proposeExcludes(ctx context.Context, report *Report) {
+	seen := make(map[url.Path]bool)
+	add := func(pattern url.Path) {
+		if !seen[pattern] {
+			seen[pattern] = true
+			report.Exclude = append(report.Exclude, pattern)
+		}
+	}
+
+	for _, disallowed := range report.Disallowed {
+		// Disallow matches prefixes and supports its own wildcards, only plain paths are kept
+		trimmed := strings.TrimSuffix(disallowed, "/")
+		if trimmed == "" || strings.ContainsAny(disallowed, "*$?") {
+			continue
+		}
+		add(url.Path(trimmed + "/**"))
+	}
+	if report.Framework != nil {
+		for _, pattern := range report.Framework.Exclude {
+			add(pattern)
+		}
+	}
+
+	// A site answering any path, like an SPA fallback, only gives away private sections by refusing them
+	catchAll := false
+	if result := p.fetch(ctx, "/bare-probe-missing-page"); result != nil && result.StatusCode < 400 {
+		catchAll = true
+	}
+	for _, path := range privatePaths {
+		if seen[url.Path(path+"/**")] {
+			continue
+		}
+		result := p.fetch(ctx, path)
+		if result == nil {
+			continue
+		}
+		// Login pages often answer private sections with a redirect, which is followed
+		refused := result.StatusCode == http.StatusUnauthorized || result.StatusCode == http.StatusForbidden
+		if refused || (!catchAll && result.StatusCode < 400) {
+			add(url.Path(path + "/**"))
+		}
+	}
+
+	sort.Slice(report.Exclude, func(i, j int) bool { return report.Exclude[i] < report.Exclude[j] })
+}
+
+// findHosts finds the public origin of the site, from its canonical URL, and the
+// other hosts serving its assets.
+func (p *Probe) findHosts(doc *goquery.Document, report *Report) {
+	for _, selector := range []string{`head link[rel="canonical"]`, `head meta[property="og:url"]`} {
+		s := doc.Find(selector).First()
+		href := s.AttrOr("href", s.AttrOr("content", ""))
+		u, err := url.Parse(href)
+		if err != nil || u.Host == "" || strings.EqualFold(u.Host, p.URL.Host) {
+			continue
+		}
+		report.PublicURL, _ = url.Parse(u.Scheme + "://" + u.Host)
+		break
+	}
+
+	hosts := make(map[string]bool)
+	doc.Find("script[src], link[href], img[src]").Each(func(i int, s *goquery.Selection) {
+		if s.Is("link") && !s.Is(`[rel~="stylesheet"], [rel~="preload"], [rel~="icon"], [rel~="modulepreload"]`) {
+			return
+		}
+		ref := s.AttrOr("src", s.AttrOr("href", ""))
+		u, err := url.Parse(ref)
+		if err != nil || u.Host == "" || strings.EqualFold(u.Host, p.URL.Host) {
+			return
+		}
+		if report.PublicURL != nil && strings.EqualFold(u.Host, report.PublicURL.Host) {
+			return
+		}
+		hosts[u.Host] = true
+	})
+	for host := range hosts {
+		report.AssetHosts = append(report.AssetHosts, host)
+	}
+	sort.Strings(report.AssetHosts)
+}
+
+// judgeJS recommends js.enabled when rendering the home page with JavaScript reveals
+// more links or content, or, without a renderer, when it looks like an empty shell.
+func (p *Probe) judgeJS(ctx context.Context, body []byte, doc *goquery.Document) JSVerdict {
+	links := countLinks(doc)
+	text := textLength(body)
+
+	if p.opts.Render != nil {
+		rendered, err := p.opts.Render(ctx, p.URL)
+		if err == nil {
+			if renderedDoc, err := goquery.NewDocumentFromReader(bytes.NewReader(rendered)); err == nil {
+				renderedLinks, renderedText := countLinks(renderedDoc), textLength(rendered)
+				verdict := JSVerdict{Rendered: true}
+				switch {
+				case renderedLinks > links+links/5+2:
+					verdict.Recommended = true
+					verdict.Reason = fmt.Sprintf("JavaScript adds links: %d over HTTP, %d rendered", links, renderedLinks)
+				case renderedText > text+text/2+200:
+					verdict.Recommended = true
+					verdict.Reason = fmt.Sprintf("JavaScript adds content: %d characters over HTTP, %d rendered", text, renderedText)
+				default:
+					verdict.Reason = "pages look the same with and without JavaScript"
+				}
+				return verdict
+			}
+		}
+	}
+
+	if text < 200 && doc.Find(`#root, #app, #__next, #__nuxt, #___gatsby, [data-reactroot]`).Length() > 0 {
+		return JSVerdict{Recommended: true, Reason: "the home page is an almost empty application shell without JavaScript"}
+	}
+	return JSVerdict{Reason: "the home page has its content without JavaScript"}
+}
+
+// sameSitePath returns the path of an absolute or relative URL on the probed site.
+func (p *Probe) sameSitePath(ref string) (string, bool) {
+	u, err := url.Parse(ref)
+	if err != nil || (u.Host != "" && !strings.EqualFold(u.Hostname(), p.URL.Hostname())) {
+		return "", false
+	}
+	return u.Path, u.Path != ""
+}
+
+func countLinks(doc *goquery.Document) int {
+	return doc.Find("a[href]").Length()
+}
+
+func textLength(body []byte) int {
+	content, err := analyzer.ExtractContent(body, analyzer.ContentSelectors{})
+	if err != nil {
+		return 0
+	}
+	return len(content.Text)
+}
@@ -0,0 +1,119 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runProbe(t *testing.T, pages map[string]string, render func(ctx context.Context, u *url.URL) ([]byte, error)) *Report {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.SetCookie(w, &http.Cookie{Name: "laravel_session", Value: "abc"})
+		}
+		if r.URL.Path == "/api" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+
+	report, err := New(u, Options{Render: render}).Run(context.Background())
+	require.NoError(t, err)
+	return report
+}
+
+func TestProbe_Run(t *testing.T) {
+	report := runProbe(t, map[string]string{
+		"/": `<html><head>
+			<link rel="canonical" href="https://example.com/">
+			<link rel="stylesheet" href="https://fonts.example.net/css">
+			<script src="https://cdn.example.org/app.js"></script>
+			<script src="https://example.com/local.js"></script>
+		</head><body><p>Hello</p><a href="/about">About</a></body></html>`,
+		"/robots.txt":  "User-agent: *\nDisallow: /private/\nDisallow: /*.pdf$\n\nUser-agent: BadBot\nDisallow: /\n\nSitemap: /sitemap.xml\n",
+		"/sitemap.xml": `<?xml version="1.0"?><urlset></urlset>`,
+	}, nil)
+
+	require.NotNil(t, report.Framework)
+	assert.Equal(t, "Laravel", report.Framework.Name)
+	assert.Equal(t, "laravel_session cookie", report.Framework.Evidence)
+
+	assert.True(t, report.Robots)
+	assert.Equal(t, []string{"/private/", "/*.pdf$"}, report.Disallowed)
+	assert.Equal(t, []string{"/sitemap.xml"}, report.Sitemaps)
+	assert.Equal(t, url.Paths{"/", "/sitemap.xml", "/robots.txt"}, report.Entrypoints)
+	assert.Equal(t, url.Paths{"/admin/**", "/api/**", "/horizon/**", "/nova/**", "/private/**", "/telescope/**"}, report.Exclude)
+
+	require.NotNil(t, report.PublicURL)
+	assert.Equal(t, "https://example.com", report.PublicURL.String())
+	assert.Equal(t, []string{"cdn.example.org", "fonts.example.net"}, report.AssetHosts)
+
+	assert.False(t, report.JS.Recommended)
+	assert.False(t, report.JS.Rendered)
+}
+
+func TestProbe_Run_Sitemaps(t *testing.T) {
+	report := runProbe(t, map[string]string{
+		"/":                  `<html><body>Hello</body></html>`,
+		"/sitemap.xml":       `<html>Not found</html>`,
+		"/sitemap_index.xml": `<?xml version="1.0"?><sitemapindex></sitemapindex>`,
+	}, nil)
+
+	assert.False(t, report.Robots)
+	assert.Equal(t, []string{"/sitemap_index.xml"}, report.Sitemaps)
+	assert.Equal(t, url.Paths{"/", "/sitemap_index.xml"}, report.Entrypoints)
+}
+
+func TestProbe_Run_JS(t *testing.T) {
+	shell := `<html><body><div id="root"></div><script src="/app.js"></script></body></html>`
+	rendered := `<html><body><div id="root"><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a></div></body></html>`
+
+	testCases := []struct {
+		name     string
+		render   func(ctx context.Context, u *url.URL) ([]byte, error)
+		expected JSVerdict
+	}{
+		{
+			name: "rendering adds links",
+			render: func(ctx context.Context, u *url.URL) ([]byte, error) {
+				return []byte(rendered), nil
+			},
+			expected: JSVerdict{Recommended: true, Rendered: true, Reason: "JavaScript adds links: 0 over HTTP, 3 rendered"},
+		},
+		{
+			name: "rendering changes nothing",
+			render: func(ctx context.Context, u *url.URL) ([]byte, error) {
+				return []byte(shell), nil
+			},
+			expected: JSVerdict{Rendered: true, Reason: "pages look the same with and without JavaScript"},
+		},
+		{
+			name:     "application shell without renderer",
+			expected: JSVerdict{Recommended: true, Reason: "the home page is an almost empty application shell without JavaScript"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := runProbe(t, map[string]string{"/": shell}, tc.render)
+			assert.Equal(t, tc.expected, report.JS)
+		})
+	}
+}
@@ -28,6 +28,7 @@ type PageReport struct {
 	IsNoindex     bool
 	Lints         []linter.Lint
 	InternalLinks []InternalLink // Internal links found on this page
+	Thumbnail     template.URL   // data URI of a screenshot thumbnail (empty unless screenshots are enabled)
 }
 
 // Report contains all the data for the full report.
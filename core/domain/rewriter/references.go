@@ -0,0 +1,183 @@
+package rewriter
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Reference is a URL referenced from an exported HTML or CSS file.
+// Start and End are the byte offsets of the URL in the file's content.
+type Reference struct {
+	URL   string
+	Start int
+	End   int
+}
+
+var (
+	// attrRegex matches HTML attributes that hold a single URL.
+	attrRegex = regexp.MustCompile(`(?i)\s(?:href|src|poster|action|data-src|xlink:href)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	// srcsetRegex matches srcset attributes, which hold a comma-separated list of URLs with descriptors.
+	srcsetRegex = regexp.MustCompile(`(?i)\s(?:srcset|data-srcset)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	// cssURLRegex matches url(...) in stylesheets, style attributes and <style> tags.
+	cssURLRegex = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^'")\s]+))\s*\)`)
+	// cssImportRegex matches @import "..." without url().
+	cssImportRegex = regexp.MustCompile(`@import\s+(?:"([^"]*)"|'([^']*)')`)
+	// metaTagRegex and metaURLRegex match meta tags holding the URL of a file in their content, e.g. og:image.
+	metaTagRegex = regexp.MustCompile(`(?i)<meta\s[^>]*>`)
+	metaURLRegex = regexp.MustCompile(`(?i)\s(?:property|name)\s*=\s*["']?(?:og:image|og:image:url|og:image:secure_url|og:video|og:audio|twitter:image)["'\s>/]`)
+	// metaContentRegex matches the content attribute of a meta tag.
+	metaContentRegex = regexp.MustCompile(`(?i)\scontent\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	// jsImportRegexes match the module specifiers of static imports and re-exports, side-effect
+	// imports, dynamic imports with a literal specifier, and new URL("...", import.meta.url).
+	jsImportRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`\b(?:import|export)\s[^;'"\x60]*?\bfrom\s*(?:"([^"]*)"|'([^']*)')`),
+		regexp.MustCompile(`\bimport\s*(?:"([^"]*)"|'([^']*)')`),
+		regexp.MustCompile(`\bimport\s*\(\s*(?:"([^"]*)"|'([^']*)'|\x60([^\x60$]*)\x60)\s*\)`),
+		regexp.MustCompile(`\bnew\s+URL\s*\(\s*(?:"([^"]*)"|'([^']*)')\s*,\s*import\.meta\.url`),
+	}
+)
+
+// FindReferences returns the URLs referenced by an HTML, CSS or JavaScript file, in order
+// of appearance. JavaScript files reference the modules they import, except bare specifiers
+// like "react" which aren't paths. Files of other types have no references.
+func FindReferences(content []byte, ext string) []Reference {
+	var refs []Reference
+
+	switch strings.ToLower(ext) {
+	case ".html", ".htm":
+		refs = append(refs, submatches(attrRegex, content)...)
+		for _, set := range submatches(srcsetRegex, content) {
+			refs = append(refs, splitSrcset(set)...)
+		}
+		refs = append(refs, submatches(cssURLRegex, content)...)
+		for _, m := range metaTagRegex.FindAllIndex(content, -1) {
+			tag := content[m[0]:m[1]]
+			if !metaURLRegex.Match(tag) {
+				continue
+			}
+			for _, ref := range submatches(metaContentRegex, tag) {
+				refs = append(refs, Reference{URL: ref.URL, Start: m[0] + ref.Start, End: m[0] + ref.End})
+			}
+		}
+	case ".css":
+		refs = append(refs, submatches(cssURLRegex, content)...)
+		refs = append(refs, submatches(cssImportRegex, content)...)
+	case ".js", ".mjs":
+		for _, re := range jsImportRegexes {
+			for _, ref := range submatches(re, content) {
+				if isPathSpecifier(ref.URL) {
+					refs = append(refs, ref)
+				}
+			}
+		}
+	default:
+		return nil
+	}
+
+	// Skip references that aren't URLs to files
+	kept := refs[:0]
+	for _, ref := range refs {
+		u := strings.TrimSpace(ref.URL)
+		if u == "" || strings.HasPrefix(u, "#") || strings.HasPrefix(u, "data:") ||
+			strings.HasPrefix(u, "mailto:") || strings.HasPrefix(u, "tel:") || strings.HasPrefix(u, "javascript:") {
+			continue
+		}
+		kept = append(kept, ref)
+	}
+
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Start < kept[j].Start })
+	return kept
+}
+
+// ReplaceReferences calls replace for every reference in content and substitutes its result.
+func ReplaceReferences(content []byte, ext string, replace func(ref string) string) []byte {
+	refs := FindReferences(content, ext)
+	if len(refs) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, ref := range refs {
+		if ref.Start < last {
+			continue // overlapping match, e.g. url() inside an attribute already handled
+		}
+		b.Write(content[last:ref.Start])
+		b.WriteString(replace(ref.URL))
+		last = ref.End
+	}
+	b.Write(content[last:])
+
+	return []byte(b.String())
+}
+
+// ResolvePath returns the file in outputDir that a root-relative URL path points to,
+// using the same rules as the rewriter: the file itself, a directory's index.html,
+// or the path with .html appended.
+func ResolvePath(outputDir, urlPath string) (string, bool) {
+	// Convert URL path to filesystem path
+	fsPath := filepath.Join(outputDir, filepath.FromSlash(urlPath))
+
+	// Check if it exists directly
+	if info, err := os.Stat(fsPath); err == nil {
+		if !info.IsDir() {
+			return fsPath, true
+		}
+		// If it's a directory, check for index.html
+		indexPath := filepath.Join(fsPath, "index.html")
+		if _, err := os.Stat(indexPath); err == nil {
+			return indexPath, true
+		}
+	}
+
+	// For paths without extension, try adding .html
+	if filepath.Ext(fsPath) == "" && !strings.HasSuffix(fsPath, "/") {
+		if _, err := os.Stat(fsPath + ".html"); err == nil {
+			return fsPath + ".html", true
+		}
+	}
+
+	return "", false
+}
+
+// isPathSpecifier reports whether a module specifier is a URL or a path, rather than a
+// bare specifier resolved by an import map or a bundler.
+func isPathSpecifier(specifier string) bool {
+	return strings.HasPrefix(specifier, "/") || strings.HasPrefix(specifier, "./") ||
+		strings.HasPrefix(specifier, "../") || strings.Contains(specifier, "://")
+}
+
+// submatches returns the first non-empty capture group of every match as a reference.
+func submatches(re *regexp.Regexp, content []byte) []Reference {
+	var refs []Reference
+	for _, m := range re.FindAllSubmatchIndex(content, -1) {
+		for g := 1; g < len(m)/2; g++ {
+			start, end := m[2*g], m[2*g+1]
+			if start >= 0 {
+				refs = append(refs, Reference{URL: string(content[start:end]), Start: start, End: end})
+				break
+			}
+		}
+	}
+	return refs
+}
+
+// splitSrcset splits a srcset reference into one reference per candidate URL.
+func splitSrcset(set Reference) []Reference {
+	var refs []Reference
+	offset := set.Start
+	for _, candidate := range strings.Split(set.URL, ",") {
+		trimmed := strings.TrimLeft(candidate, " \t\n\r")
+		start := offset + len(candidate) - len(trimmed)
+		u, _, _ := strings.Cut(trimmed, " ")
+		u = strings.TrimRight(u, " \t\n\r")
+		if u != "" {
+			refs = append(refs, Reference{URL: u, Start: start, End: start + len(u)})
+		}
+		offset += len(candidate) + 1
+	}
+	return refs
+}
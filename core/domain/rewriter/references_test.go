@@ -0,0 +1,131 @@
+package rewriter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindReferences_HTML(t *testing.T) {
+	content := []byte(`<html><head>
+<link rel="stylesheet" href="/css/app.css">
+<style>body { background: url('/img/bg.png'); }</style>
+</head><body>
+<a href='about.html#team'>About</a>
+<a href="#top">Top</a>
+<a href="mailto:hi@example.com">Mail</a>
+<img src="/img/logo.png" srcset="/img/logo.png 1x, /img/logo@2x.png 2x">
+<div style="background-image: url(hero.jpg)"></div>
+<img src="data:image/png;base64,AAAA">
+</body></html>`)
+
+	var urls []string
+	for _, ref := range FindReferences(content, ".html") {
+		urls = append(urls, ref.URL)
+		assert.Equal(t, ref.URL, string(content[ref.Start:ref.End]), "offsets should point at the URL")
+	}
+
+	assert.Equal(t, []string{
+		"/css/app.css",
+		"/img/bg.png",
+		"about.html#team",
+		"/img/logo.png",
+		"/img/logo.png",
+		"/img/logo@2x.png",
+		"hero.jpg",
+	}, urls)
+}
+
+func TestFindReferences_CSS(t *testing.T) {
+	content := []byte(`@import "reset.css";
+@import url(theme.css);
+@font-face { src: url("../fonts/inter.woff2") format("woff2"); }
+.icon { background: url(data:image/svg+xml;base64,AAAA); }`)
+
+	var urls []string
+	for _, ref := range FindReferences(content, ".css") {
+		urls = append(urls, ref.URL)
+	}
+
+	assert.Equal(t, []string{"reset.css", "theme.css", "../fonts/inter.woff2"}, urls)
+	assert.Nil(t, FindReferences(content, ".js"))
+}
+
+func TestFindReferences_JS(t *testing.T) {
+	content := []byte(`import { a } from "./a.js";
+import {
+  b,
+} from '../b.mjs';
+import "/polyfill.js";
+import React from "react";
+export * from "./c.js";
+const lazy = () => import("/lazy.js");
+const worker = new URL("./worker.js", import.meta.url);`)
+
+	var urls []string
+	for _, ref := range FindReferences(content, ".js") {
+		urls = append(urls, ref.URL)
+		assert.Equal(t, ref.URL, string(content[ref.Start:ref.End]), "offsets should point at the URL")
+	}
+
+	assert.Equal(t, []string{"./a.js", "../b.mjs", "/polyfill.js", "./c.js", "/lazy.js", "./worker.js"}, urls)
+}
+
+func TestFindReferences_Meta(t *testing.T) {
+	content := []byte(`<meta content="/og.png" property="og:image">
+<meta name="twitter:image" content='/card.png'>
+<meta name="description" content="/not/a/file">`)
+
+	var urls []string
+	for _, ref := range FindReferences(content, ".html") {
+		urls = append(urls, ref.URL)
+		assert.Equal(t, ref.URL, string(content[ref.Start:ref.End]))
+	}
+
+	assert.Equal(t, []string{"/og.png", "/card.png"}, urls)
+}
+
+func TestReplaceReferences(t *testing.T) {
+	content := []byte(`<img src="/a.png" srcset="/a.png 1x, /b.png 2x"><a href="/page">x</a>`)
+
+	out := ReplaceReferences(content, ".html", func(ref string) string {
+		return strings.ToUpper(ref)
+	})
+
+	assert.Equal(t, `<img src="/A.PNG" srcset="/A.PNG 1x, /B.PNG 2x"><a href="/PAGE">x</a>`, string(out))
+}
+
+func TestResolvePath(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "style.css"), []byte(``), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "about.html"), []byte(``), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "blog"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "blog", "index.html"), []byte(``), 0644))
+
+	testCases := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{path: "/style.css", want: "style.css", ok: true},
+		{path: "/about", want: "about.html", ok: true},
+		{path: "/blog", want: filepath.Join("blog", "index.html"), ok: true},
+		{path: "/blog/", want: filepath.Join("blog", "index.html"), ok: true},
+		{path: "/", want: "", ok: false},
+		{path: "/missing.css", want: "", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			got, ok := ResolvePath(tmpDir, tc.path)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, filepath.Join(tmpDir, tc.want), got)
+			}
+		})
+	}
+}
@@ -1,10 +1,10 @@
 package rewriter
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
 
 	"github.com/felixdorn/bare/core/domain/url"
 )
@@ -58,19 +58,33 @@ func (r *Rewriter) rewriteFile(filePath, outputDir string) error {
 		return err
 	}
 
-	original := string(content)
-	result := r.urlRegex.ReplaceAllStringFunc(original, func(match string) string {
-		return r.processURL(match, outputDir)
-	})
+	result := r.rewrite(content, outputDir)
 
 	// Only write if changed
-	if result != original {
-		return os.WriteFile(filePath, []byte(result), 0644)
+	if !bytes.Equal(result, content) {
+		return os.WriteFile(filePath, result, 0644)
 	}
 
 	return nil
 }
 
+// Rewrite returns content with the URLs rewritten as if it was a file of the export,
+// without writing anything.
+func (r *Rewriter) Rewrite(content []byte) []byte {
+	absOutputDir, err := filepath.Abs(r.OutputDir)
+	if err != nil {
+		absOutputDir = r.OutputDir
+	}
+	return r.rewrite(content, absOutputDir)
+}
+
+// rewrite rewrites the URLs of content whose target exists in outputDir.
+func (r *Rewriter) rewrite(content []byte, outputDir string) []byte {
+	return r.urlRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		return []byte(r.processURL(string(match), outputDir))
+	})
+}
+
 // processURL decides whether to rewrite a URL and how.
 func (r *Rewriter) processURL(rawURL, outputDir string) string {
 	parsed, err := url.Parse(rawURL)
@@ -108,27 +122,6 @@ func (r *Rewriter) processURL(rawURL, outputDir string) string {
 
 // targetExists checks if a URL path corresponds to an existing file in the export.
 func (r *Rewriter) targetExists(urlPath, outputDir string) bool {
-	// Convert URL path to filesystem path
-	fsPath := filepath.Join(outputDir, filepath.FromSlash(urlPath))
-
-	// Check if it exists directly
-	if info, err := os.Stat(fsPath); err == nil {
-		if !info.IsDir() {
-			return true
-		}
-		// If it's a directory, check for index.html
-		indexPath := filepath.Join(fsPath, "index.html")
-		if _, err := os.Stat(indexPath); err == nil {
-			return true
-		}
-	}
-
-	// For paths without extension, try adding .html
-	if filepath.Ext(fsPath) == "" && !strings.HasSuffix(fsPath, "/") {
-		if _, err := os.Stat(fsPath + ".html"); err == nil {
-			return true
-		}
-	}
-
-	return false
+	_, ok := ResolvePath(outputDir, urlPath)
+	return ok
 }
@@ -0,0 +1,187 @@
+/*
+ * Search client for indexes generated by bare.
+ *
+ *   <script src="/_search/search.js" defer></script>
+ *   <input type="search" data-bare-search="#results">
+ *   <div id="results"></div>
+ *
+ * or call window.bareSearch(query, limit), which resolves to [{url, title, excerpt, score}].
+ */
+(function () {
+  var script = document.currentScript;
+  var base = script ? script.src.replace(/[^/]*$/, '') : '/_search/';
+  var cache = {};
+
+  function load(name) {
+    if (!cache[name]) {
+      cache[name] = fetch(base + name).then(function (res) {
+        return res.ok ? res.json() : {};
+      });
+    }
+    return cache[name];
+  }
+
+  // tokenize and stem mirror the indexer, using the rules shipped in meta.json.
+  function tokenize(text, fold) {
+    var words = [];
+    var word = '';
+    for (var ch of text.toLowerCase()) {
+      if (fold[ch]) {
+        word += fold[ch];
+      } else if (/[\p{L}\p{N}]/u.test(ch)) {
+        word += ch;
+      } else if (word) {
+        words.push(word);
+        word = '';
+      }
+    }
+    if (word) words.push(word);
+    return words;
+  }
+
+  function stem(word, lang, minStem) {
+    if (lang.stopwords.indexOf(word) >= 0) return '';
+    for (var i = 0; i < lang.rules.length; i++) {
+      var rule = lang.rules[i];
+      if (word.endsWith(rule[0])) {
+        var s = word.slice(0, word.length - rule[0].length);
+        return Array.from(s).length >= minStem ? s + rule[1] : word;
+      }
+    }
+    return word;
+  }
+
+  function shardKey(term) {
+    return /^[a-z0-9]{2}/.test(term) ? term.slice(0, 2) : '_';
+  }
+
+  function search(query, limit) {
+    limit = limit || 10;
+    return load('meta.json').then(function (meta) {
+      var words = tokenize(query, meta.fold);
+      // The last word may still be being typed, so it also matches as a prefix
+      var prefix = /[\p{L}\p{N}]$/u.test(query) && words.length ? words[words.length - 1] : '';
+
+      var groups = words.map(function (word) {
+        var terms = [];
+        var stopword = false;
+        Object.keys(meta.languages).forEach(function (code) {
+          var term = stem(word, meta.languages[code], meta.min_stem);
+          if (!term) stopword = true;
+          else if (terms.indexOf(term) < 0) terms.push(term);
+        });
+        // A stopword in any language is ignored, pages may not have indexed it
+        return { word: word, terms: stopword ? [] : terms };
+      }).filter(function (group) {
+        return group.terms.length > 0;
+      });
+      if (!groups.length) return [];
+
+      var keys = [];
+      groups.forEach(function (group) {
+        group.terms.concat(group.word === prefix ? [prefix] : []).forEach(function (term) {
+          var key = shardKey(term);
+          if (keys.indexOf(key) < 0 && meta.shards.indexOf(key) >= 0) keys.push(key);
+        });
+      });
+
+      return Promise.all(keys.map(function (key) {
+        return load('shards/' + key + '.json');
+      })).then(function (loaded) {
+        var shards = {};
+        keys.forEach(function (key, i) { shards[key] = loaded[i]; });
+
+        var scores = null;
+        groups.forEach(function (group) {
+          var found = {};
+          var collect = function (postings, factor) {
+            for (var i = 0; i < postings.length; i += 2) {
+              found[postings[i]] = (found[postings[i]] || 0) + postings[i + 1] * factor;
+            }
+          };
+
+          group.terms.forEach(function (term) {
+            var shard = shards[shardKey(term)] || {};
+            if (shard[term]) collect(shard[term], 1);
+          });
+          if (group.word === prefix && prefix.length >= 2) {
+            var shard = shards[shardKey(prefix)] || {};
+            Object.keys(shard).forEach(function (term) {
+              if (term !== prefix && term.indexOf(prefix) === 0 && group.terms.indexOf(term) < 0) {
+                collect(shard[term], 0.5);
+              }
+            });
+          }
+
+          // Every word of the query must match
+          if (scores === null) {
+            scores = found;
+          } else {
+            Object.keys(scores).forEach(function (doc) {
+              if (found[doc] === undefined) delete scores[doc];
+              else scores[doc] += found[doc];
+            });
+          }
+        });
+
+        var ranked = Object.keys(scores).sort(function (a, b) {
+          return scores[b] - scores[a] || a - b;
+        }).slice(0, limit);
+        if (!ranked.length) return [];
+
+        return load('docs.json').then(function (docs) {
+          return ranked.map(function (doc) {
+            var d = docs[doc];
+            return { url: d[0], title: d[1] || d[0], excerpt: d[2], score: scores[doc] };
+          });
+        });
+      });
+    });
+  }
+
+  function render(target, results) {
+    target.textContent = '';
+    if (!results.length) return;
+
+    var list = document.createElement('ul');
+    results.forEach(function (result) {
+      var item = document.createElement('li');
+      var link = document.createElement('a');
+      link.href = result.url;
+      link.textContent = result.title;
+      item.appendChild(link);
+      if (result.excerpt) {
+        var excerpt = document.createElement('p');
+        excerpt.textContent = result.excerpt;
+        item.appendChild(excerpt);
+      }
+      list.appendChild(item);
+    });
+    target.appendChild(list);
+  }
+
+  function bind() {
+    document.querySelectorAll('input[data-bare-search]').forEach(function (input) {
+      var target = document.querySelector(input.getAttribute('data-bare-search'));
+      if (!target) return;
+
+      var timer;
+      input.addEventListener('input', function () {
+        clearTimeout(timer);
+        timer = setTimeout(function () {
+          var query = input.value;
+          search(query, Number(input.getAttribute('data-bare-search-limit')) || 10).then(function (results) {
+            if (input.value === query) render(target, results);
+          });
+        }, 150);
+      });
+    });
+  }
+
+  window.bareSearch = search;
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', bind);
+  } else {
+    bind();
+  }
+})();
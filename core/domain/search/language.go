@@ -0,0 +1,142 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// minStem is the shortest stem a suffix rule may leave.
+const minStem = 3
+
+// Language holds the stemming rules and stopwords of a language.
+// Rules are shipped in the index so the client stems queries exactly like the indexer.
+type Language struct {
+	// Rules are tried in order and the first matching suffix is replaced.
+	// They apply to folded words, so they are written without diacritics.
+	Rules     [][2]string `json:"rules"`
+	Stopwords []string    `json:"stopwords"`
+}
+
+// Languages are the built-in languages, by ISO 639-1 code.
+var Languages = map[string]Language{
+	"en": {
+		Rules: [][2]string{
+			{"ational", "ate"}, {"ization", "ize"}, {"fulness", "ful"}, {"ousness", "ous"}, {"iveness", "ive"},
+			{"ations", "ate"}, {"ation", "ate"}, {"ingly", ""}, {"ments", ""}, {"ment", ""}, {"ness", ""},
+			{"ings", ""}, {"ing", ""}, {"edly", ""}, {"ies", "y"}, {"ied", "y"}, {"ed", ""}, {"ly", ""},
+			{"sses", "ss"}, {"ss", "ss"}, {"us", "us"}, {"is", "is"}, {"s", ""},
+		},
+		Stopwords: []string{
+			"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "from", "has", "have", "in", "into",
+			"is", "it", "its", "of", "on", "or", "that", "the", "their", "then", "there", "these", "this",
+			"to", "was", "were", "will", "with",
+		},
+	},
+	"fr": {
+		Rules: [][2]string{
+			{"issements", ""}, {"issement", ""}, {"atrices", ""}, {"atrice", ""}, {"ations", ""}, {"ation", ""},
+			{"ements", ""}, {"ement", ""}, {"euses", ""}, {"euse", ""}, {"eux", ""}, {"ites", ""}, {"ite", ""},
+			{"ives", ""}, {"ive", ""}, {"ees", ""}, {"ee", ""}, {"es", ""}, {"er", ""}, {"e", ""}, {"s", ""},
+		},
+		Stopwords: []string{
+			"au", "aux", "avec", "ce", "ces", "dans", "de", "des", "du", "elle", "en", "est", "et", "il", "ils",
+			"la", "le", "les", "leur", "mais", "ne", "nous", "ou", "par", "pas", "pour", "qui", "que", "sa",
+			"se", "son", "sur", "un", "une", "vous",
+		},
+	},
+	"de": {
+		Rules: [][2]string{
+			{"ungen", ""}, {"heiten", ""}, {"keiten", ""}, {"heit", ""}, {"keit", ""}, {"ung", ""}, {"lich", ""},
+			{"isch", ""}, {"ern", ""}, {"em", ""}, {"en", ""}, {"er", ""}, {"es", ""}, {"e", ""}, {"s", ""},
+		},
+		Stopwords: []string{
+			"als", "am", "an", "auf", "aus", "bei", "das", "dem", "den", "der", "des", "die", "ein", "eine",
+			"einem", "einen", "einer", "es", "fur", "im", "in", "ist", "mit", "nicht", "oder", "sich", "sie",
+			"sind", "und", "von", "wie", "zu", "zum", "zur",
+		},
+	},
+	"es": {
+		Rules: [][2]string{
+			{"amientos", ""}, {"imientos", ""}, {"amiento", ""}, {"imiento", ""}, {"aciones", ""}, {"acion", ""},
+			{"idades", ""}, {"idad", ""}, {"mente", ""}, {"ismos", ""}, {"ismo", ""}, {"istas", ""}, {"ista", ""},
+			{"ables", ""}, {"able", ""}, {"ibles", ""}, {"ible", ""}, {"es", ""}, {"os", ""}, {"as", ""},
+			{"o", ""}, {"a", ""}, {"s", ""},
+		},
+		Stopwords: []string{
+			"al", "como", "con", "de", "del", "el", "en", "es", "esta", "la", "las", "lo", "los", "mas", "no",
+			"o", "para", "pero", "por", "que", "se", "sin", "su", "sus", "un", "una", "y",
+		},
+	},
+}
+
+// fold maps letters with diacritics to their base letters, so "café" matches "cafe".
+var fold = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'œ': "oe",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
+
+// stemmer stems the words of one language.
+type stemmer struct {
+	rules     [][2]string
+	stopwords map[string]bool
+}
+
+func newStemmer(code string) (*stemmer, error) {
+	lang, ok := Languages[code]
+	if !ok {
+		return nil, fmt.Errorf("unsupported search language %q", code)
+	}
+
+	stopwords := make(map[string]bool, len(lang.Stopwords))
+	for _, w := range lang.Stopwords {
+		stopwords[w] = true
+	}
+	return &stemmer{rules: lang.Rules, stopwords: stopwords}, nil
+}
+
+// stem returns the stem of a folded word, or "" for a stopword.
+func (s *stemmer) stem(word string) string {
+	if s.stopwords[word] {
+		return ""
+	}
+	for _, rule := range s.rules {
+		if strings.HasSuffix(word, rule[0]) {
+			stem := strings.TrimSuffix(word, rule[0])
+			if len([]rune(stem)) >= minStem {
+				return stem + rule[1]
+			}
+			return word
+		}
+	}
+	return word
+}
+
+// tokenize splits text into lowercase, folded words.
+func tokenize(text string) []string {
+	var words []string
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			words = append(words, b.String())
+			b.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case fold[r] != "":
+			b.WriteString(fold[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return words
+}
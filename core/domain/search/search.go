@@ -0,0 +1,305 @@
+package search
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/felixdorn/bare/core/domain/analyzer"
+	"github.com/felixdorn/bare/core/domain/linter"
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+//go:embed client.js
+var client []byte
+
+// Field weights: a word in a title ranks a page higher than the same word in a paragraph.
+const (
+	titleWeight   = 10
+	headingWeight = 5
+	textWeight    = 1
+)
+
+const (
+	minTermLength = 2
+	maxTermLength = 40
+	excerptLength = 160
+)
+
+// DefaultMain and DefaultExclude are the content selectors used when none are configured.
+var (
+	DefaultMain    = []string{"main", "article", `[role="main"]`}
+	DefaultExclude = []string{"nav", "header", "footer", "aside", `[role="navigation"]`, "[data-search-ignore]"}
+)
+
+// Options configures the Indexer.
+type Options struct {
+	Dir       string    // directory of the index, relative to the output directory
+	Main      []string  // selectors of the main content, defaults to DefaultMain
+	Exclude   []string  // selectors removed from the main content, defaults to DefaultExclude
+	Languages []string  // language codes, the first is used for pages without a known lang attribute
+	Pages     url.Paths // pages left out of the index
+}
+
+// Stats summarizes an indexing run.
+type Stats struct {
+	Documents int
+	Terms     int
+	Shards    int
+}
+
+// meta is the entry point of the index, loaded first by the client.
+type meta struct {
+	Version   int                 `json:"version"`
+	Documents int                 `json:"documents"`
+	Languages map[string]Language `json:"languages"`
+	Fold      map[string]string   `json:"fold"`
+	MinStem   int                 `json:"min_stem"`
+	Shards    []string            `json:"shards"`
+}
+
+// Indexer builds a static search index of the HTML pages in an output directory.
+//
+// The index lives in Options.Dir:
+//   - meta.json holds the stemming rules and the list of shards
+//   - docs.json lists the documents as [url, title, excerpt]
+//   - shards/<key>.json maps the terms starting with key to flat [doc, score, doc, score...] postings
+//   - search.js is the client, which only downloads the shards a query needs
+type Indexer struct {
+	OutputDir string
+	opts      Options
+}
+
+// New creates a new Indexer instance.
+func New(outputDir string, opts Options) *Indexer {
+	if opts.Dir == "" {
+		opts.Dir = "_search"
+	}
+	if len(opts.Main) == 0 {
+		opts.Main = DefaultMain
+	}
+	if len(opts.Exclude) == 0 {
+		opts.Exclude = DefaultExclude
+	}
+	if len(opts.Languages) == 0 {
+		opts.Languages = []string{"en"}
+	}
+
+	return &Indexer{
+		OutputDir: outputDir,
+		opts:      opts,
+	}
+}
+
+// indexFiles are the files written in Options.Dir, replaced by every run.
+var indexFiles = []string{"meta.json", "docs.json", "shards", "search.js"}
+
+// Run indexes the HTML pages of the output directory and writes the index and client.
+func (ix *Indexer) Run() (*Stats, error) {
+	stemmers := make(map[string]*stemmer, len(ix.opts.Languages))
+	languages := make(map[string]Language, len(ix.opts.Languages))
+	for _, code := range ix.opts.Languages {
+		s, err := newStemmer(code)
+		if err != nil {
+			return nil, err
+		}
+		stemmers[code] = s
+		languages[code] = Languages[code]
+	}
+
+	indexDir := filepath.Join(ix.OutputDir, filepath.FromSlash(ix.opts.Dir))
+	// The directory may hold exported pages too, only the files of the index are removed
+	for _, name := range indexFiles {
+		if err := os.RemoveAll(filepath.Join(indexDir, name)); err != nil {
+			return nil, fmt.Errorf("could not clear the previous index: %w", err)
+		}
+	}
+
+	var docs [][3]string
+	postings := make(map[string]map[int]int) // term -> document -> score
+
+	err := filepath.WalkDir(ix.OutputDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".html" && ext != ".htm" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ix.OutputDir, path)
+		if err != nil {
+			return err
+		}
+		pagePath := strings.TrimSuffix("/"+filepath.ToSlash(rel), "index.html")
+		if rel == "404.html" || ix.opts.Pages.MatchAny(pagePath) {
+			return nil
+		}
+
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if linter.IsNoindexHTML(body) {
+			return nil
+		}
+
+		content, err := analyzer.ExtractContent(body, analyzer.ContentSelectors{Main: ix.opts.Main, Exclude: ix.opts.Exclude})
+		if err != nil {
+			return fmt.Errorf("could not parse %s: %w", rel, err)
+		}
+
+		s := stemmers[ix.opts.Languages[0]]
+		lang, _, _ := strings.Cut(strings.ToLower(content.Lang), "-")
+		if stemmers[lang] != nil {
+			s = stemmers[lang]
+		}
+
+		doc := len(docs)
+		docs = append(docs, [3]string{pagePath, content.Title, excerpt(content)})
+
+		add := func(text string, weight int) {
+			for _, word := range tokenize(text) {
+				term := s.stem(word)
+				if n := utf8.RuneCountInString(term); n < minTermLength || n > maxTermLength {
+					continue
+				}
+				if postings[term] == nil {
+					postings[term] = make(map[int]int)
+				}
+				postings[term][doc] += weight
+			}
+		}
+		add(content.Title, titleWeight)
+		for _, heading := range content.Headings {
+			add(heading, headingWeight)
+		}
+		add(content.Text, textWeight)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shards := make(map[string]map[string][]int)
+	for term, scores := range postings {
+		flat := make([]int, 0, 2*len(scores))
+		for doc, score := range scores {
+			flat = append(flat, doc, score)
+		}
+		sortPostings(flat)
+
+		key := shardKey(term)
+		if shards[key] == nil {
+			shards[key] = make(map[string][]int)
+		}
+		shards[key][term] = flat
+	}
+
+	keys := make([]string, 0, len(shards))
+	for key, terms := range shards {
+		keys = append(keys, key)
+		if err := writeJSON(filepath.Join(indexDir, "shards", key+".json"), terms); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(keys)
+
+	folds := make(map[string]string, len(fold))
+	for r, s := range fold {
+		folds[string(r)] = s
+	}
+
+	m := meta{
+		Version:   1,
+		Documents: len(docs),
+		Languages: languages,
+		Fold:      folds,
+		MinStem:   minStem,
+		Shards:    keys,
+	}
+	if err := writeJSON(filepath.Join(indexDir, "meta.json"), m); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(filepath.Join(indexDir, "docs.json"), docs); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(indexDir, "search.js"), client, 0644); err != nil {
+		return nil, fmt.Errorf("could not write search client: %w", err)
+	}
+
+	return &Stats{Documents: len(docs), Terms: len(postings), Shards: len(keys)}, nil
+}
+
+// shardKey returns the shard of a term: its first two characters when they are
+// ASCII letters or digits, "_" otherwise.
+func shardKey(term string) string {
+	if len(term) < 2 {
+		return "_"
+	}
+	for i := 0; i < 2; i++ {
+		c := term[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return "_"
+		}
+	}
+	return term[:2]
+}
+
+// sortPostings sorts flat [doc, score...] postings by descending score, then document.
+func sortPostings(flat []int) {
+	type posting struct{ doc, score int }
+	ps := make([]posting, len(flat)/2)
+	for i := range ps {
+		ps[i] = posting{flat[2*i], flat[2*i+1]}
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].score != ps[j].score {
+			return ps[i].score > ps[j].score
+		}
+		return ps[i].doc < ps[j].doc
+	})
+	for i, p := range ps {
+		flat[2*i], flat[2*i+1] = p.doc, p.score
+	}
+}
+
+// excerpt returns the description of a page, or the beginning of its text.
+func excerpt(content *analyzer.Content) string {
+	if content.Description != "" {
+		return content.Description
+	}
+
+	runes := []rune(content.Text)
+	if len(runes) <= excerptLength {
+		return content.Text
+	}
+	cut := string(runes[:excerptLength])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
+
+func writeJSON(path string, v any) error {
+	byt, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, byt, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
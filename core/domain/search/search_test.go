@@ -0,0 +1,149 @@
+package search
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStemmer_Stem(t *testing.T) {
+	testCases := []struct {
+		lang     string
+		word     string
+		expected string
+	}{
+		{"en", "running", "runn"},
+		{"en", "exports", "export"},
+		{"en", "studies", "study"},
+		{"en", "status", "status"},
+		{"en", "classes", "class"},
+		{"en", "configuration", "configurate"},
+		{"en", "the", ""},
+		{"en", "is", ""},
+		{"en", "sing", "sing"}, // the stem would be too short
+		{"fr", "rapidement", "rapid"},
+		{"fr", "exportees", "export"},
+		{"de", "einstellungen", "einstell"},
+		{"es", "configuraciones", "configur"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.lang+"/"+tc.word, func(t *testing.T) {
+			s, err := newStemmer(tc.lang)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, s.stem(tc.word))
+		})
+	}
+
+	_, err := newStemmer("xx")
+	assert.Error(t, err)
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"cafe", "creme", "strasse", "v2", "api"}, tokenize("Café-Crème, Straße v2 (API)!"))
+	assert.Empty(t, tokenize(" -- "))
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	byt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(byt, v))
+}
+
+func TestIndexer_Run(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html": `<html lang="en"><head><title>Home</title></head><body>
+		<nav>Exporting Navigation</nav>
+		<main><h1>Welcome</h1><p>Bare exports websites.</p></main>
+	</body></html>`,
+		"docs/export/index.html": `<html><head><title>Exporting</title>
+		<meta name="description" content="How to export a site."></head><body>
+		<main><h1>Exporting</h1><p>Run the export command.</p><div class="ad">Sponsored</div></main>
+	</body></html>`,
+		"fr/index.html":      `<html lang="fr-FR"><head><title>Accueil</title></head><body><p>Exportées rapidement.</p></body></html>`,
+		"private/index.html": `<html><head><meta name="robots" content="noindex"></head><body>Secret exports</body></html>`,
+		"drafts/index.html":  `<html><body>Draft exports</body></html>`,
+		"404.html":           `<html><body>Not found</body></html>`,
+		"style.css":          `body { color: red; }`,
+	})
+
+	stats, err := New(dir, Options{
+		Exclude:   []string{"nav", ".ad"},
+		Languages: []string{"en", "fr"},
+		Pages:     url.Paths{"/drafts/**"},
+	}).Run()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Documents)
+
+	indexDir := filepath.Join(dir, "_search")
+	assert.FileExists(t, filepath.Join(indexDir, "search.js"))
+
+	var m meta
+	readJSON(t, filepath.Join(indexDir, "meta.json"), &m)
+	assert.Equal(t, 3, m.Documents)
+	assert.Contains(t, m.Languages, "fr")
+	assert.Equal(t, "e", m.Fold["é"])
+	assert.Len(t, m.Shards, stats.Shards)
+
+	var docs [][3]string
+	readJSON(t, filepath.Join(indexDir, "docs.json"), &docs)
+	byURL := make(map[string]int)
+	for i, d := range docs {
+		byURL[d[0]] = i
+	}
+	require.Contains(t, byURL, "/")
+	require.Contains(t, byURL, "/docs/export/")
+	require.Contains(t, byURL, "/fr/")
+	assert.Equal(t, [3]string{"/docs/export/", "Exporting", "How to export a site."}, docs[byURL["/docs/export/"]])
+	assert.Equal(t, "Welcome Bare exports websites.", docs[byURL["/"]][2])
+
+	var ex map[string][]int
+	readJSON(t, filepath.Join(indexDir, "shards", "ex.json"), &ex)
+
+	// The title and heading of /docs/export/ rank it first (headings are also part of the text),
+	// "Exporting" in <nav> isn't indexed, and "exportées" is stemmed with the French rules
+	assert.Equal(t, []int{
+		byURL["/docs/export/"], titleWeight + headingWeight + 2*textWeight,
+		byURL["/fr/"], textWeight,
+		byURL["/"], textWeight,
+	}, ex["export"])
+
+	// Excluded selectors are not indexed
+	assert.NoFileExists(t, filepath.Join(indexDir, "shards", "sp.json"))
+}
+
+func TestIndexer_Run_ClearsPreviousIndex(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html":             `<html><body>Hello</body></html>`,
+		"_search/shards/zz.json": `{}`,
+	})
+
+	_, err := New(dir, Options{}).Run()
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "_search", "shards", "zz.json"))
+	assert.FileExists(t, filepath.Join(dir, "_search", "shards", "he.json"))
+}
+
+func TestIndexer_Run_KeepsOtherFiles(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"docs/index.html": `<html><body>Hello</body></html>`,
+	})
+
+	_, err := New(dir, Options{Dir: "docs"}).Run()
+	require.NoError(t, err)
+	_, err = New(dir, Options{Dir: "docs"}).Run()
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "docs", "index.html"))
+	assert.FileExists(t, filepath.Join(dir, "docs", "shards", "he.json"))
+}
+
+func TestIndexer_Run_UnknownLanguage(t *testing.T) {
+	_, err := New(t.TempDir(), Options{Languages: []string{"xx"}}).Run()
+	assert.Error(t, err)
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	neturl "net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/felixdorn/bare/core/domain/differ"
+	"github.com/felixdorn/bare/core/domain/rewriter"
+)
+
+// CompareParam is the query parameter switching a page to the origin, with ?_bare=origin.
+const CompareParam = "_bare"
+
+// CompareOptions configures the Compare handler.
+type CompareOptions struct {
+	Origin *neturl.URL // proxied when a page is viewed on the origin
+	// Render returns the status the origin responds with for a path, and the
+	// content the export would contain for it if it was exported now.
+	Render    func(ctx context.Context, path string) (int, []byte, error)
+	OnCompare func(Comparison) // called for every compared response, e.g. to log it
+	// Diff masks what changes on every request before hashing, like bare diff does,
+	// e.g. CSRF tokens, nonces and timestamps.
+	Diff differ.Options
+}
+
+// Comparison is the difference between an exported response and the origin.
+type Comparison struct {
+	Path         string
+	ExportStatus int
+	OriginStatus int
+	ExportHash   string   // sha256 of the normalized body, empty unless the status is 200
+	OriginHash   string   // sha256 of what the export would contain now, normalized
+	Missing      []string // references of the exported page that aren't in the export
+	Error        string   // set if the origin could not be reached
+}
+
+// Differences describes how the exported response differs from the origin.
+func (c Comparison) Differences() []string {
+	var diffs []string
+	if c.Error != "" {
+		return []string{"origin unreachable: " + c.Error}
+	}
+	if c.ExportStatus != c.OriginStatus {
+		diffs = append(diffs, fmt.Sprintf("status %d, origin %d", c.ExportStatus, c.OriginStatus))
+	} else if c.ExportHash != c.OriginHash {
+		diffs = append(diffs, "content differs from the origin")
+	}
+	if len(c.Missing) > 0 {
+		diffs = append(diffs, fmt.Sprintf("%d missing assets", len(c.Missing)))
+	}
+	return diffs
+}
+
+// Compare serves the export through next, and compares every GET response with what
+// the origin returns for the same path. The differences are shown in a toolbar injected
+// in HTML pages and in the X-Bare-Compare header. With ?_bare=origin, the page is
+// proxied from the origin instead.
+func Compare(dir string, next http.Handler, opts CompareOptions) http.Handler {
+	proxy := originProxy(opts.Origin)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get(CompareParam) == "origin" {
+			proxy.ServeHTTP(w, r)
+			return
+		}
+
+		// Hashes are computed on the content, not on precompressed sidecars
+		for _, name := range []string{"Accept-Encoding", "Range", "If-Modified-Since", "If-None-Match"} {
+			r.Header.Del(name)
+		}
+
+		rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		body := rec.body.Bytes()
+
+		// Redirects are followed by the browser, and compared once they land
+		if r.Method == http.MethodGet && (rec.status < 300 || rec.status >= 400) {
+			isHTML := strings.HasPrefix(rec.header.Get("Content-Type"), "text/html")
+			cmp := compare(r, dir, rec.status, body, isHTML, opts)
+			if opts.OnCompare != nil {
+				opts.OnCompare(cmp)
+			}
+
+			if diffs := cmp.Differences(); len(diffs) > 0 {
+				rec.header.Set("X-Bare-Compare", strings.Join(diffs, "; "))
+			} else {
+				rec.header.Set("X-Bare-Compare", "same")
+			}
+
+			if isHTML {
+				body = inject(body, toolbar(r.URL, cmp, false))
+				rec.header.Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+
+		for name, values := range rec.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(body)
+	})
+}
+
+// compare compares an exported response with the origin.
+func compare(r *http.Request, dir string, status int, body []byte, isHTML bool, opts CompareOptions) Comparison {
+	cmp := Comparison{Path: r.URL.Path, ExportStatus: status}
+
+	// Pages are normalized as HTML whatever their URL
+	name := r.URL.Path
+	if isHTML {
+		name = path.Join(name, "index.html")
+	}
+	if status == http.StatusOK {
+		cmp.ExportHash = hash(name, body, opts.Diff)
+	}
+
+	originStatus, originBody, err := opts.Render(r.Context(), r.URL.Path)
+	if err != nil {
+		cmp.Error = err.Error()
+		return cmp
+	}
+	cmp.OriginStatus = originStatus
+	if originStatus == http.StatusOK {
+		cmp.OriginHash = hash(name, originBody, opts.Diff)
+	}
+
+	if status == http.StatusOK && isHTML {
+		cmp.Missing = missingReferences(dir, r.URL.Path, body)
+	}
+	return cmp
+}
+
+// missingReferences lists the internal references of a page that don't resolve in the export.
+func missingReferences(dir, pagePath string, body []byte) []string {
+	var missing []string
+	seen := make(map[string]bool)
+
+	for _, ref := range rewriter.FindReferences(body, ".html") {
+		u, err := neturl.Parse(strings.TrimSpace(ref.URL))
+		if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+			continue
+		}
+
+		p := u.Path
+		if !strings.HasPrefix(p, "/") {
+			p = path.Join(pagePath[:strings.LastIndex(pagePath, "/")+1], p)
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		if _, ok := rewriter.ResolvePath(dir, p); !ok {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// originProxy proxies requests to the origin, without the compare parameter, and keeps
+// redirects within the origin view.
+func originProxy(origin *neturl.URL) http.Handler {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(origin)
+			q := pr.Out.URL.Query()
+			q.Del(CompareParam)
+			pr.Out.URL.RawQuery = q.Encode()
+			pr.Out.Header.Del("Accept-Encoding") // let the transport decompress the page
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if loc := resp.Header.Get("Location"); loc != "" {
+				if u, err := neturl.Parse(loc); err == nil && (u.Host == "" || u.Host == origin.Host) {
+					resp.Header.Set("Location", viewURL(u, "origin").String())
+				}
+			}
+
+			if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+				return nil
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+
+			body = inject(body, toolbar(resp.Request.URL, Comparison{}, true))
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			resp.ContentLength = int64(len(body))
+			resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			return nil
+		},
+	}
+}
+
+// viewURL returns the path and query of u, switched to the export or the origin view.
+func viewURL(u *neturl.URL, view string) *neturl.URL {
+	q := u.Query()
+	if view == "origin" {
+		q.Set(CompareParam, "origin")
+	} else {
+		q.Del(CompareParam)
+	}
+	return &neturl.URL{Path: u.Path, RawQuery: q.Encode()}
+}
+
+var toolbarTemplate = template.Must(template.New("toolbar").Parse(`
+<div id="bare-compare" style="position:fixed;left:0;right:0;bottom:0;z-index:2147483647;display:flex;flex-wrap:wrap;gap:12px;align-items:center;padding:6px 12px;background:#111827;color:#f9fafb;font:13px/1.5 system-ui,sans-serif">
+  <strong>bare</strong>
+  {{if .OnOrigin}}
+  <span style="color:#93c5fd">Viewing the origin</span>
+  <a href="{{.ExportLink}}" style="color:#f9fafb">View the export</a>
+  {{else}}
+  <a href="{{.OriginLink}}" style="color:#f9fafb">View on the origin</a>
+  {{range .Differences}}<span style="color:#fca5a5">{{.}}</span>{{else}}<span style="color:#86efac">Same as the origin</span>{{end}}
+  {{if .Missing}}<details><summary style="cursor:pointer">Missing assets</summary>{{range .Missing}}<div><code>{{.}}</code></div>{{end}}</details>{{end}}
+  {{end}}
+</div>`))
+
+// toolbar renders the toolbar injected in compared pages.
+func toolbar(u *neturl.URL, cmp Comparison, onOrigin bool) []byte {
+	var b bytes.Buffer
+	_ = toolbarTemplate.Execute(&b, struct {
+		OnOrigin    bool
+		ExportLink  string
+		OriginLink  string
+		Differences []string
+		Missing     []string
+	}{
+		OnOrigin:    onOrigin,
+		ExportLink:  viewURL(u, "export").String(),
+		OriginLink:  viewURL(u, "origin").String(),
+		Differences: cmp.Differences(),
+		Missing:     cmp.Missing,
+	})
+	return b.Bytes()
+}
+
+// hash returns the hex sha256 of the content of file p, normalized with opts if it is text.
+func hash(p string, content []byte, opts differ.Options) string {
+	if utf8.Valid(content) {
+		if lines, err := differ.Normalize(content, p, opts); err == nil {
+			content = []byte(strings.Join(lines, "\n"))
+		}
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/differ"
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	pages := map[string]string{
+		"/":      "<html><body>home</body></html>",
+		"/about": "<html><body>about v2</body></html>",
+	}
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has(CompareParam) {
+			t.Errorf("compare parameter sent to the origin: %s", r.URL)
+		}
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/about", http.StatusMovedPermanently)
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	defer origin.Close()
+	originURL, err := neturl.Parse(origin.URL)
+	require.NoError(t, err)
+
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html":     "<html><body>home</body></html>",
+		"about.html":     `<html><body>about v1<img src="logo.png"><link href="/style.css"></body></html>`,
+		"removed.html":   "<html><body>removed</body></html>",
+		"style.css":      "body{}",
+		"_redirects":     "/old /about 301",
+		"blog/post.html": `<html><body><img src="cover.jpg"><a href="https://example.com/x.png">x</a></body></html>`,
+	})
+
+	var compared []Comparison
+	handler := Compare(dir, Host(dir, HostOptions{Profile: Profiles["netlify"]}), CompareOptions{
+		Origin: originURL,
+		Render: func(ctx context.Context, path string) (int, []byte, error) {
+			page, ok := pages[path]
+			if !ok {
+				return http.StatusNotFound, nil, nil
+			}
+			return http.StatusOK, []byte(page), nil
+		},
+		OnCompare: func(cmp Comparison) { compared = append(compared, cmp) },
+	})
+
+	testCases := []struct {
+		path        string
+		differences string
+		missing     []string
+	}{
+		{"/", "same", nil},
+		{"/about", "content differs from the origin; 1 missing assets", []string{"/logo.png"}},
+		{"/removed", "status 200, origin 404", nil},
+		{"/blog/post", "status 200, origin 404; 1 missing assets", []string{"/blog/cover.jpg"}},
+		{"/style.css", "status 200, origin 404", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			compared = nil
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.differences, rec.Header().Get("X-Bare-Compare"))
+			require.Len(t, compared, 1)
+			assert.Equal(t, tc.missing, compared[0].Missing)
+		})
+	}
+
+	// The toolbar links to the origin, and isn't added to other files
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/about?page=2", nil))
+	assert.Contains(t, rec.Body.String(), `id="bare-compare"`)
+	assert.Contains(t, rec.Body.String(), `href="/about?_bare=origin&amp;page=2"`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/style.css", nil))
+	assert.Equal(t, "body{}", rec.Body.String())
+
+	// Redirects aren't compared
+	compared = nil
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/old", nil))
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Empty(t, compared)
+}
+
+func TestCompare_Origin(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/about?x=1", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body>origin %s</body></html>", r.URL.RawQuery)
+	}))
+	defer origin.Close()
+	originURL, err := neturl.Parse(origin.URL)
+	require.NoError(t, err)
+
+	handler := Compare(t.TempDir(), http.NotFoundHandler(), CompareOptions{Origin: originURL})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/about?_bare=origin&page=2", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "origin page=2")
+	assert.Contains(t, rec.Body.String(), `href="/about?page=2"`)
+
+	// Redirects stay on the origin view
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/old?_bare=origin", nil))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/about?_bare=origin&x=1", rec.Header().Get("Location"))
+}
+
+func TestCompare_IgnoresVolatileContent(t *testing.T) {
+	page := `<html><head><meta name="csrf-token" content="%s"><script nonce="%s"></script></head><body>Updated %s</body></html>`
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html": fmt.Sprintf(page, "a1b2", "n1", "2024-05-01T10:00:00Z"),
+	})
+
+	patterns, err := differ.CompilePatterns(differ.DefaultIgnorePatterns)
+	require.NoError(t, err)
+
+	var compared []Comparison
+	handler := Compare(dir, Host(dir, HostOptions{}), CompareOptions{
+		Render: func(ctx context.Context, path string) (int, []byte, error) {
+			return http.StatusOK, []byte(fmt.Sprintf(page, "c3d4", "n2", "2024-06-01T08:30:00Z")), nil
+		},
+		OnCompare: func(cmp Comparison) { compared = append(compared, cmp) },
+		Diff: differ.Options{
+			IgnoreSelectors:  differ.DefaultIgnoreSelectors,
+			IgnoreAttributes: differ.DefaultIgnoreAttributes,
+			IgnorePatterns:   patterns,
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "same", rec.Header().Get("X-Bare-Compare"))
+	require.Len(t, compared, 1)
+	assert.NotEmpty(t, compared[0].ExportHash)
+}
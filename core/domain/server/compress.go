@@ -0,0 +1,135 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// brotliLevel trades ratio for speed, as responses are compressed on every request.
+const brotliLevel = 5
+
+// Compress compresses responses with brotli or gzip on the fly, like hosts do for files
+// without precompressed sidecars. Responses that are already encoded, partial, smaller
+// than minSize or of a type that isn't worth compressing are sent as is.
+func Compress(next http.Handler, minSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := ""
+		if r.Method != http.MethodHead {
+			for _, enc := range encodings {
+				if acceptsEncoding(r.Header.Get("Accept-Encoding"), enc.name) {
+					encoding = enc.name
+					break
+				}
+			}
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter decides whether to compress a response once its headers are written.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string // chosen from the request, before next can change it
+	minSize     int
+	encoder     io.WriteCloser // nil if the response isn't compressed
+	wroteHeader bool
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+
+	header := c.Header()
+	if compressibleType(header.Get("Content-Type")) && !strings.Contains(strings.Join(header.Values("Vary"), ","), "Accept-Encoding") {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if c.compresses(status) {
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges") // ranges would apply to the compressed bytes
+		header.Set("Content-Encoding", c.encoding)
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag) // the bytes differ from the file's
+		}
+
+		if c.encoding == "br" {
+			c.encoder = brotli.NewWriterLevel(c.ResponseWriter, brotliLevel)
+		} else {
+			c.encoder, _ = gzip.NewWriterLevel(c.ResponseWriter, gzip.DefaultCompression)
+		}
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+// compresses reports whether a response with the headers written so far is compressed.
+func (c *compressWriter) compresses(status int) bool {
+	header := c.Header()
+	if c.encoding == "" || header.Get("Content-Encoding") != "" || !compressibleType(header.Get("Content-Type")) {
+		return false
+	}
+	if status < 200 || status == http.StatusNoContent || status == http.StatusPartialContent || status == http.StatusNotModified {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < c.minSize {
+		return false
+	}
+	return true
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		if c.Header().Get("Content-Type") == "" {
+			c.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.encoder != nil {
+		return c.encoder.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush sends the compressed data written so far, for streamed responses.
+func (c *compressWriter) Flush() {
+	if f, ok := c.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *compressWriter) close() {
+	if c.encoder != nil {
+		_ = c.encoder.Close()
+	}
+}
+
+// compressibleType checks if a Content-Type is text-based, and worth compressing.
+func compressibleType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	switch {
+	case mediaType == "text/event-stream":
+		return false // streamed, and compressing delays events
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+xml"), strings.HasSuffix(mediaType, "+json"):
+		return true
+	}
+	switch mediaType {
+	case "application/javascript", "application/json", "application/xml", "application/wasm",
+		"image/x-icon", "image/vnd.microsoft.icon", "font/ttf", "font/otf", "application/vnd.ms-fontobject":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	page := "<html><body>" + strings.Repeat("hello ", 500) + "</body></html>"
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html":     page,
+		"small.css":      "body{}",
+		"logo.png":       strings.Repeat("\x89PNG", 500),
+		"app.js":         strings.Repeat("console.log(1);", 100),
+		"app.js.gz":      "precompressed",
+		"assets/big.svg": "<svg>" + strings.Repeat("<g/>", 500) + "</svg>",
+	})
+	handler := Compress(Host(dir, HostOptions{Profile: Profiles["netlify"]}), 1024)
+
+	testCases := []struct {
+		path           string
+		acceptEncoding string
+		encoding       string
+	}{
+		{"/", "gzip, deflate, br", "br"},
+		{"/", "gzip", "gzip"},
+		{"/", "", ""},
+		{"/", "br;q=0, gzip", "gzip"},
+		{"/assets/big.svg", "gzip", "gzip"},
+		{"/small.css", "gzip", ""},  // smaller than the minimum size
+		{"/logo.png", "gzip", ""},   // already compressed
+		{"/app.js", "gzip", "gzip"}, // served from the sidecar
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path+" "+tc.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.encoding, rec.Header().Get("Content-Encoding"))
+			if tc.encoding != "" {
+				assert.Equal(t, []string{"Accept-Encoding"}, rec.Header().Values("Vary"))
+			}
+		})
+	}
+
+	decode := map[string]func(io.Reader) (io.Reader, error){
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+	for encoding, newReader := range decode {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Empty(t, rec.Header().Get("Content-Length"))
+		assert.True(t, strings.HasPrefix(rec.Header().Get("ETag"), `W/"`), rec.Header().Get("ETag"))
+
+		r, err := newReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, page, string(body), encoding)
+	}
+}
+
+func TestHost_CacheHeaders(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html":             "<html></html>",
+		"assets/app.1a2b3c4d.js": "js",
+		"_headers":               "/robots.txt\n  Cache-Control: no-store\n",
+		"robots.txt":             "User-agent: *",
+	})
+	handler := Host(dir, HostOptions{
+		Profile: Profiles["netlify"],
+		CacheControl: func(rel string) string {
+			if strings.HasPrefix(rel, "assets/") {
+				return "public, max-age=31536000, immutable"
+			}
+			return DefaultCacheControl
+		},
+	})
+
+	testCases := []struct {
+		path         string
+		cacheControl string
+	}{
+		{"/", DefaultCacheControl},
+		{"/assets/app.1a2b3c4d.js", "public, max-age=31536000, immutable"},
+		{"/robots.txt", "no-store"}, // _headers take precedence
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.cacheControl, rec.Header().Get("Cache-Control"))
+
+			// Browsers revalidate with the ETag
+			etag := rec.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("If-None-Match", etag)
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusNotModified, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile describes how a static host maps URLs to the files of a site.
+type Profile struct {
+	CleanURLs      bool // /about is served from about.html
+	StripHTML      bool // /about.html and /blog/index.html redirect to /about and /blog/
+	Rules          bool // _redirects and _headers files are applied
+	NotFoundPage   bool // /404.html is served for missing paths
+	RedirectStatus int  // status of the redirects normalizing URLs
+}
+
+// Profiles are the hosts bare serve can emulate.
+var Profiles = map[string]Profile{
+	// Netlify with pretty URLs, the default
+	"netlify":    {CleanURLs: true, StripHTML: true, Rules: true, NotFoundPage: true, RedirectStatus: http.StatusMovedPermanently},
+	"cloudflare": {CleanURLs: true, StripHTML: true, Rules: true, NotFoundPage: true, RedirectStatus: http.StatusPermanentRedirect},
+	// nginx with try_files $uri $uri/ $uri.html =404 and error_page 404 /404.html
+	"nginx": {CleanURLs: true, NotFoundPage: true, RedirectStatus: http.StatusMovedPermanently},
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HostOptions configures the Host handler.
+type HostOptions struct {
+	Profile Profile
+	SPA     bool // serve /index.html for missing paths without an extension
+	// CacheControl returns the Cache-Control of a file, from its slash-separated path
+	// relative to the directory. Defaults to DefaultCacheControl for every file.
+	// Headers set by _headers rules take precedence.
+	CacheControl func(rel string) string
+}
+
+// DefaultCacheControl makes browsers revalidate every file, like most static hosts do
+// for files without a fingerprint in their name.
+const DefaultCacheControl = "public, max-age=0, must-revalidate"
+
+// Host serves a directory the way a static host would, unlike http.FileServer:
+// no directory listings, clean URLs, a custom 404 page, redirect and header rules,
+// and precompressed sidecars.
+func Host(dir string, opts HostOptions) http.Handler {
+	if opts.Profile.RedirectStatus == 0 {
+		opts.Profile.RedirectStatus = http.StatusMovedPermanently
+	}
+	return &host{dir: dir, opts: opts, rules: &rules{dir: dir}}
+}
+
+type host struct {
+	dir   string
+	opts  HostOptions
+	rules *rules
+}
+
+func (h *host) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var redirects []Redirect
+	if h.opts.Profile.Rules {
+		var headers []Header
+		var err error
+		redirects, headers, err = h.rules.get()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, header := range headers {
+			if !header.Matches(r.URL.Path) {
+				continue
+			}
+			for name, values := range header.Values {
+				w.Header()[name] = append(w.Header()[name], values...)
+			}
+		}
+	}
+
+	file, canonical := h.resolve(r.URL.Path)
+
+	// Existing files shadow redirects, unless they are forced
+	exists := file != "" || canonical != ""
+	for _, redirect := range redirects {
+		if exists && !redirect.Force {
+			continue
+		}
+		if target, ok := redirect.Target(r.URL.Path); ok {
+			h.redirect(w, r, redirect, target)
+			return
+		}
+	}
+
+	if canonical != "" {
+		if r.URL.RawQuery != "" {
+			canonical += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, canonical, h.opts.Profile.RedirectStatus)
+		return
+	}
+	if file != "" {
+		h.serveFile(w, r, file, http.StatusOK)
+		return
+	}
+
+	if h.opts.SPA && path.Ext(r.URL.Path) == "" {
+		if index := h.lookup("/"); index != "" {
+			h.serveFile(w, r, index, http.StatusOK)
+			return
+		}
+	}
+
+	h.notFound(w, r)
+}
+
+// resolve maps a URL path to the file to serve, or to the canonical URL to redirect to.
+func (h *host) resolve(urlPath string) (file, canonical string) {
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	if h.isRulesFile(urlPath) {
+		return "", ""
+	}
+	fsPath := filepath.Join(h.dir, filepath.FromSlash(path.Clean(urlPath)))
+	trailingSlash := strings.HasSuffix(urlPath, "/")
+
+	if info, err := os.Stat(fsPath); err == nil {
+		if info.IsDir() {
+			index := filepath.Join(fsPath, "index.html")
+			if !isFile(index) {
+				return "", "" // no directory listings
+			}
+			if !trailingSlash {
+				return "", urlPath + "/"
+			}
+			return index, ""
+		}
+
+		if h.opts.Profile.StripHTML && !trailingSlash && strings.HasSuffix(urlPath, ".html") {
+			if strings.HasSuffix(urlPath, "/index.html") {
+				return "", strings.TrimSuffix(urlPath, "index.html")
+			}
+			return "", strings.TrimSuffix(urlPath, ".html")
+		}
+		if trailingSlash {
+			return "", "" // a file isn't a directory
+		}
+		return fsPath, ""
+	}
+
+	if h.opts.Profile.CleanURLs && path.Ext(urlPath) == "" {
+		if trimmed := strings.TrimSuffix(fsPath, string(filepath.Separator)); isFile(trimmed + ".html") {
+			if trailingSlash {
+				if h.opts.Profile.StripHTML {
+					return "", strings.TrimSuffix(urlPath, "/")
+				}
+				return "", ""
+			}
+			return trimmed + ".html", ""
+		}
+	}
+
+	return "", ""
+}
+
+// redirect applies a matching redirect rule.
+func (h *host) redirect(w http.ResponseWriter, r *http.Request, redirect Redirect, target string) {
+	switch {
+	case redirect.Status >= 300 && redirect.Status < 400:
+		if r.URL.RawQuery != "" && !strings.Contains(target, "?") {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, redirect.Status)
+
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		// Rewrites to another site proxy the request
+		u, err := neturl.Parse(target)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("_redirects:%d: invalid target %q", redirect.Line, target), http.StatusInternalServerError)
+			return
+		}
+		proxy := &httputil.ReverseProxy{Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL = u
+			pr.Out.Host = u.Host
+		}}
+		proxy.ServeHTTP(w, r)
+
+	default:
+		// Rewrites and custom statuses serve the target without changing the URL
+		targetPath, _, _ := strings.Cut(target, "?")
+		file := h.lookup(targetPath)
+		if file == "" {
+			h.notFound(w, r)
+			return
+		}
+		h.serveFile(w, r, file, redirect.Status)
+	}
+}
+
+// lookup returns the file a rewrite to urlPath serves: the file itself, its
+// directory's index.html or the path with .html appended, without redirects.
+func (h *host) lookup(urlPath string) string {
+	if h.isRulesFile(urlPath) {
+		return ""
+	}
+	fsPath := filepath.Join(h.dir, filepath.FromSlash(path.Clean("/"+urlPath)))
+	for _, candidate := range []string{fsPath, filepath.Join(fsPath, "index.html"), fsPath + ".html"} {
+		if isFile(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// isRulesFile reports whether urlPath is the _redirects or _headers file of the site,
+// which hosts applying them don't serve.
+func (h *host) isRulesFile(urlPath string) bool {
+	if !h.opts.Profile.Rules {
+		return false
+	}
+	p := path.Clean("/" + urlPath)
+	return p == "/_redirects" || p == "/_headers"
+}
+
+// notFound serves the 404 page of the site, or a plain 404.
+func (h *host) notFound(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Profile.NotFoundPage {
+		if page := filepath.Join(h.dir, "404.html"); isFile(page) {
+			h.serveFile(w, r, page, http.StatusNotFound)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// serveFile serves a file of the site with its Cache-Control.
+func (h *host) serveFile(w http.ResponseWriter, r *http.Request, file string, status int) {
+	if w.Header().Get("Cache-Control") == "" {
+		value := DefaultCacheControl
+		if h.opts.CacheControl != nil {
+			rel, _ := filepath.Rel(h.dir, file)
+			value = h.opts.CacheControl(filepath.ToSlash(rel))
+		}
+		if value != "" {
+			w.Header().Set("Cache-Control", value)
+		}
+	}
+	serveFile(w, r, file, status)
+}
+
+// isFile reports whether p exists and is a regular file.
+func isFile(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && !info.IsDir()
+}
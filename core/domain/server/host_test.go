@@ -0,0 +1,152 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHost(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html":      "home",
+		"about.html":      "about",
+		"blog/index.html": "blog",
+		"404.html":        "not found",
+		"assets/app.css":  "css",
+		"empty/.keep":     "",
+		"shadowed.html":   "shadowed",
+		"_redirects": `# comments are skipped
+/old-blog/*     /blog/:splat   301
+/posts/:slug    /blog/:slug    302
+/about          /elsewhere     301
+/legal          /about.html    200
+/gone           /404.html      410
+/shadowed       /forced        302!
+`,
+		"_headers": `/assets/*
+  Cache-Control: public, max-age=31536000
+/*
+  X-Frame-Options: DENY
+`,
+	})
+
+	testCases := []struct {
+		name     string
+		profile  string
+		path     string
+		status   int
+		body     string
+		location string
+	}{
+		{name: "index", profile: "netlify", path: "/", status: 200, body: "home"},
+		{name: "clean URL", profile: "netlify", path: "/about", status: 200, body: "about"},
+		{name: "strip .html", profile: "netlify", path: "/about.html?a=1", status: 301, location: "/about?a=1"},
+		{name: "strip index.html", profile: "netlify", path: "/blog/index.html", status: 301, location: "/blog/"},
+		{name: "directory adds slash", profile: "netlify", path: "/blog", status: 301, location: "/blog/"},
+		{name: "page removes slash", profile: "netlify", path: "/about/", status: 301, location: "/about"},
+		{name: "no listing", profile: "netlify", path: "/empty/", status: 404, body: "not found"},
+		{name: "404 page", profile: "netlify", path: "/missing", status: 404, body: "not found"},
+		{name: "splat redirect", profile: "netlify", path: "/old-blog/a/b", status: 301, location: "/blog/a/b"},
+		{name: "placeholder redirect", profile: "netlify", path: "/posts/hello", status: 302, location: "/blog/hello"},
+		{name: "file shadows redirect", profile: "netlify", path: "/about", status: 200, body: "about"},
+		{name: "forced redirect", profile: "netlify", path: "/shadowed", status: 302, location: "/forced"},
+		{name: "rewrite", profile: "netlify", path: "/legal", status: 200, body: "about"},
+		{name: "custom status", profile: "netlify", path: "/gone", status: 410, body: "not found"},
+		{name: "rules are not served", profile: "netlify", path: "/_redirects", status: 404, body: "not found"},
+		{name: "headers are not served", profile: "cloudflare", path: "/_headers", status: 404, body: "not found"},
+		{name: "cloudflare status", profile: "cloudflare", path: "/about.html", status: 308, location: "/about"},
+		{name: "nginx keeps .html", profile: "nginx", path: "/about.html", status: 200, body: "about"},
+		{name: "nginx clean URL", profile: "nginx", path: "/about", status: 200, body: "about"},
+		{name: "nginx ignores rules", profile: "nginx", path: "/posts/hello", status: 404, body: "not found"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := Host(dir, HostOptions{Profile: Profiles[tc.profile]})
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.location != "" {
+				assert.Equal(t, tc.location, rec.Header().Get("Location"))
+			} else {
+				assert.Equal(t, tc.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHost_Headers(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"assets/app.css": "css",
+		"_headers": `/assets/*
+  Cache-Control: public, max-age=31536000
+/*
+  X-Frame-Options: DENY
+  Link: </a.css>; rel=preload
+  Link: </b.css>; rel=preload
+`,
+	})
+	handler := Host(dir, HostOptions{Profile: Profiles["netlify"]})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.css", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "public, max-age=31536000", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+	assert.Equal(t, []string{"</a.css>; rel=preload", "</b.css>; rel=preload"}, rec.Header().Values("Link"))
+
+	// Rules are reloaded when the files change
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "_headers"), []byte("/*\n  X-Frame-Options: SAMEORIGIN\n  X-Extra: 1\n"), 0644))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.css", nil))
+	assert.Equal(t, "SAMEORIGIN", rec.Header().Get("X-Frame-Options"))
+	assert.Equal(t, DefaultCacheControl, rec.Header().Get("Cache-Control"))
+}
+
+func TestHost_SPA(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{"index.html": "app", "app.js": "js"})
+	handler := Host(dir, HostOptions{Profile: Profiles["netlify"], SPA: true})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard/settings", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "app", rec.Body.String())
+
+	// Missing assets are still missing
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing.js", nil))
+	assert.Equal(t, 404, rec.Code)
+}
+
+func TestHost_InvalidRules(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{"index.html": "home", "_redirects": "/a /b 301 extra\n"})
+	handler := Host(dir, HostOptions{Profile: Profiles["netlify"]})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, 500, rec.Code)
+	assert.Contains(t, rec.Body.String(), "_redirects:1")
+}
+
+func TestHost_Precompressed404(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{"404.html": "plain", "404.html.gz": "gzipped"})
+	handler := Host(dir, HostOptions{Profile: Profiles["netlify"]})
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, 404, rec.Code)
+	assert.Equal(t, "gzipped", rec.Body.String())
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+}
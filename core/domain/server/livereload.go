@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LiveReloadPath is the endpoint browsers listen to for reload events.
+const LiveReloadPath = "/_bare/livereload"
+
+// liveReloadScript reloads the page when it, or an asset, changed.
+const liveReloadScript = `<script>(function () {
+  var trim = function (p) { return p.replace(/(\/index\.html|\.html|\/)$/, "") || "/"; };
+  var source = new EventSource("` + LiveReloadPath + `");
+  source.addEventListener("reload", function (e) {
+    var paths = JSON.parse(e.data), here = trim(location.pathname);
+    if (paths.length === 0 || paths.some(function (p) { return trim(p) === here || /\.(css|js)$/.test(p); })) {
+      location.reload();
+    }
+  });
+})();</script>`
+
+// LiveReload pushes reload events to the pages open in browsers, over server-sent events.
+// The script listening to them is injected in the HTML responses of the handler it wraps,
+// never in the files on disk.
+type LiveReload struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// NewLiveReload creates a new LiveReload instance.
+func NewLiveReload() *LiveReload {
+	return &LiveReload{clients: make(map[chan []byte]struct{})}
+}
+
+// Reload tells the browsers showing one of paths to reload. Pages reload for any
+// change to a stylesheet or a script, and for any change if paths is empty.
+func (l *LiveReload) Reload(paths []string) {
+	if paths == nil {
+		paths = []string{}
+	}
+	data, _ := json.Marshal(paths)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.clients {
+		select {
+		case ch <- data:
+		default: // the browser is busy with a previous event
+		}
+	}
+}
+
+// Wrap serves the event stream on LiveReloadPath and injects the reload script in
+// the HTML pages served by next.
+func (l *LiveReload) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == LiveReloadPath {
+			l.serveEvents(w, r)
+			return
+		}
+
+		// Precompressed sidecars and partial responses can't be modified, and
+		// conditional requests could keep a page refreshed within the same second
+		for _, name := range []string{"Accept-Encoding", "Range", "If-Modified-Since", "If-None-Match"} {
+			r.Header.Del(name)
+		}
+
+		rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		body := rec.body.Bytes()
+		if strings.HasPrefix(rec.header.Get("Content-Type"), "text/html") && rec.header.Get("Content-Encoding") == "" && r.Method != http.MethodHead {
+			body = inject(body, []byte(liveReloadScript))
+			rec.header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+
+		for name, values := range rec.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(body)
+	})
+}
+
+// serveEvents streams reload events until the browser disconnects.
+func (l *LiveReload) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, 1)
+	l.mu.Lock()
+	l.clients[ch] = struct{}{}
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.clients, ch)
+		l.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-ch:
+			if _, err := fmt.Fprintf(w, "event: reload\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// inject adds a snippet before </body>, or at the end of the page.
+func inject(body, snippet []byte) []byte {
+	i := bytes.LastIndex(bytes.ToLower(body), []byte("</body>"))
+	if i < 0 {
+		return append(body, snippet...)
+	}
+
+	injected := make([]byte, 0, len(body)+len(snippet))
+	injected = append(injected, body[:i]...)
+	injected = append(injected, snippet...)
+	return append(injected, body[i:]...)
+}
+
+// bufferedResponse holds a response so that it can be modified before being sent.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
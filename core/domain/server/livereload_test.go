@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveReload_Inject(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html":    "<html><body><h1>Home</h1></BODY></html>",
+		"index.html.gz": "gzipped",
+		"style.css":     "body{}",
+	})
+	handler := NewLiveReload().Wrap(Host(dir, HostOptions{Profile: Profiles["netlify"]}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "<html><body><h1>Home</h1><script>"), rec.Body.String())
+	assert.True(t, strings.HasSuffix(rec.Body.String(), "</script></BODY></html>"))
+	assert.Equal(t, strconv.Itoa(rec.Body.Len()), rec.Header().Get("Content-Length"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/style.css", nil))
+	assert.Equal(t, "body{}", rec.Body.String())
+
+	// The files on disk are untouched
+	content, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "script")
+}
+
+func TestLiveReload_Events(t *testing.T) {
+	live := NewLiveReload()
+	server := httptest.NewServer(live.Wrap(http.NotFoundHandler()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + LiveReloadPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Wait for the browser to be registered
+	require.Eventually(t, func() bool {
+		live.mu.Lock()
+		defer live.mu.Unlock()
+		return len(live.clients) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	live.Reload([]string{"/about"})
+
+	reader := bufio.NewReader(resp.Body)
+	event, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	data, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: reload\n", event)
+	assert.Equal(t, "data: [\"/about\"]\n", data)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// encodings lists the precompressed sidecars we look for, in order of preference.
+var encodings = []struct {
+	name      string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Precompressed serves precompressed sidecar files (file.css.br, file.css.gz)
+// when the client accepts their encoding, like nginx's gzip_static does.
+// Requests without a matching sidecar are passed to next.
+func Precompressed(dir string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		file := resolveFile(dir, r.URL.Path)
+		if file == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if _, encoding := sidecar(file, r.Header.Get("Accept-Encoding")); encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		serveFile(w, r, file, http.StatusOK)
+	})
+}
+
+// serveFile serves a file with the given status, or its precompressed sidecar
+// if the client accepts its encoding.
+func serveFile(w http.ResponseWriter, r *http.Request, file string, status int) {
+	if w.Header().Get("Vary") == "" {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	name, encoding := sidecar(file, r.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		name = file
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(file))
+	if encoding != "" {
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	if status == http.StatusOK {
+		if w.Header().Get("ETag") == "" {
+			w.Header().Set("ETag", etag(info))
+		}
+		http.ServeContent(w, r, filepath.Base(file), info.ModTime(), f)
+		return
+	}
+
+	// Error pages and custom statuses skip conditional and range requests
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, f)
+	}
+}
+
+// etag derives a strong ETag from the modification time and size of a file, like nginx.
+// Sidecars get their own ETag, as their content differs.
+func etag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().Unix(), info.Size())
+}
+
+// sidecar returns the precompressed sidecar of a file the client accepts, and its encoding.
+// The encoding is empty if there is none.
+func sidecar(file, acceptEncoding string) (string, string) {
+	for _, enc := range encodings {
+		if acceptsEncoding(acceptEncoding, enc.name) && isFile(file+enc.extension) {
+			return file + enc.extension, enc.name
+		}
+	}
+	return "", ""
+}
+
+// resolveFile maps a URL path to the file http.FileServer would serve, or "" if there is none.
+func resolveFile(dir, urlPath string) string {
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	file := filepath.Join(dir, filepath.FromSlash(path.Clean(urlPath)))
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		// http.FileServer redirects directory requests without a trailing slash
+		if !strings.HasSuffix(urlPath, "/") {
+			return ""
+		}
+		file = filepath.Join(file, "index.html")
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			return ""
+		}
+	}
+	return file
+}
+
+// acceptsEncoding checks if an Accept-Encoding header allows the given encoding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) && strings.TrimSpace(name) != "*" {
+			continue
+		}
+		// An explicit q=0 means the encoding is refused
+		q := strings.ReplaceAll(strings.TrimSpace(params), " ", "")
+		if q == "q=0" || q == "q=0.0" || q == "q=0.00" || q == "q=0.000" {
+			return false
+		}
+		return true
+	}
+	return false
+}
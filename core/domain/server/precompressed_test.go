@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "style.css"), []byte("plain"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "style.css.gz"), []byte("gzipped"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "style.css.br"), []byte("brotli"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("home"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html.gz"), []byte("home gzipped"), 0644))
+
+	handler := Precompressed(dir, http.FileServer(http.Dir(dir)))
+
+	testCases := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantBody       string
+		wantEncoding   string
+		wantType       string
+	}{
+		{name: "prefers brotli", path: "/style.css", acceptEncoding: "gzip, deflate, br", wantBody: "brotli", wantEncoding: "br", wantType: "text/css; charset=utf-8"},
+		{name: "falls back to gzip", path: "/style.css", acceptEncoding: "gzip", wantBody: "gzipped", wantEncoding: "gzip", wantType: "text/css; charset=utf-8"},
+		{name: "refused encoding", path: "/style.css", acceptEncoding: "br;q=0, gzip", wantBody: "gzipped", wantEncoding: "gzip", wantType: "text/css; charset=utf-8"},
+		{name: "no accept-encoding", path: "/style.css", acceptEncoding: "", wantBody: "plain", wantEncoding: "", wantType: "text/css; charset=utf-8"},
+		{name: "directory index", path: "/", acceptEncoding: "br, gzip", wantBody: "home gzipped", wantEncoding: "gzip", wantType: "text/html; charset=utf-8"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+			assert.Equal(t, tc.wantEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, tc.wantType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MissingPath is the endpoint listing the paths the preview could not find.
+const MissingPath = "/_bare/missing"
+
+// Request is a request handled by the preview.
+type Request struct {
+	Method   string
+	Path     string
+	Status   int
+	Referrer string // path of the referring page on the preview, or the URL of another site
+	Duration time.Duration
+}
+
+// Missing lists the missing paths requested by a page.
+type Missing struct {
+	Referrer string   `json:"referrer"` // empty for paths typed in the address bar
+	Paths    []string `json:"paths"`
+}
+
+// RequestLog records the requests handled by the preview, and the paths that were
+// not found, e.g. assets the crawler missed.
+type RequestLog struct {
+	onRequest func(Request)
+	mu        sync.Mutex
+	missing   map[string]map[string]bool // referrer, then path
+}
+
+// NewRequestLog creates a RequestLog calling onRequest, if set, after every request.
+func NewRequestLog(onRequest func(Request)) *RequestLog {
+	return &RequestLog{onRequest: onRequest, missing: make(map[string]map[string]bool)}
+}
+
+// Wrap records the requests handled by next, and serves the missing paths on MissingPath,
+// as text or as JSON for clients accepting it. bare's own endpoints, and pages viewed
+// on the origin in compare mode, are not recorded.
+func (l *RequestLog) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == MissingPath {
+			l.serveMissing(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/_bare/") || r.URL.Query().Has(CompareParam) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		req := Request{
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Status:   sw.status,
+			Referrer: referrer(r),
+			Duration: time.Since(start),
+		}
+		if req.Status == http.StatusNotFound && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			l.mu.Lock()
+			if l.missing[req.Referrer] == nil {
+				l.missing[req.Referrer] = make(map[string]bool)
+			}
+			l.missing[req.Referrer][req.Path] = true
+			l.mu.Unlock()
+		}
+		if l.onRequest != nil {
+			l.onRequest(req)
+		}
+	})
+}
+
+// Missing returns the paths that were not found, grouped by referring page, sorted.
+func (l *RequestLog) Missing() []Missing {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	missing := make([]Missing, 0, len(l.missing))
+	for ref, paths := range l.missing {
+		m := Missing{Referrer: ref}
+		for p := range paths {
+			m.Paths = append(m.Paths, p)
+		}
+		sort.Strings(m.Paths)
+		missing = append(missing, m)
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Referrer < missing[j].Referrer })
+	return missing
+}
+
+// MissingPaths returns every path that was not found, once, sorted.
+func (l *RequestLog) MissingPaths() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, m := range l.Missing() {
+		for _, p := range m.Paths {
+			if !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// WriteMissing writes missing paths under their referring page.
+func WriteMissing(w io.Writer, missing []Missing) error {
+	for _, m := range missing {
+		ref := m.Referrer
+		if ref == "" {
+			ref = "(no referrer)"
+		}
+		if _, err := fmt.Fprintln(w, ref); err != nil {
+			return err
+		}
+		for _, p := range m.Paths {
+			if _, err := fmt.Fprintf(w, "  %s\n", p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *RequestLog) serveMissing(w http.ResponseWriter, r *http.Request) {
+	missing := l.Missing()
+	w.Header().Set("Cache-Control", "no-store")
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(missing)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if len(missing) == 0 {
+		_, _ = fmt.Fprintln(w, "No missing paths")
+		return
+	}
+	_ = WriteMissing(w, missing)
+}
+
+// referrer returns the path of the referring page if it is on the preview, or its URL.
+func referrer(r *http.Request) string {
+	ref := r.Header.Get("Referer")
+	u, err := neturl.Parse(ref)
+	if err != nil || ref == "" {
+		return ref
+	}
+	if u.Host == r.Host {
+		return u.Path
+	}
+	return ref
+}
+
+// statusWriter records the status of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusWriter) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Flush keeps streamed responses, such as live reload events, working.
+func (s *statusWriter) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
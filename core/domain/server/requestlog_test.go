@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/felixdorn/bare/core/domain/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLog(t *testing.T) {
+	dir := testutil.WriteSite(t, map[string]string{
+		"index.html": "<html></html>",
+		"about.html": "<html></html>",
+	})
+
+	var requests []Request
+	log := NewRequestLog(func(r Request) { requests = append(requests, r) })
+	handler := log.Wrap(Host(dir, HostOptions{Profile: Profiles["netlify"]}))
+
+	get := func(path, referrer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if referrer != "" {
+			req.Header.Set("Referer", referrer)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	get("/", "")
+	get("/img/logo.png", "http://example.com/")
+	get("/js/app.js", "http://example.com/")
+	get("/img/logo.png", "http://example.com/about")
+	get("/img/logo.png", "http://example.com/about") // recorded once
+	get("/about.html", "http://example.com/")
+	get("/favicon.ico", "")
+	get("/page?_bare=origin", "") // the origin's 404s are not the export's
+	get("/nope", "https://google.com/search?q=bare")
+
+	require.Len(t, requests, 8)
+	assert.Equal(t, Request{Method: "GET", Path: "/img/logo.png", Status: 404, Referrer: "/"}, withoutDuration(requests[1]))
+	assert.Equal(t, http.StatusMovedPermanently, requests[5].Status)
+
+	expected := []Missing{
+		{Referrer: "", Paths: []string{"/favicon.ico"}},
+		{Referrer: "/", Paths: []string{"/img/logo.png", "/js/app.js"}},
+		{Referrer: "/about", Paths: []string{"/img/logo.png"}},
+		{Referrer: "https://google.com/search?q=bare", Paths: []string{"/nope"}},
+	}
+	assert.Equal(t, expected, log.Missing())
+	assert.Equal(t, []string{"/favicon.ico", "/img/logo.png", "/js/app.js", "/nope"}, log.MissingPaths())
+
+	rec := get(MissingPath, "")
+	assert.Equal(t, `(no referrer)
+  /favicon.ico
+/
+  /img/logo.png
+  /js/app.js
+/about
+  /img/logo.png
+https://google.com/search?q=bare
+  /nope
+`, rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, MissingPath, nil)
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var decoded []Missing
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded))
+	assert.Equal(t, expected, decoded)
+
+	// The endpoint isn't recorded
+	assert.Len(t, requests, 8)
+}
+
+func withoutDuration(r Request) Request {
+	r.Duration = 0
+	return r
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Redirect is a rule of a _redirects file, in the format shared by Netlify and Cloudflare Pages:
+//
+//	/old-path   /new-path   301
+//	/blog/:slug /posts/:slug
+//	/app/*      /app/index.html 200
+//	/forced     /elsewhere  302!
+type Redirect struct {
+	From   string
+	To     string
+	Status int  // 3xx redirects, 200 rewrites, other statuses serve To with that status
+	Force  bool // applies even when a file exists at From
+	Line   int
+
+	pattern *regexp.Regexp
+}
+
+// Header is a block of a _headers file: the headers set on every path matching Path.
+//
+//	/assets/*
+//	  Cache-Control: public, max-age=31536000, immutable
+type Header struct {
+	Path   string
+	Values http.Header
+	Line   int
+
+	pattern *regexp.Regexp
+}
+
+var (
+	placeholderRegex        = regexp.MustCompile(`:[a-zA-Z_][a-zA-Z0-9_]*`)
+	leadingPlaceholderRegex = regexp.MustCompile(`^:[a-zA-Z_][a-zA-Z0-9_]*`)
+)
+
+// compilePattern turns a rule path into a regular expression. ":name" matches a
+// segment and "*" matches the rest of the path, available as ":splat".
+func compilePattern(p string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(p); {
+		switch {
+		case p[i] == '*':
+			b.WriteString(`(?P<splat>.*)`)
+			i++
+		case leadingPlaceholderRegex.MatchString(p[i:]):
+			name := leadingPlaceholderRegex.FindString(p[i:])
+			fmt.Fprintf(&b, `(?P<%s>[^/]+)`, name[1:])
+			i += len(name)
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+			i++
+		}
+	}
+	// A trailing slash is optional, as on the hosts
+	pattern := strings.TrimSuffix(b.String(), "/")
+	return regexp.Compile(pattern + "/?$")
+}
+
+// match returns the placeholder values of a path matching the rule, or nil.
+func match(pattern *regexp.Regexp, urlPath string) map[string]string {
+	m := pattern.FindStringSubmatch(urlPath)
+	if m == nil {
+		return nil
+	}
+	values := make(map[string]string)
+	for i, name := range pattern.SubexpNames() {
+		if name != "" {
+			values[name] = m[i]
+		}
+	}
+	return values
+}
+
+// Target returns the destination of the redirect for a path it matches, with
+// the placeholders of To replaced, and false if the rule doesn't match.
+func (r Redirect) Target(urlPath string) (string, bool) {
+	values := match(r.pattern, urlPath)
+	if values == nil {
+		return "", false
+	}
+	return placeholderRegex.ReplaceAllStringFunc(r.To, func(ph string) string {
+		if v, ok := values[ph[1:]]; ok {
+			return v
+		}
+		return ph
+	}), true
+}
+
+// Matches reports whether the header block applies to a path.
+func (h Header) Matches(urlPath string) bool {
+	return h.pattern.MatchString(urlPath)
+}
+
+// ParseRedirects parses the content of a _redirects file.
+func ParseRedirects(content []byte) ([]Redirect, error) {
+	var redirects []Redirect
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var fields []string
+		for _, f := range strings.Fields(text) {
+			if strings.HasPrefix(f, "#") {
+				break // trailing comment
+			}
+			if strings.Contains(f, "=") {
+				continue // query and country conditions aren't emulated
+			}
+			fields = append(fields, f)
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("_redirects:%d: expected 'from to [status]'", line)
+		}
+
+		r := Redirect{From: fields[0], To: fields[1], Status: http.StatusMovedPermanently, Line: line}
+		if len(fields) == 3 {
+			status, force := strings.CutSuffix(fields[2], "!")
+			code, err := strconv.Atoi(status)
+			if err != nil || code < 200 || code > 599 {
+				return nil, fmt.Errorf("_redirects:%d: invalid status %q", line, fields[2])
+			}
+			r.Status, r.Force = code, force
+		}
+		if !strings.HasPrefix(r.From, "/") {
+			return nil, fmt.Errorf("_redirects:%d: %q must start with /", line, r.From)
+		}
+
+		pattern, err := compilePattern(r.From)
+		if err != nil {
+			return nil, fmt.Errorf("_redirects:%d: %w", line, err)
+		}
+		r.pattern = pattern
+		redirects = append(redirects, r)
+	}
+
+	return redirects, scanner.Err()
+}
+
+// ParseHeaders parses the content of a _headers file.
+func ParseHeaders(content []byte) ([]Header, error) {
+	var headers []Header
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// Paths start a block, indented lines are its headers
+		if raw[0] != ' ' && raw[0] != '\t' {
+			pattern, err := compilePattern(text)
+			if err != nil {
+				return nil, fmt.Errorf("_headers:%d: %w", line, err)
+			}
+			headers = append(headers, Header{Path: text, Values: http.Header{}, Line: line, pattern: pattern})
+			continue
+		}
+
+		if len(headers) == 0 {
+			return nil, fmt.Errorf("_headers:%d: header outside of a path block", line)
+		}
+		name, value, ok := strings.Cut(text, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("_headers:%d: expected 'Name: value'", line)
+		}
+		headers[len(headers)-1].Values.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return headers, scanner.Err()
+}
+
+// rules holds the _redirects and _headers of a directory, reloaded when they change.
+type rules struct {
+	dir string
+
+	mu        sync.Mutex
+	redirects []Redirect
+	headers   []Header
+	versions  [2]string
+	err       error
+}
+
+// get returns the rules, reparsing the files if they changed since the last call.
+func (r *rules) get() ([]Redirect, []Header, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var versions [2]string
+	for i, name := range []string{"_redirects", "_headers"} {
+		if info, err := os.Stat(filepath.Join(r.dir, name)); err == nil {
+			versions[i] = fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	if versions == r.versions && r.versions != [2]string{} {
+		return r.redirects, r.headers, r.err
+	}
+	r.versions = versions
+	r.redirects, r.headers, r.err = nil, nil, nil
+
+	if content, err := os.ReadFile(filepath.Join(r.dir, "_redirects")); err == nil {
+		if r.redirects, r.err = ParseRedirects(content); r.err != nil {
+			return nil, nil, r.err
+		}
+	}
+	if content, err := os.ReadFile(filepath.Join(r.dir, "_headers")); err == nil {
+		if r.headers, r.err = ParseHeaders(content); r.err != nil {
+			return nil, nil, r.err
+		}
+	}
+	return r.redirects, r.headers, nil
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 397 * 24 * time.Hour // the longest browsers accept
+	certRenewal  = 7 * 24 * time.Hour   // renew certificates expiring sooner than this
+)
+
+// LocalCA is a certificate authority, created on the machine, signing the certificates
+// of the HTTPS preview. Once its certificate is trusted by the system, browsers accept
+// the preview without warnings.
+type LocalCA struct {
+	CertFile string // PEM certificate to add to the system's trust store
+	dir      string
+	cert     *x509.Certificate
+	key      crypto.Signer
+}
+
+// LoadCA loads the local certificate authority stored in dir, or creates it.
+// created reports whether it was just created, and needs to be trusted.
+func LoadCA(dir string) (ca *LocalCA, created bool, err error) {
+	ca = &LocalCA{CertFile: filepath.Join(dir, "ca.pem"), dir: dir}
+	keyFile := filepath.Join(dir, "ca-key.pem")
+
+	if ca.cert, ca.key, err = loadPair(ca.CertFile, keyFile); err == nil {
+		return ca, false, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, false, fmt.Errorf("could not load the local certificate authority: %w", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:               pkix.Name{Organization: []string{"bare"}, CommonName: "bare local development CA"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	if ca.cert, ca.key, err = createPair(dir, ca.CertFile, keyFile, template, caValidity, nil, nil); err != nil {
+		return nil, false, fmt.Errorf("could not create the local certificate authority: %w", err)
+	}
+	return ca, true, nil
+}
+
+// Certificate returns a certificate for hosts, names or IP addresses, signed by the
+// authority. It is stored next to the authority and reused until it expires or no
+// longer covers hosts.
+func (ca *LocalCA) Certificate(hosts []string) (tls.Certificate, error) {
+	certFile := filepath.Join(ca.dir, "cert.pem")
+	keyFile := filepath.Join(ca.dir, "key.pem")
+
+	if cert, _, err := loadPair(certFile, keyFile); err == nil && ca.covers(cert, hosts) {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{Organization: []string{"bare"}, CommonName: hosts[0]},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	if _, _, err := createPair(ca.dir, certFile, keyFile, template, certValidity, ca.cert, ca.key); err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not create a certificate: %w", err)
+	}
+	return tls.LoadX509KeyPair(certFile, keyFile)
+}
+
+// covers reports whether a certificate was signed by the authority, is valid for a
+// while and is valid for every host.
+func (ca *LocalCA) covers(cert *x509.Certificate, hosts []string) bool {
+	if cert.CheckSignatureFrom(ca.cert) != nil || time.Until(cert.NotAfter) < certRenewal {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// loadPair reads a PEM certificate and its private key.
+func loadPair(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		if _, statErr := os.Stat(certFile); errors.Is(statErr, os.ErrNotExist) {
+			return nil, nil, statErr
+		}
+		return nil, nil, err
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: unsupported private key", keyFile)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// createPair generates a key, signs a certificate from template with the parent, or
+// itself without one, and writes both in PEM files.
+func createPair(dir, certFile, keyFile string, template *x509.Certificate, validity time.Duration, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if template.SerialNumber, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)); err != nil {
+		return nil, nil, err
+	}
+	template.NotBefore = time.Now().Add(-time.Hour) // tolerate clock skew
+	template.NotAfter = time.Now().Add(validity)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"crypto/x509"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalCA(t *testing.T) {
+	dir := t.TempDir()
+
+	ca, created, err := LoadCA(dir)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	cert, err := ca.Certificate([]string{"localhost", "127.0.0.1"})
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	// The certificate is trusted once the authority is
+	pem, err := os.ReadFile(ca.CertFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(pem))
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		_, err = leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+		assert.NoError(t, err, host)
+	}
+
+	// Both are reused
+	ca, created, err = LoadCA(dir)
+	require.NoError(t, err)
+	assert.False(t, created)
+	again, err := ca.Certificate([]string{"127.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, cert.Certificate[0], again.Certificate[0])
+
+	// The certificate is renewed for new hosts
+	renewed, err := ca.Certificate([]string{"localhost", "192.168.1.20"})
+	require.NoError(t, err)
+	assert.NotEqual(t, cert.Certificate[0], renewed.Certificate[0])
+	leaf, err = x509.ParseCertificate(renewed.Certificate[0])
+	require.NoError(t, err)
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "192.168.1.20", Roots: roots})
+	assert.NoError(t, err)
+}
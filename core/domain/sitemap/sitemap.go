@@ -0,0 +1,265 @@
+package sitemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/url"
+)
+
+// MaxURLs is the number of URLs a single sitemap file may list, per the sitemaps.org protocol.
+// Larger sites get a sitemap index pointing to several sitemap files.
+const MaxURLs = 50000
+
+const xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// Entry is an exported page listed in the sitemap.
+type Entry struct {
+	Path         string    // root-relative URL path of the page
+	LastModified time.Time // from the Last-Modified response header, zero if unknown
+	Hash         string    // content hash, used to date pages without a Last-Modified header
+}
+
+// Options configures the Generator.
+type Options struct {
+	PublicURL *url.URL // URL the export is deployed to, used for absolute <loc> entries
+	Robots    bool     // also write a robots.txt pointing to the sitemap
+	State     string   // file remembering content hashes between exports, no state is kept if empty
+	MaxURLs   int      // URLs per sitemap file, defaults to MaxURLs
+}
+
+// Stats summarizes a generation run.
+type Stats struct {
+	URLs  int
+	Files int // sitemap files written, including the index
+}
+
+// Generator writes sitemap.xml (or a sitemap index and its parts) to an output directory.
+type Generator struct {
+	OutputDir string
+	opts      Options
+	now       func() time.Time
+}
+
+// New creates a new Generator instance.
+func New(outputDir string, opts Options) *Generator {
+	if opts.MaxURLs <= 0 {
+		opts.MaxURLs = MaxURLs
+	}
+
+	return &Generator{
+		OutputDir: outputDir,
+		opts:      opts,
+		now:       time.Now,
+	}
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	URLs    []urlTag `xml:"url"`
+}
+
+type urlTag struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapTag `xml:"sitemap"`
+}
+
+type sitemapTag struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// stateEntry is what the state file remembers about a page.
+type stateEntry struct {
+	Hash         string    `json:"hash"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Run writes the sitemap for the given entries.
+func (g *Generator) Run(entries []Entry) (Stats, error) {
+	if g.opts.PublicURL == nil || g.opts.PublicURL.Host == "" {
+		return Stats{}, errors.New("a public URL is required to generate a sitemap")
+	}
+
+	entries = dedupe(entries)
+
+	state, err := g.loadState()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	now := g.now().UTC().Truncate(time.Second)
+	next := make(map[string]stateEntry, len(entries))
+	urls := make([]urlTag, len(entries))
+	var newest time.Time
+
+	for i, entry := range entries {
+		modified := entry.LastModified
+		if modified.IsZero() && entry.Hash != "" && g.opts.State != "" {
+			// Without a Last-Modified header, a page is dated by the export where its content last changed
+			modified = now
+			if prev, ok := state[entry.Path]; ok && prev.Hash == entry.Hash {
+				modified = prev.LastModified
+			}
+		}
+		next[entry.Path] = stateEntry{Hash: entry.Hash, LastModified: modified}
+
+		if modified.After(newest) {
+			newest = modified
+		}
+		urls[i] = urlTag{Loc: g.loc(entry.Path), LastMod: formatTime(modified)}
+	}
+
+	stats := Stats{URLs: len(urls)}
+
+	if len(urls) <= g.opts.MaxURLs {
+		if err := writeXML(filepath.Join(g.OutputDir, "sitemap.xml"), urlSet{Xmlns: xmlns, URLs: urls}); err != nil {
+			return Stats{}, err
+		}
+		stats.Files = 1
+	} else {
+		index := sitemapIndex{Xmlns: xmlns}
+		for part := 0; part*g.opts.MaxURLs < len(urls); part++ {
+			chunk := urls[part*g.opts.MaxURLs : min((part+1)*g.opts.MaxURLs, len(urls))]
+			name := fmt.Sprintf("sitemap-%d.xml", part+1)
+			if err := writeXML(filepath.Join(g.OutputDir, name), urlSet{Xmlns: xmlns, URLs: chunk}); err != nil {
+				return Stats{}, err
+			}
+			index.Sitemaps = append(index.Sitemaps, sitemapTag{Loc: g.loc("/" + name), LastMod: formatTime(newest)})
+		}
+		if err := writeXML(filepath.Join(g.OutputDir, "sitemap.xml"), index); err != nil {
+			return Stats{}, err
+		}
+		stats.Files = len(index.Sitemaps) + 1
+	}
+
+	if g.opts.Robots {
+		if err := g.writeRobots(); err != nil {
+			return Stats{}, err
+		}
+	}
+
+	if err := g.saveState(next); err != nil {
+		return Stats{}, err
+	}
+
+	return stats, nil
+}
+
+// writeRobots writes a robots.txt allowing everything and pointing to the sitemap.
+// A robots.txt exported from the origin is kept, and gets the Sitemap line appended if it lacks one.
+func (g *Generator) writeRobots() error {
+	path := filepath.Join(g.OutputDir, "robots.txt")
+	line := "Sitemap: " + g.loc("/sitemap.xml") + "\n"
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if len(existing) == 0 {
+		return os.WriteFile(path, []byte("User-agent: *\nDisallow:\n\n"+line), 0644)
+	}
+
+	if bytes.Contains(existing, []byte(strings.TrimSpace(line))) {
+		return nil
+	}
+	if !bytes.HasSuffix(existing, []byte("\n")) {
+		existing = append(existing, '\n')
+	}
+	return os.WriteFile(path, append(existing, []byte("\n"+line)...), 0644)
+}
+
+// loc returns the absolute public URL of a root-relative path.
+func (g *Generator) loc(path string) string {
+	return strings.TrimRight(g.opts.PublicURL.String(), "/") + path
+}
+
+func (g *Generator) loadState() (map[string]stateEntry, error) {
+	state := make(map[string]stateEntry)
+	if g.opts.State == "" {
+		return state, nil
+	}
+
+	byt, err := os.ReadFile(g.opts.State)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read sitemap state: %w", err)
+	}
+	if err := json.Unmarshal(byt, &state); err != nil {
+		return nil, fmt.Errorf("could not parse sitemap state %s: %w", g.opts.State, err)
+	}
+	return state, nil
+}
+
+func (g *Generator) saveState(state map[string]stateEntry) error {
+	if g.opts.State == "" {
+		return nil
+	}
+
+	byt, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(g.opts.State), 0755); err != nil {
+		return fmt.Errorf("could not create directory for sitemap state: %w", err)
+	}
+	if err := os.WriteFile(g.opts.State, byt, 0644); err != nil {
+		return fmt.Errorf("could not write sitemap state: %w", err)
+	}
+	return nil
+}
+
+// dedupe sorts entries by path and drops duplicates, keeping the first one.
+func dedupe(entries []Entry) []Entry {
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	kept := sorted[:0]
+	for _, entry := range sorted {
+		if len(kept) > 0 && entry.Path == kept[len(kept)-1].Path {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+func writeXML(path string, v any) error {
+	byt, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	content := append([]byte(xml.Header), byt...)
+	content = append(content, '\n')
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// formatTime formats a time in the W3C datetime format used by sitemaps, or "" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
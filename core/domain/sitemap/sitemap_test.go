@@ -0,0 +1,121 @@
+package sitemap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publicURL(t *testing.T) *url.URL {
+	u, err := url.Parse("https://example.com/")
+	require.NoError(t, err)
+	return u
+}
+
+func TestGenerator_Run(t *testing.T) {
+	dir := t.TempDir()
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	stats, err := New(dir, Options{PublicURL: publicURL(t)}).Run([]Entry{
+		{Path: "/about", LastModified: modified},
+		{Path: "/"},
+		{Path: "/about"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{URLs: 2, Files: 1}, stats)
+
+	content, err := os.ReadFile(filepath.Join(dir, "sitemap.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	assert.Contains(t, string(content), "<url>\n    <loc>https://example.com/</loc>\n  </url>")
+	assert.Contains(t, string(content), "<loc>https://example.com/about</loc>\n    <lastmod>2024-03-01T12:00:00Z</lastmod>")
+
+	assert.NoFileExists(t, filepath.Join(dir, "robots.txt"))
+}
+
+func TestGenerator_Run_Index(t *testing.T) {
+	dir := t.TempDir()
+
+	entries := []Entry{{Path: "/a"}, {Path: "/b"}, {Path: "/c"}, {Path: "/d"}, {Path: "/e"}}
+	stats, err := New(dir, Options{PublicURL: publicURL(t), MaxURLs: 2}).Run(entries)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{URLs: 5, Files: 4}, stats)
+
+	index, err := os.ReadFile(filepath.Join(dir, "sitemap.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "<sitemapindex")
+	for _, name := range []string{"sitemap-1.xml", "sitemap-2.xml", "sitemap-3.xml"} {
+		assert.Contains(t, string(index), "<loc>https://example.com/"+name+"</loc>")
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+
+	last, err := os.ReadFile(filepath.Join(dir, "sitemap-3.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(last), "https://example.com/e")
+	assert.NotContains(t, string(last), "https://example.com/d")
+}
+
+func TestGenerator_Run_HashLastMod(t *testing.T) {
+	dir := t.TempDir()
+	state := filepath.Join(t.TempDir(), ".bare", "sitemap.json")
+
+	run := func(now time.Time, entries []Entry) string {
+		g := New(dir, Options{PublicURL: publicURL(t), State: state})
+		g.now = func() time.Time { return now }
+		_, err := g.Run(entries)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dir, "sitemap.xml"))
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	content := run(first, []Entry{{Path: "/a", Hash: "1"}, {Path: "/b", Hash: "1"}})
+	assert.Contains(t, content, "<loc>https://example.com/a</loc>\n    <lastmod>2024-01-01T00:00:00Z</lastmod>")
+
+	// Only /b changed, /a keeps the date of the export where it last changed
+	content = run(second, []Entry{{Path: "/a", Hash: "1"}, {Path: "/b", Hash: "2"}})
+	assert.Contains(t, content, "<loc>https://example.com/a</loc>\n    <lastmod>2024-01-01T00:00:00Z</lastmod>")
+	assert.Contains(t, content, "<loc>https://example.com/b</loc>\n    <lastmod>2024-02-01T00:00:00Z</lastmod>")
+}
+
+func TestGenerator_Run_Robots(t *testing.T) {
+	testCases := []struct {
+		name     string
+		existing string
+		expected string
+	}{
+		{"generated", "", "User-agent: *\nDisallow:\n\nSitemap: https://example.com/sitemap.xml\n"},
+		{"appended", "User-agent: *\nDisallow: /admin", "User-agent: *\nDisallow: /admin\n\nSitemap: https://example.com/sitemap.xml\n"},
+		{"already listed", "Sitemap: https://example.com/sitemap.xml\n", "Sitemap: https://example.com/sitemap.xml\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tc.existing != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "robots.txt"), []byte(tc.existing), 0644))
+			}
+
+			_, err := New(dir, Options{PublicURL: publicURL(t), Robots: true}).Run([]Entry{{Path: "/"}})
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(filepath.Join(dir, "robots.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(content))
+		})
+	}
+}
+
+func TestGenerator_Run_RequiresPublicURL(t *testing.T) {
+	_, err := New(t.TempDir(), Options{}).Run([]Entry{{Path: "/"}})
+	assert.Error(t, err)
+}
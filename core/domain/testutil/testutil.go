@@ -0,0 +1,24 @@
+// Package testutil holds the fixtures shared by the tests of the domain packages.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// WriteSite writes files, keyed by their slash-separated path, to a temporary directory
+// and returns it. The directory is removed when the test ends.
+func WriteSite(t testing.TB, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+	return dir
+}
@@ -1,39 +1,263 @@
 package url
 
 import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
 	"strings"
+	"sync"
 )
 
 // Path represents a URL path pattern for matching against other paths.
 type Path string
 
-// Match checks if the given path string `s` matches the pattern `p`.
+// pattern is a Path parsed for matching.
+type pattern struct {
+	negated bool
+	scheme  string // empty for any scheme
+	host    string // glob, empty if the pattern is not host-qualified
+	re      *regexp.Regexp
+	segs    []string
+	query   []queryParam
+	err     error
+}
+
+// queryParam is a query parameter a pattern requires, e.g. "page=*" or "preview".
+type queryParam struct {
+	key      string
+	value    string // glob
+	hasValue bool
+}
+
+// subject is what a pattern is matched against.
+type subject struct {
+	scheme, host, path, query string
+}
+
+// patterns caches parsed patterns, as the same few patterns are matched against every URL of a crawl.
+var patterns sync.Map
+
+func (p Path) parse() *pattern {
+	if cached, ok := patterns.Load(p); ok {
+		return cached.(*pattern)
+	}
+
+	parsed := &pattern{}
+	s := string(p)
+	if strings.HasPrefix(s, "!") {
+		parsed.negated = true
+		s = s[1:]
+	}
+
+	if expr, ok := strings.CutPrefix(s, "re:"); ok {
+		parsed.re, parsed.err = regexp.Compile(`^(?:` + expr + `)$`)
+		if parsed.err != nil {
+			parsed.err = fmt.Errorf("invalid regular expression %q: %w", expr, parsed.err)
+		}
+		patterns.Store(p, parsed)
+		return parsed
+	}
+
+	// Host-qualified patterns, e.g. "//cdn.example.com/**" or "https://*.example.com/**"
+	if scheme, rest, ok := strings.Cut(s, "://"); ok && !strings.Contains(scheme, "/") {
+		parsed.scheme = scheme
+		s = "//" + rest
+	}
+	if rest, ok := strings.CutPrefix(s, "//"); ok {
+		host, pathAndQuery, _ := strings.Cut(rest, "/")
+		parsed.host = host
+		s = "/" + pathAndQuery
+		if host == "" {
+			parsed.err = fmt.Errorf("%q has no host", string(p))
+		}
+	}
+
+	if i := queryStart(s); i != -1 {
+		var rawQuery string
+		s, rawQuery = s[:i], s[i+1:]
+		for _, param := range strings.Split(rawQuery, "&") {
+			key, value, hasValue := strings.Cut(param, "=")
+			if key == "" {
+				continue
+			}
+			value = strings.ReplaceAll(value, "[!", "[^")
+			parsed.query = append(parsed.query, queryParam{key: key, value: value, hasValue: hasValue})
+		}
+	}
+
+	// Classes are negated with "[!...]" like in shells, path.Match expects "[^...]"
+	s = strings.ReplaceAll(s, "[!", "[^")
+	parsed.host = strings.ReplaceAll(parsed.host, "[!", "[^")
+	parsed.segs = strings.Split(strings.Trim(s, "/"), "/")
+	for _, glob := range append(parsed.segs, parsed.host) {
+		if _, err := path.Match(glob, ""); err != nil && parsed.err == nil {
+			parsed.err = fmt.Errorf("%q has an invalid character class or escape", string(p))
+		}
+	}
+	for _, param := range parsed.query {
+		if _, err := path.Match(param.value, ""); err != nil && parsed.err == nil {
+			parsed.err = fmt.Errorf("%q has an invalid character class or escape", string(p))
+		}
+	}
+
+	patterns.Store(p, parsed)
+	return parsed
+}
+
+// queryStart finds the "?" starting the query of a pattern: the first one followed by
+// a parameter name and no more segments. Other "?" are single-character wildcards.
+func queryStart(s string) int {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] != '?' || strings.Contains(s[i:], "/") {
+			continue
+		}
+		if c := s[i+1]; c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsNegated reports whether the pattern starts with "!", so it excludes what it matches
+// from the patterns before it in Paths.
+func (p Path) IsNegated() bool {
+	return strings.HasPrefix(string(p), "!")
+}
+
+// Matches checks if the given path string `s` matches the pattern `p`.
 // The matching is path-segment based and supports wildcards.
 // It ignores leading and trailing slashes for both the pattern and the path.
+// A leading "!" is ignored, see Paths for negation.
 //
 // Wildcards:
-//   - `*`: matches any single path segment.
+//   - `*`: matches any single path segment, or any characters within a segment, e.g. "*.pdf".
 //   - `**`: matches zero or more path segments.
+//   - `?`: matches any single character within a segment.
+//   - `[abc]`, `[a-z]`, `[!0-9]` or `[^0-9]`: matches a character of the class, or not in it.
+//
+// Patterns can also be:
+//   - a regular expression prefixed with "re:", matching the whole path and its query
+//     string, if any, e.g. "re:/blog/\d{4}/.*".
+//   - followed by query parameters the path must have, e.g. "/search?q=*&page" (other
+//     parameters are ignored). Without a query, the query string of the path is ignored.
+//     A "?" followed by a parameter name in the last segment starts the query, any other
+//     "?" is a wildcard.
+//   - qualified with a host, e.g. "//cdn.example.com/**" or "https://*.example.com/**",
+//     which only match full URLs.
+//
+// `s` is a path, with an optional query string, or a full URL.
 //
 // Examples:
 //   - Pattern "/posts/*" matches "/posts/my-first-post" but not "/posts/a/b" or "/posts/".
 //   - Pattern "/posts/**" matches "/posts/my-first-post", "/posts/a/b", and "/posts/".
 //   - Pattern "/**/secret" matches "/top/secret" and "/secret".
+//   - Pattern "/blog/20??/*" matches "/blog/2024/hello" but not "/blog/drafts/hello".
 func (p Path) Matches(s string) bool {
-	pattern := strings.Trim(string(p), "/")
-	path := strings.Trim(s, "/")
+	return p.parse().matches(parseSubject(s))
+}
 
-	pSegs := strings.Split(pattern, "/")
-	sSegs := strings.Split(path, "/")
+// MatchesURL checks if a URL matches the pattern, like Matches.
+func (p Path) MatchesURL(u *URL) bool {
+	return p.parse().matches(subjectOf(u))
+}
 
+func parseSubject(s string) subject {
+	var sub subject
+	if scheme, rest, ok := strings.Cut(s, "://"); ok && !strings.Contains(scheme, "/") {
+		sub.scheme = scheme
+		end := strings.IndexAny(rest, "/?#")
+		if end == -1 {
+			end = len(rest)
+		}
+		sub.host, s = rest[:end], rest[end:]
+	}
+	s, _, _ = strings.Cut(s, "#")
+	sub.path, sub.query, _ = strings.Cut(s, "?")
+	return sub
+}
+
+func subjectOf(u *URL) subject {
+	if u == nil || u.URL == nil {
+		return subject{}
+	}
+	return subject{scheme: u.Scheme, host: u.Host, path: u.Path, query: u.RawQuery}
+}
+
+func (pt *pattern) matches(s subject) bool {
+	if pt.err != nil {
+		return false
+	}
+
+	if pt.re != nil {
+		target := s.path
+		if s.query != "" {
+			target += "?" + s.query
+		}
+		return pt.re.MatchString(target)
+	}
+
+	if pt.host != "" {
+		if s.host == "" || (pt.scheme != "" && !strings.EqualFold(pt.scheme, s.scheme)) {
+			return false
+		}
+		if ok, _ := path.Match(strings.ToLower(pt.host), strings.ToLower(s.host)); !ok {
+			return false
+		}
+	}
+
+	if len(pt.query) > 0 && !pt.matchesQuery(s.query) {
+		return false
+	}
+
+	return matchSegments(pt.segs, strings.Split(strings.Trim(s.path, "/"), "/"))
+}
+
+func (pt *pattern) matchesQuery(rawQuery string) bool {
+	values, _ := url.ParseQuery(rawQuery)
+	for _, param := range pt.query {
+		vs, ok := values[param.key]
+		if !ok {
+			return false
+		}
+		if !param.hasValue {
+			continue
+		}
+		matched := false
+		for _, v := range vs {
+			if ok, _ := path.Match(param.value, v); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSegment matches a path segment against a segment of a pattern other than `**`.
+func matchSegment(pattern, segment string) bool {
+	if pattern == "**" {
+		return false
+	}
+	if pattern == segment {
+		return true
+	}
+	ok, _ := path.Match(pattern, segment)
+	return ok
+}
+
+func matchSegments(pSegs, sSegs []string) bool {
 	pIdx, sIdx := 0, 0
 	// starIdx stores the position of the last `**` in pSegs
 	// sTmpIdx stores the position in sSegs that we're trying to match from
 	starIdx, sTmpIdx := -1, -1
 
 	for sIdx < len(sSegs) {
-		// Case 1: Segments match (or pattern has '*')
-		if pIdx < len(pSegs) && (pSegs[pIdx] == "*" || pSegs[pIdx] == sSegs[sIdx]) {
+		// Case 1: Segments match (or pattern has a glob matching the segment)
+		if pIdx < len(pSegs) && matchSegment(pSegs[pIdx], sSegs[sIdx]) {
 			pIdx++
 			sIdx++
 			continue
@@ -71,17 +295,51 @@ func (p Path) Matches(s string) bool {
 	return pIdx == len(pSegs)
 }
 
+// Validate checks that the pattern is a root-relative path, such as "/blog/**", a
+// host-qualified one, such as "//cdn.example.com/**", or a valid "re:" expression.
+func (p Path) Validate() error {
+	parsed := p.parse()
+	if parsed.err != nil {
+		return parsed.err
+	}
+	s := strings.TrimPrefix(string(p), "!")
+	if parsed.re == nil && parsed.host == "" && !strings.HasPrefix(s, "/") {
+		return fmt.Errorf("%q must start with /", string(p))
+	}
+	return nil
+}
+
 // Paths is a collection of Path patterns.
 type Paths []Path
 
-// MatchAny checks if the given path string `s` matches any of the patterns in the collection.
+// MatchAny checks if the given path string `s` matches the patterns of the collection.
+// Patterns are evaluated in order and the last one matching wins: a negated pattern,
+// such as "!/blog/drafts/**", excludes what it matches. If the first pattern is negated,
+// everything it doesn't match is included, so ["!/blog/**"] matches anything but the blog.
 func (ps Paths) MatchAny(s string) bool {
+	sub := parseSubject(s)
+	return ps.match(func(p *pattern) bool { return p.matches(sub) })
+}
+
+// MatchURL checks if a URL matches the patterns of the collection, like MatchAny.
+func (ps Paths) MatchURL(u *URL) bool {
+	sub := subjectOf(u)
+	return ps.match(func(p *pattern) bool { return p.matches(sub) })
+}
+
+func (ps Paths) match(matches func(*pattern) bool) bool {
+	matched := len(ps) > 0 && ps[0].IsNegated()
 	for _, p := range ps {
-		if p.Matches(s) {
-			return true
+		parsed := p.parse()
+		if parsed.negated == !matched {
+			// An include can't change an included path, nor an exclude an excluded one
+			continue
+		}
+		if matches(parsed) {
+			matched = !parsed.negated
 		}
 	}
-	return false
+	return matched
 }
 
 // String provides a string representation of Paths for logging.
@@ -227,3 +227,128 @@ func TestPath_Matches(t *testing.T) {
 		})
 	}
 }
+
+func TestPath_Matches_Extended(t *testing.T) {
+	testCases := []struct {
+		pattern Path
+		path    string
+		want    bool
+	}{
+		// In-segment globs and character classes
+		{"/assets/*.pdf", "/assets/report.pdf", true},
+		{"/assets/*.pdf", "/assets/report.pdf.zip", false},
+		{"/assets/*.pdf", "/assets/2024/report.pdf", false},
+		{"/assets/**/*.pdf", "/assets/2024/report.pdf", true},
+		{"/blog/20??/*", "/blog/2024/hello", true},
+		{"/blog/20??/*", "/blog/drafts/hello", false},
+		{"/v[0-9]/**", "/v2/users", true},
+		{"/v[0-9]/**", "/vx/users", false},
+		{"/v[!0-9]/**", "/vx/users", true},
+		{"/file?.html", "/file1.html", true},
+
+		// Regular expressions
+		{`re:/blog/\d{4}/.*`, "/blog/2024/hello", true},
+		{`re:/blog/\d{4}/.*`, "/blog/drafts/hello", false},
+		{`re:/blog/\d{4}`, "/blog/2024/hello", false}, // the whole path must match
+		{`re:/search\?q=.+`, "/search?q=bare", true},
+
+		// Query parameters
+		{"/search?q", "/search?q=bare", true},
+		{"/search?q", "/search", false},
+		{"/search?q=b*", "/search?q=bare&page=2", true},
+		{"/search?q=b*&page=2", "/search?q=bare&page=3", false},
+		{"/search", "/search?q=bare", true}, // the query is ignored without parameters
+		{"/**?preview", "/blog/post?preview=1", true},
+
+		// Hosts
+		{"//cdn.example.com/**", "https://cdn.example.com/img/a.png", true},
+		{"//cdn.example.com/**", "https://example.com/img/a.png", false},
+		{"//cdn.example.com/**", "/img/a.png", false},
+		{"https://*.example.com/**", "https://cdn.example.com/a", true},
+		{"https://*.example.com/**", "http://cdn.example.com/a", false},
+		{"/img/**", "https://cdn.example.com/img/a.png", true}, // paths match any host
+
+		// Negation is applied by Paths
+		{"!/blog/**", "/blog/post", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.pattern)+" "+tc.path, func(t *testing.T) {
+			got := tc.pattern.Matches(tc.path)
+			if got != tc.want {
+				t.Errorf("Path(%q).Matches(%q) = %v; want %v", tc.pattern, tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPaths_MatchAny(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns Paths
+		path     string
+		want     bool
+	}{
+		{"empty", Paths{}, "/blog", false},
+		{"any pattern", Paths{"/about", "/blog/**"}, "/blog/post", true},
+		{"excluded by a negation", Paths{"/blog/**", "!/blog/drafts/**"}, "/blog/drafts/wip", false},
+		{"not excluded by a negation", Paths{"/blog/**", "!/blog/drafts/**"}, "/blog/post", true},
+		{"included again", Paths{"/blog/**", "!/blog/drafts/**", "/blog/drafts/ready"}, "/blog/drafts/ready", true},
+		{"leading negation includes the rest", Paths{"!/blog/**"}, "/about", true},
+		{"leading negation", Paths{"!/blog/**"}, "/blog/post", false},
+		{"negation before include", Paths{"!/blog/**", "/blog/featured"}, "/blog/featured", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.patterns.MatchAny(tc.path)
+			if got != tc.want {
+				t.Errorf("%v.MatchAny(%q) = %v; want %v", tc.patterns, tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPaths_MatchURL(t *testing.T) {
+	u, err := Parse("https://example.com/docs/My%20Page?lang=fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !(Paths{"/docs/My Page?lang=fr"}).MatchURL(u) {
+		t.Errorf("expected the decoded path and its query to match")
+	}
+	if (Paths{"//cdn.example.com/**"}).MatchURL(u) {
+		t.Errorf("expected another host not to match")
+	}
+}
+
+func TestPath_Validate(t *testing.T) {
+	testCases := []struct {
+		pattern Path
+		err     string
+	}{
+		{"/blog/**", ""},
+		{"!/blog/drafts/**", ""},
+		{"/search?q=*", ""},
+		{"//cdn.example.com/**", ""},
+		{`re:/blog/\d+`, ""},
+		{"blog/**", `"blog/**" must start with /`},
+		{"/v[0-9/**", `"/v[0-9/**" has an invalid character class or escape`},
+		{"re:/blog/(", "invalid regular expression \"/blog/(\": error parsing regexp: missing closing ): `^(?:/blog/()$`"},
+		{"///blog", `"///blog" has no host`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.pattern), func(t *testing.T) {
+			err := tc.pattern.Validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.err {
+				t.Errorf("Path(%q).Validate() = %q; want %q", tc.pattern, got, tc.err)
+			}
+		})
+	}
+}
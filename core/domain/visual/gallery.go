@@ -0,0 +1,53 @@
+package visual
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// GalleryPage is a single compared page in the gallery.
+type GalleryPage struct {
+	Path     string
+	Ratio    float64
+	Failed   bool   // true if Ratio is above the threshold
+	Error    string // non-empty if the page could not be compared
+	Origin   string // image paths, relative to the gallery
+	Export   string
+	DiffPath string
+}
+
+// Gallery contains all the data for the visual regression report.
+type Gallery struct {
+	OriginURL   string
+	ExportURL   string
+	Threshold   float64
+	GeneratedAt time.Time
+	Pages       []GalleryPage
+	TotalFailed int
+}
+
+// WriteGallery renders the HTML gallery of the compared pages.
+func WriteGallery(w io.Writer, g *Gallery) error {
+	funcMap := template.FuncMap{
+		"percent": func(f float64) float64 { return f * 100 },
+	}
+
+	tmpl, err := template.New("").Funcs(funcMap).ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return err
+	}
+
+	g.TotalFailed = 0
+	for _, p := range g.Pages {
+		if p.Failed || p.Error != "" {
+			g.TotalFailed++
+		}
+	}
+
+	return tmpl.ExecuteTemplate(w, "gallery.html", g)
+}
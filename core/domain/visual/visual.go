@@ -0,0 +1,154 @@
+package visual
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+)
+
+// tolerance is the per-channel difference, on a 0-255 scale, below which two pixels are considered equal.
+// It absorbs anti-aliasing and font hinting noise between two renders of the same page.
+const tolerance = 16
+
+// Result is the outcome of comparing two screenshots.
+type Result struct {
+	DiffPixels  int
+	TotalPixels int
+	Diff        image.Image // faded copy of the first image with differing pixels in red
+}
+
+// Ratio returns the fraction of pixels that differ, between 0 and 1.
+func (r *Result) Ratio() float64 {
+	if r.TotalPixels == 0 {
+		return 0
+	}
+	return float64(r.DiffPixels) / float64(r.TotalPixels)
+}
+
+// Compare computes a pixel diff of two images.
+// Images of different sizes are compared over the union of their bounds,
+// pixels only present in one of them count as different.
+func Compare(a, b image.Image) *Result {
+	ab, bb := a.Bounds(), b.Bounds()
+	width := max(ab.Dx(), bb.Dx())
+	height := max(ab.Dy(), bb.Dy())
+
+	diff := image.NewRGBA(image.Rect(0, 0, width, height))
+	result := &Result{TotalPixels: width * height, Diff: diff}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pa, inA := pixelAt(a, x, y)
+			pb, inB := pixelAt(b, x, y)
+
+			if inA && inB && similar(pa, pb) {
+				diff.Set(x, y, fade(pa))
+				continue
+			}
+
+			result.DiffPixels++
+			diff.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	return result
+}
+
+// ComparePNG decodes two PNG screenshots and compares them.
+func ComparePNG(a, b []byte) (*Result, error) {
+	imgA, err := png.Decode(bytes.NewReader(a))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode first screenshot: %w", err)
+	}
+	imgB, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode second screenshot: %w", err)
+	}
+	return Compare(imgA, imgB), nil
+}
+
+// Thumbnail decodes a PNG screenshot and returns a JPEG scaled down to the given width.
+// Only the top of tall pages is kept so that the thumbnail stays at most 4:3.
+func Thumbnail(screenshot []byte, width int) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(screenshot))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode screenshot: %w", err)
+	}
+
+	sb := src.Bounds()
+	if sb.Dx() == 0 || sb.Dy() == 0 {
+		return nil, fmt.Errorf("screenshot is empty")
+	}
+	if width > sb.Dx() {
+		width = sb.Dx()
+	}
+
+	scale := float64(sb.Dx()) / float64(width)
+	height := min(int(float64(sb.Dy())/scale), width*3/4)
+
+	// Nearest-neighbour is good enough for a thumbnail and keeps us free of image dependencies.
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dst.Set(x, y, src.At(sb.Min.X+int(float64(x)*scale), sb.Min.Y+int(float64(y)*scale)))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 70}); err != nil {
+		return nil, fmt.Errorf("could not encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WritePNG encodes an image as PNG to the given file.
+func WritePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		return fmt.Errorf("could not encode %s: %w", path, err)
+	}
+	return nil
+}
+
+func pixelAt(img image.Image, x, y int) (color.RGBA, bool) {
+	b := img.Bounds()
+	px, py := b.Min.X+x, b.Min.Y+y
+	if px >= b.Max.X || py >= b.Max.Y {
+		return color.RGBA{}, false
+	}
+	r, g, bl, a := img.At(px, py).RGBA()
+	return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(bl >> 8), A: uint8(a >> 8)}, true
+}
+
+func similar(a, b color.RGBA) bool {
+	return absDiff(a.R, b.R) <= tolerance &&
+		absDiff(a.G, b.G) <= tolerance &&
+		absDiff(a.B, b.B) <= tolerance &&
+		absDiff(a.A, b.A) <= tolerance
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// fade blends a pixel towards white so that differences stand out in the diff image.
+func fade(c color.RGBA) color.RGBA {
+	return color.RGBA{
+		R: 255 - (255-c.R)/4,
+		G: 255 - (255-c.G)/4,
+		B: 255 - (255-c.B)/4,
+		A: 255,
+	}
+}
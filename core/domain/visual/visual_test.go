@@ -0,0 +1,99 @@
+package visual
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCompare_Identical(t *testing.T) {
+	a := solid(10, 10, color.White)
+	b := solid(10, 10, color.RGBA{R: 250, G: 250, B: 250, A: 255}) // within tolerance
+
+	result := Compare(a, b)
+	assert.Equal(t, 0, result.DiffPixels)
+	assert.Equal(t, 100, result.TotalPixels)
+	assert.Zero(t, result.Ratio())
+}
+
+func TestCompare_Differences(t *testing.T) {
+	a := solid(10, 10, color.White)
+	b := solid(10, 10, color.White)
+	for x := 0; x < 10; x++ {
+		b.Set(x, 0, color.Black)
+	}
+
+	result := Compare(a, b)
+	assert.Equal(t, 10, result.DiffPixels)
+	assert.InDelta(t, 0.1, result.Ratio(), 0.0001)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, result.Diff.At(0, 0))
+	assert.NotEqual(t, color.RGBA{R: 255, A: 255}, result.Diff.At(0, 1))
+}
+
+func TestCompare_DifferentSizes(t *testing.T) {
+	a := solid(10, 10, color.White)
+	b := solid(10, 20, color.White)
+
+	result := Compare(a, b)
+	assert.Equal(t, 200, result.TotalPixels)
+	assert.Equal(t, 100, result.DiffPixels, "pixels only present in the taller image differ")
+}
+
+func TestComparePNG_InvalidInput(t *testing.T) {
+	_, err := ComparePNG([]byte("not a png"), []byte("not a png"))
+	assert.Error(t, err)
+}
+
+func TestThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, solid(1280, 4000, color.White)))
+
+	thumb, err := Thumbnail(buf.Bytes(), 320)
+	require.NoError(t, err)
+
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, 320, img.Bounds().Dx())
+	assert.Equal(t, 240, img.Bounds().Dy(), "tall pages are cropped to 4:3")
+}
+
+func TestWriteGallery(t *testing.T) {
+	g := &Gallery{
+		OriginURL:   "http://127.0.0.1:8000",
+		ExportURL:   "http://127.0.0.1:54321",
+		Threshold:   0.01,
+		GeneratedAt: time.Now(),
+		Pages: []GalleryPage{
+			{Path: "/", Ratio: 0.001, Origin: "origin/index.png", Export: "export/index.png", DiffPath: "diff/index.png"},
+			{Path: "/about", Ratio: 0.2, Failed: true, Origin: "origin/about.png", Export: "export/about.png", DiffPath: "diff/about.png"},
+			{Path: "/broken", Error: "chrome fetch failed"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteGallery(&buf, g))
+	html := buf.String()
+
+	assert.Equal(t, 2, g.TotalFailed)
+	assert.Contains(t, html, "diff/about.png")
+	assert.Contains(t, html, "20.00%")
+	assert.Contains(t, html, "chrome fetch failed")
+	assert.Contains(t, html, "Above 1.00% difference")
+}
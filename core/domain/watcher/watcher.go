@@ -0,0 +1,93 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stamp identifies a version of a file.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Poll calls fn whenever a file under dirs is created, modified or removed, checking
+// every interval. Without dirs, fn is called on every tick, e.g. to poll the origin.
+// Hidden directories, such as .git, and node_modules are skipped.
+// It returns when ctx is cancelled.
+func Poll(ctx context.Context, dirs []string, interval time.Duration, fn func(ctx context.Context)) error {
+	last, err := snapshot(dirs)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if len(dirs) == 0 {
+			fn(ctx)
+			continue
+		}
+
+		current, err := snapshot(dirs)
+		if err != nil {
+			continue // a directory being replaced, try again on the next tick
+		}
+		if changed(last, current) {
+			last = current
+			fn(ctx)
+		}
+	}
+}
+
+// snapshot records the version of every file under dirs.
+func snapshot(dirs []string) (map[string]stamp, error) {
+	files := make(map[string]stamp)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return nil // removed while walking
+			}
+			files[p] = stamp{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// changed reports whether two snapshots differ.
+func changed(a, b map[string]stamp) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for p, s := range a {
+		if other, ok := b[p]; !ok || !other.modTime.Equal(s.modTime) || other.size != s.size {
+			return true
+		}
+	}
+	return false
+}
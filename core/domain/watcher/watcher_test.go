@@ -0,0 +1,62 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoll(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "page.tpl"), []byte("v1"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int32
+	done := make(chan error)
+	go func() {
+		done <- Poll(ctx, []string{dir}, 10*time.Millisecond, func(context.Context) { calls.Add(1) })
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, calls.Load(), "nothing changed yet")
+
+	// Hidden directories are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "index"), []byte("x"), 0644))
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, calls.Load())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "page.tpl"), []byte("version 2"), 0644))
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "page.tpl")))
+	assert.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestPoll_WithoutDirs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int32
+	go func() {
+		_ = Poll(ctx, nil, 10*time.Millisecond, func(context.Context) { calls.Add(1) })
+	}()
+
+	assert.Eventually(t, func() bool { return calls.Load() >= 3 }, time.Second, 10*time.Millisecond)
+}
+
+func TestPoll_MissingDir(t *testing.T) {
+	err := Poll(context.Background(), []string{filepath.Join(t.TempDir(), "missing")}, time.Second, func(context.Context) {})
+	assert.Error(t, err)
+}
@@ -7,13 +7,17 @@ import (
 
 func New(opts ...cli.Opt) *cli.CLI {
 	app := cli.New(
-		opts...,
+		append(opts, cli.WithFlags(bare.ConfigFlags))...,
 	)
 
 	app.Add(
 		bare.NewInitCommand(app),
 		bare.NewExportCommand(app),
 		bare.NewServeCommand(app),
+		bare.NewVerifyCommand(app),
+		bare.NewCheckCommand(app),
+		bare.NewDiffCommand(app),
+		bare.NewConfigCommand(app),
 	)
 
 	return app
@@ -0,0 +1,141 @@
+package bare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/checker"
+	"github.com/felixdorn/bare/core/domain/exporter"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"github.com/spf13/cobra"
+)
+
+func runCheck(c *cli.CLI, cmd *cobra.Command, args []string) error {
+	conf, _, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("url") {
+		uStr, _ := cmd.Flags().GetString("url")
+		if !strings.HasPrefix(uStr, "http://") && !strings.HasPrefix(uStr, "https://") {
+			uStr = "http://" + uStr
+		}
+		u, err := url.Parse(uStr)
+		if err != nil {
+			return fmt.Errorf("invalid URL: %w", err)
+		}
+		conf.URL = u
+	}
+
+	dir := conf.Output
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("directory '%s' does not exist. Run 'bare export' first", dir)
+		}
+		return err
+	}
+
+	// Files the stages write for JavaScript or servers to load are not orphans
+	var ignore url.Paths
+	if conf.Search.Enabled {
+		ignore = append(ignore, url.Path(path.Join("/", conf.Search.Dir, "**")))
+	}
+	if conf.Fingerprint.Enabled {
+		ignore = append(ignore, url.Path(path.Join("/", conf.Fingerprint.Manifest)))
+	}
+	ignored, _ := cmd.Flags().GetStringSlice("ignore")
+	for _, p := range ignored {
+		ignore = append(ignore, url.Path(p))
+	}
+
+	// Pages the export crawled from are not orphans either, even when nothing links to them
+	entrypoints := make([]string, len(conf.Pages.Entrypoints))
+	for i, ep := range conf.Pages.Entrypoints {
+		entrypoints[i] = string(ep)
+	}
+	if len(conf.Routes) > 0 {
+		routes, err := exporter.ExpandRoutes(context.Background(), conf.URL, conf.Routes, &http.Client{Timeout: 30 * time.Second})
+		if err != nil {
+			// Routes taking their values from the origin can't be expanded while it is down
+			log := c.Log()
+			log.Warn().Err(err).Msg("Could not expand routes, the pages only they reach may be reported as orphans")
+		}
+		entrypoints = append(entrypoints, routes...)
+	}
+
+	report, err := checker.New(dir, checker.Options{
+		OriginURL:   conf.URL,
+		PublicURL:   conf.PublicURL,
+		Ignore:      ignore,
+		Entrypoints: entrypoints,
+	}).Run()
+	if err != nil {
+		return fmt.Errorf("could not check %s: %w", dir, err)
+	}
+
+	printProblems("Missing targets", report.Missing)
+	printProblems("Leftover origin URLs", report.Leftovers)
+	if len(report.Orphans) > 0 {
+		fmt.Printf("Orphan files (%d):\n", len(report.Orphans))
+		for _, f := range report.Orphans {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+
+	fmt.Printf("Checked %d references in %d files.\n", report.References, report.Files)
+
+	if n := report.Problems(); n > 0 {
+		return &cli.StatusError{
+			Status:     fmt.Sprintf("%d problems found in %s", n, dir),
+			StatusCode: 1,
+		}
+	}
+
+	return nil
+}
+
+// printProblems prints a titled list of problems, if any.
+func printProblems(title string, problems []checker.Problem) {
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, len(problems))
+	for _, p := range problems {
+		fmt.Printf("  %s:%d  %s\n", p.File, p.Line, p.URL)
+	}
+}
+
+func NewCheckCommand(c *cli.CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check [directory]",
+		Short: "Check that the references of the export resolve",
+		Long: `Checks the exported site offline, without starting a server.
+
+Every reference in the HTML and CSS files is resolved against the export with the
+same rules as the rewriter. References to missing files, absolute URLs left pointing
+to the origin or to a local server, and files nothing references are reported. The
+entrypoints, the expanded routes and the pages listed in the sitemaps are referenced.
+
+Exits with a non-zero status when problems are found, so it can gate a deploy.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCheck(c, cmd, args)
+		},
+	}
+
+	cmd.Flags().String("url", "", "Base URL of the origin (overrides bare.toml)")
+	cmd.Flags().StringSlice("ignore", nil, "Paths never reported as orphans, e.g. files only loaded from JavaScript (can be repeated)")
+
+	return cmd
+}
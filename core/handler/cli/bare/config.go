@@ -0,0 +1,201 @@
+package bare
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ConfigFlags adds the flags selecting the config file and its profile, shared by every command.
+func ConfigFlags(flags *pflag.FlagSet) {
+	flags.String("config", config.DefaultFile, "Config file to use")
+	flags.String("profile", "", "Profile of the config file to apply, [profile.<name>] (defaults to $"+config.ProfileEnv+")")
+}
+
+// loadConfig reads the config file selected with --config, with the profile selected
+// with --profile or BARE_PROFILE. Without --config, a missing bare.toml gives the
+// default config.
+func loadConfig(cmd *cobra.Command) (*config.Config, config.Sources, error) {
+	file := configFile(cmd)
+	profile, _ := cmd.Flags().GetString("profile")
+	if !cmd.Flags().Changed("profile") {
+		profile = os.Getenv(config.ProfileEnv)
+	}
+
+	conf, sources, err := config.Load(file, profile)
+	if errors.Is(err, os.ErrNotExist) && !cmd.Flags().Changed("config") {
+		if profile != "" {
+			return nil, nil, fmt.Errorf("profile %q selected, but %s does not exist", profile, file)
+		}
+		return config.NewDefaultConfig(), nil, nil
+	}
+	return conf, sources, err
+}
+
+// configFile returns the config file selected with --config.
+func configFile(cmd *cobra.Command) string {
+	file, _ := cmd.Flags().GetString("config")
+	if file == "" {
+		return config.DefaultFile
+	}
+	return file
+}
+
+func runConfigShow(c *cli.CLI, cmd *cobra.Command) error {
+	conf, sources, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	content, err := conf.Export()
+	if err != nil {
+		return err
+	}
+
+	shown := string(content)
+	if reveal, _ := cmd.Flags().GetBool("reveal"); !reveal {
+		shown = mask(shown, func(key string) bool {
+			return secretKeys[key] || strings.Contains(sources.Of(key), "${")
+		})
+	}
+
+	_, err = fmt.Fprint(c.Out(), annotate(shown, sources.Of))
+	return err
+}
+
+// secretKeys are the keys masked by config show even when they are set in the file.
+var secretKeys = map[string]bool{
+	"deploy.s3.access_key_id":     true,
+	"deploy.s3.secret_access_key": true,
+}
+
+// maskedValue replaces the values hidden by config show.
+const maskedValue = "'********'"
+
+func runConfigValidate(c *cli.CLI, cmd *cobra.Command) error {
+	_, _, err := loadConfig(cmd)
+	var errs config.Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			_, _ = fmt.Fprintln(c.Out(), e.Error())
+		}
+		return &cli.StatusError{
+			Status:     fmt.Sprintf("%d problems found in %s", len(errs), configFile(cmd)),
+			StatusCode: 1,
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.Out(), "%s is valid\n", configFile(cmd))
+	return err
+}
+
+func runConfigSchema(c *cli.CLI) error {
+	content, err := config.Schema()
+	if err != nil {
+		return err
+	}
+	_, err = c.Out().Write(content)
+	return err
+}
+
+// annotate adds the note on each key of a TOML document, such as "js.enabled", in a
+// comment after its value. Keys without a note are left as is.
+func annotate(content string, note func(key string) string) string {
+	return rewriteKeys(content, func(key, line string) string {
+		if n := note(key); n != "" {
+			line += "  # " + n
+		}
+		return line
+	})
+}
+
+// mask replaces the non-empty values of the keys of a TOML document selected by masked.
+func mask(content string, masked func(key string) bool) string {
+	return rewriteKeys(content, func(key, line string) string {
+		name, value, _ := strings.Cut(line, "=")
+		if value = strings.TrimSpace(value); !masked(key) || value == "''" || value == `""` {
+			return line
+		}
+		return name + "= " + maskedValue
+	})
+}
+
+// rewriteKeys replaces each key-value line of a TOML document with the result of
+// rewrite, called with its dotted key. Other lines are left as is.
+func rewriteKeys(content string, rewrite func(key, line string) string) string {
+	var b strings.Builder
+	table := ""
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			table = strings.Trim(trimmed, "[] ")
+		case trimmed != "" && !strings.HasPrefix(trimmed, "#"):
+			if key, _, ok := strings.Cut(trimmed, "="); ok {
+				key = strings.Trim(strings.TrimSpace(key), `"'`)
+				if table != "" {
+					key = table + "." + key
+				}
+				line = rewrite(key, line)
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func NewConfigCommand(c *cli.CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the merged configuration and where each value comes from",
+		Long: `Prints the configuration commands use: the config file, with the selected profile
+applied and environment variables replaced. Each value is followed by its source:
+the file, the profile, the environment variables used, or "default" if it isn't set.
+Values read from environment variables, and S3 credentials, are masked unless --reveal is set.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigShow(c, cmd)
+		},
+	}
+	show.Flags().Bool("reveal", false, "Print secrets and values read from environment variables")
+	cmd.AddCommand(show)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration, for CI",
+		Long: `Checks the config file and the selected profile: unknown keys, values of the wrong
+type, URLs, path patterns, selectors, regular expressions and durations. Every problem
+is printed with its line and column, and the command exits with status 1 if any.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigValidate(c, cmd)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the config file",
+		Long: `Prints a JSON Schema of the config file, for completion and checks in editors.
+Reference it from bare.toml with a "#:schema ./` + config.SchemaFile + `" comment on the first line.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSchema(c)
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,125 @@
+package bare
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/domain/differ"
+	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"github.com/spf13/cobra"
+)
+
+func runDiff(c *cli.CLI, cmd *cobra.Command, args []string) error {
+	conf, _, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	selectors, _ := cmd.Flags().GetStringSlice("ignore-selector")
+	patterns, _ := cmd.Flags().GetStringArray("ignore-pattern")
+	opts, err := diffOptions(conf, selectors, patterns)
+	if err != nil {
+		return err
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	write := map[string]func(io.Writer, *differ.Result) error{
+		"text": differ.WriteText,
+		"json": differ.WriteJSON,
+		"html": differ.WriteHTML,
+	}[format]
+	if write == nil {
+		return fmt.Errorf("invalid format '%s': use text, json or html", format)
+	}
+
+	for _, dir := range args {
+		if _, err := os.Stat(dir); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("directory '%s' does not exist", dir)
+			}
+			return err
+		}
+	}
+
+	result, err := differ.Compare(args[0], args[1], opts)
+	if err != nil {
+		return fmt.Errorf("could not compare exports: %w", err)
+	}
+
+	out := c.Out()
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("could not create %s: %w", output, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := write(out, result); err != nil {
+		return fmt.Errorf("could not write diff: %w", err)
+	}
+
+	if exitCode, _ := cmd.Flags().GetBool("exit-code"); exitCode && len(result.Changes) > 0 {
+		return &cli.StatusError{
+			Status:     fmt.Sprintf("%d files changed", len(result.Changes)),
+			StatusCode: 1,
+		}
+	}
+
+	return nil
+}
+
+// diffOptions returns the [diff] settings of conf, along with extra selectors and patterns
+// to ignore. Lists left out of bare.toml keep their defaults, an empty list ignores nothing.
+func diffOptions(conf *config.Config, selectors, patterns []string) (differ.Options, error) {
+	ignoreSelectors := conf.Diff.IgnoreSelectors
+	if ignoreSelectors == nil {
+		ignoreSelectors = differ.DefaultIgnoreSelectors
+	}
+	ignoreAttributes := conf.Diff.IgnoreAttributes
+	if ignoreAttributes == nil {
+		ignoreAttributes = differ.DefaultIgnoreAttributes
+	}
+	ignorePatterns := conf.Diff.IgnorePatterns
+	if ignorePatterns == nil {
+		ignorePatterns = differ.DefaultIgnorePatterns
+	}
+
+	compiled, err := differ.CompilePatterns(append(append([]string{}, ignorePatterns...), patterns...))
+	if err != nil {
+		return differ.Options{}, err
+	}
+	return differ.Options{
+		IgnoreSelectors:  append(append([]string{}, ignoreSelectors...), selectors...),
+		IgnoreAttributes: ignoreAttributes,
+		IgnorePatterns:   compiled,
+	}, nil
+}
+
+func NewDiffCommand(c *cli.CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <old-dir> <new-dir>",
+		Short: "Show what changed between two exports",
+		Long: `Compares two export directories and classifies their files as added, removed or modified.
+
+HTML pages are normalized before being compared, so formatting changes don't show up.
+Elements holding per-request tokens, nonce attributes and timestamps are ignored by
+default; configure what is ignored in the [diff] section of bare.toml.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(c, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "text", "Output format: text, json or html")
+	cmd.Flags().StringP("output", "o", "", "File to write the diff to (default stdout)")
+	cmd.Flags().StringSlice("ignore-selector", nil, "Also ignore elements matching this selector (can be repeated)")
+	cmd.Flags().StringArray("ignore-pattern", nil, "Also ignore text matching this regular expression (can be repeated)")
+	cmd.Flags().Bool("exit-code", false, "Exit with status 1 when the exports differ")
+
+	return cmd
+}
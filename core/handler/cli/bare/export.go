@@ -2,29 +2,32 @@ package bare
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/felixdorn/bare/core/domain/config"
 	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/deploy"
 	"github.com/felixdorn/bare/core/domain/exporter"
+	"github.com/felixdorn/bare/core/domain/lifecycle"
 	"github.com/felixdorn/bare/core/domain/rewriter"
+	"github.com/felixdorn/bare/core/domain/search"
+	"github.com/felixdorn/bare/core/domain/sitemap"
 	"github.com/felixdorn/bare/core/domain/url"
 	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"github.com/felixdorn/bare/core/handler/cli/fetch"
 	"github.com/spf13/cobra"
 )
 
 func runExport(c *cli.CLI, cmd *cobra.Command, args []string) error {
-	conf, err := config.Get()
+	conf, _, err := loadConfig(cmd)
 	if err != nil {
-		if !errors.Is(err, os.ErrNotExist) {
-			return err
-		}
-		conf = config.NewDefaultConfig()
+		return err
 	}
 
 	// Optional URL flag override
@@ -104,7 +107,7 @@ func runExport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 
 	if cmd.Flags().Changed("js-wait") {
 		wait, _ := cmd.Flags().GetDuration("js-wait")
-		conf.JS.Wait = wait
+		conf.JS.Wait = config.Duration(wait)
 	}
 
 	if cmd.Flags().Changed("js-executable") {
@@ -122,32 +125,123 @@ func runExport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 		conf.JS.MaxTabs = maxTabs
 	}
 
+	if cmd.Flags().Changed("js-device") {
+		device, _ := cmd.Flags().GetString("js-device")
+		conf.JS.Device = device
+	}
+
+	if cmd.Flags().Changed("public-url") {
+		publicURL, _ := cmd.Flags().GetString("public-url")
+		u, err := url.Parse(publicURL)
+		if err != nil {
+			return fmt.Errorf("invalid public URL: %w", err)
+		}
+		conf.PublicURL = u
+	}
+
+	if cmd.Flags().Changed("sitemap") {
+		sitemap, _ := cmd.Flags().GetBool("sitemap")
+		conf.Sitemap.Enabled = sitemap
+	}
+
+	if cmd.Flags().Changed("search") {
+		search, _ := cmd.Flags().GetBool("search")
+		conf.Search.Enabled = search
+	}
+
+	if cmd.Flags().Changed("minify") {
+		minify, _ := cmd.Flags().GetBool("minify")
+		conf.Minify.Enabled = minify
+	}
+
+	if cmd.Flags().Changed("fingerprint") {
+		fingerprint, _ := cmd.Flags().GetBool("fingerprint")
+		conf.Fingerprint.Enabled = fingerprint
+	}
+
+	if cmd.Flags().Changed("compress") {
+		compress, _ := cmd.Flags().GetBool("compress")
+		conf.Compress.Enabled = compress
+	}
+
+	// Archives and buckets are written from a temporary export directory
+	target, err := deploy.ParseTarget(conf.Output)
+	if err != nil {
+		return err
+	}
+	if target.Kind != deploy.Directory {
+		tmp, err := os.MkdirTemp("", "bare-export-")
+		if err != nil {
+			return fmt.Errorf("could not create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		conf.Output = tmp
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	// Create the appropriate fetcher based on JS config
-	var fetcher crawler.Fetcher
-	if conf.JS.Enabled {
-		jsFetcher, err := crawler.NewJSFetcher(crawler.JSFetcherOptions{
-			Wait:           int(conf.JS.Wait.Milliseconds()),
-			MaxTabs:        conf.JS.MaxTabs,
-			ExecutablePath: conf.JS.ExecutablePath,
-			Flags:          conf.JS.Flags,
-			Logger:         c.Log(),
+	// Create the appropriate fetcher based on JS config and rules
+	fetcher, closeFetcher, err := newFetcher(c, conf)
+	if err != nil {
+		return err
+	}
+	if closeFetcher != nil {
+		defer closeFetcher()
+	}
+
+	hookEnv := lifecycle.HookEnv{URL: conf.URL.String(), OutputDir: conf.Output}
+	if err := lifecycle.RunHook(ctx, lifecycle.PreExport, conf.Hooks.PreExport, hookEnv, c.Out(), c.Err()); err != nil {
+		return err
+	}
+
+	// Start the origin server, if bare manages it, for the duration of the crawl
+	stopServer := func() {}
+	if conf.Server.Command != "" {
+		readyURL := conf.Server.ReadyURL
+		if readyURL == "" {
+			readyURL = conf.URL.String()
+		}
+
+		var output io.Writer = io.Discard
+		if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+			output = c.Err()
+		}
+
+		fmt.Printf("Starting %s...\n", conf.Server.Command)
+		server, err := lifecycle.StartServer(ctx, lifecycle.ServerOptions{
+			Command:  conf.Server.Command,
+			Dir:      conf.Server.Dir,
+			ReadyURL: readyURL,
+			Timeout:  time.Duration(conf.Server.Timeout),
+			Output:   output,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to create JS fetcher: %w", err)
+			return err
 		}
-		defer jsFetcher.Close()
-		fetcher = jsFetcher
-	} else {
-		fetcher = crawler.NewHTTPFetcher(nil)
+		defer server.Stop()
+		stopServer = server.Stop
 	}
 
 	export := exporter.NewExport(conf, c.Log(), fetcher)
 	if err := export.Run(ctx); err != nil {
 		return err
 	}
+	stopServer()
+
+	// A cancelled export is partial: the hooks and later stages are skipped, and delivering
+	// it would drop the missing pages from the target
+	if ctx.Err() != nil {
+		if target.Kind != deploy.Directory {
+			return fmt.Errorf("export cancelled, %s was left untouched", target)
+		}
+		return nil
+	}
+
+	hookEnv.PageCount = export.PageCount()
+	if err := lifecycle.RunHook(ctx, lifecycle.PostExport, conf.Hooks.PostExport, hookEnv, c.Out(), c.Err()); err != nil {
+		return err
+	}
 
 	fmt.Println("Rewriting URLs...")
 	rw := rewriter.New(conf.Output, conf.URL)
@@ -155,9 +249,148 @@ func runExport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("error rewriting URLs: %w", err)
 	}
 
+	if err := lifecycle.RunHook(ctx, lifecycle.PostRewrite, conf.Hooks.PostRewrite, hookEnv, c.Out(), c.Err()); err != nil {
+		return err
+	}
+
+	if conf.Sitemap.Enabled {
+		fmt.Println("Generating sitemap...")
+		stats, err := sitemap.New(conf.Output, sitemap.Options{
+			PublicURL: conf.PublicURL,
+			Robots:    conf.Sitemap.Robots,
+			State:     conf.Sitemap.State,
+		}).Run(export.SitemapEntries())
+		if err != nil {
+			return fmt.Errorf("error generating sitemap: %w", err)
+		}
+		fmt.Printf("Listed %d pages in %d sitemap files\n", stats.URLs, stats.Files)
+	}
+
+	if conf.Search.Enabled {
+		fmt.Println("Building search index...")
+		stats, err := search.New(conf.Output, search.Options{
+			Dir:       conf.Search.Dir,
+			Main:      conf.Search.Content,
+			Exclude:   conf.Search.Exclude,
+			Languages: conf.Search.Languages,
+			Pages:     conf.Search.ExcludePages,
+		}).Run()
+		if err != nil {
+			return fmt.Errorf("error building search index: %w", err)
+		}
+		fmt.Printf("Indexed %d pages: %d terms in %d shards\n", stats.Documents, stats.Terms, stats.Shards)
+	}
+
+	if conf.Minify.Enabled {
+		fmt.Println("Minifying files...")
+		stats, err := newMinifier(conf).Run()
+		if err != nil {
+			return fmt.Errorf("error minifying files: %w", err)
+		}
+		fmt.Printf("Minified %d files: %d bytes -> %d bytes (-%.1f%%)\n", stats.Files, stats.BytesBefore, stats.BytesAfter, stats.Saved()*100)
+	}
+
+	if conf.Fingerprint.Enabled {
+		fmt.Println("Fingerprinting assets...")
+		manifest, err := newFingerprinter(conf).Run()
+		if err != nil {
+			return fmt.Errorf("error fingerprinting assets: %w", err)
+		}
+		fmt.Printf("Fingerprinted %d assets\n", len(manifest))
+	}
+
+	if conf.Compress.Enabled {
+		fmt.Println("Compressing files...")
+		stats, err := newCompressor(conf).Run()
+		if err != nil {
+			return fmt.Errorf("error compressing files: %w", err)
+		}
+		fmt.Printf("Wrote %d compressed files for %d files (%d bytes)\n", stats.Sidecars, stats.Files, stats.OriginalBytes)
+	}
+
+	switch target.Kind {
+	case deploy.TarGz, deploy.Zip:
+		fmt.Printf("Writing %s...\n", target)
+		if err := deploy.Archive(conf.Output, target); err != nil {
+			return err
+		}
+	case deploy.S3:
+		fmt.Printf("Syncing to %s...\n", target)
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		rules := make([]deploy.CacheRule, len(conf.Deploy.CacheControl))
+		for i, rule := range conf.Deploy.CacheControl {
+			rules[i] = deploy.CacheRule{Paths: rule.Paths, Value: rule.Value}
+		}
+
+		manifest := ""
+		if conf.Fingerprint.Enabled {
+			manifest = conf.Fingerprint.Manifest
+		}
+
+		stats, err := deploy.Sync(ctx, conf.Output, target, deploy.NewS3Client(s3Config(conf), target.Bucket), deploy.SyncOptions{
+			CacheControl: rules,
+			Delete:       conf.Deploy.Delete,
+			Manifest:     manifest,
+			DryRun:       dryRun,
+		})
+		if err != nil {
+			return fmt.Errorf("error syncing to %s: %w", target, err)
+		}
+		if dryRun {
+			fmt.Print("Dry run: would have ")
+		}
+		fmt.Printf("Uploaded %d files (%d bytes), %d unchanged, %d deleted\n", stats.Uploaded, stats.Bytes, stats.Unchanged, stats.Deleted)
+	}
+
 	return nil
 }
 
+// s3Config builds the S3 connection settings from the config, falling back
+// to the standard AWS environment variables for credentials and region.
+func s3Config(conf *config.Config) deploy.S3Config {
+	s3 := deploy.S3Config{
+		Endpoint:        conf.Deploy.S3.Endpoint,
+		Region:          conf.Deploy.S3.Region,
+		AccessKeyID:     conf.Deploy.S3.AccessKeyID,
+		SecretAccessKey: conf.Deploy.S3.SecretAccessKey,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		PathStyle:       conf.Deploy.S3.PathStyle,
+	}
+	if s3.AccessKeyID == "" {
+		s3.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if s3.SecretAccessKey == "" {
+		s3.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if s3.Region == "" {
+		s3.Region = os.Getenv("AWS_REGION")
+	}
+	return s3
+}
+
+// jsFetcherOptions builds the JS fetcher options from the config.
+func jsFetcherOptions(c *cli.CLI, conf *config.Config) (crawler.JSFetcherOptions, error) {
+	var device *crawler.Device
+	if conf.JS.Device != "" {
+		d, err := crawler.DeviceByName(conf.JS.Device)
+		if err != nil {
+			return crawler.JSFetcherOptions{}, err
+		}
+		device = d
+	}
+
+	return crawler.JSFetcherOptions{
+		Wait:           int(time.Duration(conf.JS.Wait).Milliseconds()),
+		MaxTabs:        conf.JS.MaxTabs,
+		ExecutablePath: conf.JS.ExecutablePath,
+		Flags:          conf.JS.Flags,
+		Interactions:   fetch.Interactions(conf.JS.Interactions),
+		Device:         device,
+		Logger:         c.Log(),
+	}, nil
+}
+
 func NewExportCommand(c *cli.CLI) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "export [url]",
@@ -170,7 +403,8 @@ The starting URL can be provided as an argument. If not provided, the URL from b
 		},
 	}
 
-	cmd.Flags().StringP("output", "o", "", "Output directory for the exported site")
+	cmd.Flags().StringP("output", "o", "", "Output directory, .tar.gz or .zip archive, or s3://bucket/prefix for the exported site")
+	cmd.Flags().Bool("dry-run", false, "Show what a sync to S3 would upload and delete without changing the bucket")
 	cmd.Flags().String("url", "", "Base URL of the site to export (overrides bare.toml)")
 	cmd.Flags().IntP("workers", "w", 0, "Number of concurrent workers")
 	cmd.Flags().StringSlice("entrypoint", []string{}, "Entrypoint paths to seed the crawl (can be used multiple times)")
@@ -183,6 +417,13 @@ The starting URL can be provided as an argument. If not provided, the URL from b
 	cmd.Flags().Int("js-max-tabs", 1, "Maximum parallel Chrome tabs for JS fetching")
 	cmd.Flags().String("js-executable", "", "Path to Chrome/Chromium executable")
 	cmd.Flags().StringSlice("js-flag", []string{}, "Additional Chrome flags (can be used multiple times)")
+	cmd.Flags().String("public-url", "", "URL the export is deployed to, used in the sitemap (overrides bare.toml)")
+	cmd.Flags().Bool("sitemap", false, "Generate sitemap.xml from the exported pages")
+	cmd.Flags().Bool("search", false, "Build a static search index of the exported pages")
+	cmd.Flags().Bool("minify", false, "Minify exported HTML and CSS files")
+	cmd.Flags().Bool("fingerprint", false, "Rename assets to content-hashed names and rewrite references to them")
+	cmd.Flags().Bool("compress", false, "Write precompressed .gz and .br files next to compressible files")
+	cmd.Flags().String("js-device", "", "Device profile to render with: desktop or mobile")
 
 	return cmd
 }
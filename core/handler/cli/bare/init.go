@@ -1,45 +1,263 @@
 package bare
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
-	"github.com/felixdorn/bare/core/domain/config"
-	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"io"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/probe"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
-func runInit(cli *cli.CLI) error {
-	if _, err := os.Stat("bare.toml"); !errors.Is(err, os.ErrNotExist) {
+// probeTimeout bounds how long bare init inspects the site, rendering included.
+const probeTimeout = time.Minute
+
+func runInit(c *cli.CLI, cmd *cobra.Command, args []string) error {
+	file := configFile(cmd)
+	if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
 		if err == nil {
-			return fmt.Errorf("bare.toml already exists")
+			return fmt.Errorf("%s already exists", file)
 		}
 
-		return fmt.Errorf("bare.toml already exists: %w", err)
+		return fmt.Errorf("%s already exists: %w", file, err)
 	}
 
-	defaultConfigBytes, err := config.NewDefaultConfig().Export()
+	yes, _ := cmd.Flags().GetBool("yes")
+	p := &prompter{in: bufio.NewReader(c.In()), out: c.Out(), yes: yes || !isatty.IsTerminal(c.In().Fd())}
+
+	conf := config.NewDefaultConfig()
+	rawURL := ""
+	if len(args) > 0 {
+		rawURL = args[0]
+	} else if !p.yes {
+		rawURL = p.ask("URL of the site to export", conf.URL.String())
+	}
+
+	content, err := conf.Export()
 	if err != nil {
 		return err
 	}
+	if rawURL != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid URL %q, expected e.g. http://127.0.0.1:8000", rawURL)
+		}
 
-	err = os.WriteFile("bare.toml", defaultConfigBytes, 0644)
-	if err != nil {
-		return fmt.Errorf("could not write bare.toml: %w", err)
+		report, err := probeSite(c, u)
+		if err != nil {
+			return fmt.Errorf("could not probe %s: %w", u, err)
+		}
+		printReport(c.Out(), u, report)
+
+		notes := proposeConfig(p, conf, u, report)
+		if content, err = conf.Export(); err != nil {
+			return err
+		}
+		content = []byte(initHeader(u, report) + annotate(string(content), func(key string) string { return notes[key] }))
 	}
 
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", file, err)
+	}
+	if rawURL != "" {
+		_, _ = fmt.Fprintf(c.Out(), "Wrote %s, check it with bare config validate\n", file)
+	}
 	return nil
 }
 
-func NewInitCommand(cli *cli.CLI) *cobra.Command {
+// probeSite inspects the site, rendering its home page with Chrome if it is available.
+func probeSite(c *cli.CLI, u *url.URL) (*probe.Report, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	jsFetcher, err := crawler.NewJSFetcher(crawler.JSFetcherOptions{Logger: c.Log()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JS fetcher: %w", err)
+	}
+	defer jsFetcher.Close()
+
+	log := c.Log()
+	return probe.New(u, probe.Options{
+		Render: func(ctx context.Context, u *url.URL) ([]byte, error) {
+			// Without Chrome, the probe falls back to looking at the HTML alone
+			result, err := jsFetcher.Fetch(ctx, u)
+			if err != nil {
+				log.Debug().Err(err).Msg("Could not render the home page with JavaScript")
+				return nil, err
+			}
+			return result.Body, nil
+		},
+	}).Run(ctx)
+}
+
+func printReport(w io.Writer, u *url.URL, report *probe.Report) {
+	_, _ = fmt.Fprintf(w, "Probed %s\n", u)
+	if report.Framework != nil {
+		_, _ = fmt.Fprintf(w, "  Framework:   %s, from its %s\n", report.Framework.Name, report.Framework.Evidence)
+	}
+	if report.Robots {
+		disallowed := "nothing"
+		if len(report.Disallowed) > 0 {
+			disallowed = strings.Join(report.Disallowed, ", ")
+		}
+		_, _ = fmt.Fprintf(w, "  robots.txt:  disallows %s\n", disallowed)
+	}
+	if len(report.Sitemaps) > 0 {
+		_, _ = fmt.Fprintf(w, "  Sitemaps:    %s\n", strings.Join(report.Sitemaps, ", "))
+	}
+	verdict := "not needed"
+	if report.JS.Recommended {
+		verdict = "needed"
+	}
+	_, _ = fmt.Fprintf(w, "  JavaScript:  %s, %s\n", verdict, report.JS.Reason)
+	if report.PublicURL != nil {
+		_, _ = fmt.Fprintf(w, "  Public URL:  %s, from the canonical URL of the home page\n", report.PublicURL)
+	}
+	if len(report.AssetHosts) > 0 {
+		_, _ = fmt.Fprintf(w, "  Asset hosts: %s\n", strings.Join(report.AssetHosts, ", "))
+	}
+}
+
+// proposeConfig applies the findings the user accepts to conf, and returns a note
+// explaining each value set.
+func proposeConfig(p *prompter, conf *config.Config, u *url.URL, report *probe.Report) map[string]string {
+	notes := map[string]string{"url": "probed by bare init"}
+	conf.URL = u
+
+	conf.JS.Enabled = p.confirm("Render pages with JavaScript?", report.JS.Recommended)
+	if conf.JS.Enabled == report.JS.Recommended {
+		notes["js.enabled"] = report.JS.Reason
+	}
+
+	if len(report.Entrypoints) > 1 && p.confirm(fmt.Sprintf("Crawl from %s?", joinPaths(report.Entrypoints)), true) {
+		conf.Pages.Entrypoints = report.Entrypoints
+		notes["pages.entrypoints"] = "the home page, sitemaps and robots.txt found on the site"
+	}
+
+	for _, pattern := range report.Exclude {
+		if p.confirm(fmt.Sprintf("Exclude %s?", pattern), true) {
+			conf.Pages.Exclude = append(conf.Pages.Exclude, pattern)
+		}
+	}
+	if len(conf.Pages.Exclude) > 0 {
+		notes["pages.exclude"] = "private sections found on the site, its framework's and the ones disallowed in robots.txt"
+	}
+
+	if report.PublicURL != nil && p.confirm(fmt.Sprintf("Deploy to %s?", report.PublicURL), true) {
+		conf.PublicURL = report.PublicURL
+		notes["public_url"] = "canonical URL of the home page"
+	}
+
+	// Guessing the command is only safe when the site runs locally, and it is off by default
+	if f := report.Framework; f != nil && f.Command != "" && isLocal(u) &&
+		p.confirm(fmt.Sprintf("Start the site with %q before exporting?", f.Command), false) {
+		conf.Server.Command = f.Command
+		notes["server.command"] = "usual command of " + f.Name + ", check its port matches url"
+	}
+
+	return notes
+}
+
+// initHeader comments the findings of the probe that are not settings.
+func initHeader(u *url.URL, report *probe.Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Created by bare init from %s\n", u)
+	if report.Framework != nil {
+		fmt.Fprintf(&b, "# Built with %s, detected from its %s\n", report.Framework.Name, report.Framework.Evidence)
+	}
+	if len(report.AssetHosts) > 0 {
+		fmt.Fprintf(&b, "# Assets are loaded from %s: they are not exported,\n", strings.Join(report.AssetHosts, ", "))
+		b.WriteString("# these hosts must stay reachable from the deployed site\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func joinPaths(paths url.Paths) string {
+	s := make([]string, len(paths))
+	for i, p := range paths {
+		s[i] = string(p)
+	}
+	return strings.Join(s, ", ")
+}
+
+func isLocal(u *url.URL) bool {
+	host := u.Hostname()
+	return host == "localhost" || host == "127.0.0.1" || host == "::1" || strings.HasSuffix(host, ".test")
+}
+
+// prompter asks questions on the terminal, or takes the default answers with --yes.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	yes bool
+}
+
+func (p *prompter) ask(question, def string) string {
+	if p.yes {
+		return def
+	}
+	_, _ = fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	answer, _ := p.in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+	return def
+}
+
+func (p *prompter) confirm(question string, def bool) bool {
+	if p.yes {
+		return def
+	}
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+	for {
+		_, _ = fmt.Fprintf(p.out, "%s [%s]: ", question, choices)
+		answer, err := p.in.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		case "":
+			return def
+		}
+		if err != nil {
+			return def
+		}
+	}
+}
+
+func NewInitCommand(c *cli.CLI) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "init",
-		Short: "Create the default bare.toml config",
+		Use:   "init [url]",
+		Short: "Create a bare.toml config for a site",
+		Long: `Creates bare.toml. Given the URL of a running site, it probes the site first: it
+detects its framework, reads robots.txt and its sitemaps, compares its home page with
+and without JavaScript, and looks for private sections such as /admin or /api. It then
+proposes the entrypoints, excludes and settings to use, and writes them in an
+annotated config. Without a URL, the default config is written.
+
+Proposals are confirmed one by one on a terminal, and accepted with --yes.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runInit(cli)
+			return runInit(c, cmd, args)
 		},
 	}
 
+	cmd.Flags().BoolP("yes", "y", false, "accept every proposal without asking")
+
 	return cmd
 }
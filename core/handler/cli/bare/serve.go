@@ -1,15 +1,30 @@
 package bare
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/deploy"
+	"github.com/felixdorn/bare/core/domain/exporter"
+	"github.com/felixdorn/bare/core/domain/server"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/felixdorn/bare/core/domain/watcher"
 	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"github.com/felixdorn/bare/core/handler/cli/fetch"
 	"github.com/pkg/browser"
 	"github.com/spf13/cobra"
 )
@@ -20,15 +35,24 @@ func runServe(c *cli.CLI, cmd *cobra.Command, args []string) error {
 		dir = args[0]
 	}
 
+	conf, _, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
 	if dir == "" {
-		conf, err := config.Get()
+		dir = conf.Output
+	}
+
+	if cmd.Flags().Changed("url") {
+		uStr, _ := cmd.Flags().GetString("url")
+		if !strings.HasPrefix(uStr, "http://") && !strings.HasPrefix(uStr, "https://") {
+			uStr = "http://" + uStr
+		}
+		u, err := url.Parse(uStr)
 		if err != nil {
-			if !errors.Is(err, os.ErrNotExist) {
-				return err
-			}
-			conf = config.NewDefaultConfig()
+			return fmt.Errorf("invalid URL: %w", err)
 		}
-		dir = conf.Output
+		conf.URL = u
 	}
 
 	if _, err := os.Stat(dir); err != nil {
@@ -38,15 +62,22 @@ func runServe(c *cli.CLI, cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	profileName, _ := cmd.Flags().GetString("emulate")
+	profile, ok := server.Profiles[profileName]
+	if !ok {
+		return fmt.Errorf("unknown host '%s': use %s", profileName, strings.Join(server.ProfileNames(), ", "))
+	}
+	spa, _ := cmd.Flags().GetBool("spa")
+
+	host, _ := cmd.Flags().GetString("host")
 	port, _ := cmd.Flags().GetInt("port")
 	maxRetries := 5
 	var ln net.Listener
-	var err error
 	var addr string
 
 	for i := 0; i < maxRetries; i++ {
 		currentPort := port + i
-		addr = fmt.Sprintf("127.0.0.1:%d", currentPort)
+		addr = net.JoinHostPort(host, strconv.Itoa(currentPort))
 		ln, err = net.Listen("tcp", addr)
 		if err == nil {
 			break // Port is available
@@ -68,7 +99,15 @@ func runServe(c *cli.CLI, cmd *cobra.Command, args []string) error {
 	}
 	defer ln.Close()
 
-	url := fmt.Sprintf("http://%s", addr)
+	useHTTPS, _ := cmd.Flags().GetBool("https")
+	var tlsConfig *tls.Config
+	if useHTTPS {
+		if tlsConfig, err = localTLSConfig(c, host); err != nil {
+			return err
+		}
+	}
+
+	url := previewURL(ln.Addr().(*net.TCPAddr), useHTTPS)
 
 	open, _ := cmd.Flags().GetBool("open")
 	if open {
@@ -80,24 +119,371 @@ func runServe(c *cli.CLI, cmd *cobra.Command, args []string) error {
 		}()
 	}
 
-	_, _ = fmt.Fprintf(c.Out(), "Serving static files from %s on %s\n", dir, url)
-	fs := http.FileServer(http.Dir(dir))
-	http.Handle("/", fs)
+	_, _ = fmt.Fprintf(c.Out(), "Serving static files from %s on %s (emulating %s)\n", dir, url, profileName)
+	cacheControl, err := cacheControlFunc(conf, dir)
+	if err != nil {
+		return err
+	}
+	var handler http.Handler = server.Host(dir, server.HostOptions{Profile: profile, SPA: spa, CacheControl: cacheControl})
 
-	err = http.Serve(ln, nil)
+	if compare, _ := cmd.Flags().GetBool("compare"); compare {
+		conf.Output = dir
+		var stop func()
+		handler, stop, err = compareHandler(c, conf, handler)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		_, _ = fmt.Fprintf(c.Out(), "Comparing pages with %s, add ?%s=origin to view them on the origin\n", conf.URL, server.CompareParam)
+	}
+
+	watchDirs, _ := cmd.Flags().GetStringSlice("watch-dir")
+	if watch, _ := cmd.Flags().GetBool("watch"); watch || len(watchDirs) > 0 {
+		conf.Output = dir
+		live := server.NewLiveReload()
+		stop, err := startWatch(c, cmd, conf, watchDirs, live)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		handler = live.Wrap(handler)
+	}
+
+	if compress, _ := cmd.Flags().GetBool("compress"); compress {
+		handler = server.Compress(handler, 1024)
+	}
+
+	log := c.Log()
+	requests := server.NewRequestLog(func(r server.Request) {
+		event := log.Info()
+		if r.Status >= 400 {
+			event = log.Warn()
+		}
+		event.Int("status", r.Status).Str("referrer", r.Referrer).Dur("duration", r.Duration).Msg(r.Method + " " + r.Path)
+	})
+	handler = requests.Wrap(handler)
+
+	// Open connections, such as live reload streams, end with the context
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			served <- srv.ServeTLS(ln, "", "")
+		} else {
+			served <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err = <-served:
+	case <-ctx.Done():
+		_, _ = fmt.Fprintln(c.Out(), "Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = srv.Shutdown(shutdownCtx)
+	}
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("server failed: %w", err)
 	}
 
+	addMissing, _ := cmd.Flags().GetBool("add-missing")
+	return reportMissing(c, requests, addMissing, configFile(cmd))
+}
+
+// reportMissing prints the paths the preview could not find, and adds them to the
+// entrypoints of the config file if asked to.
+func reportMissing(c *cli.CLI, requests *server.RequestLog, add bool, file string) error {
+	missing := requests.Missing()
+	if len(missing) == 0 {
+		return nil
+	}
+
+	_, _ = fmt.Fprintln(c.Out(), "Missing paths, by referring page:")
+	if err := server.WriteMissing(c.Out(), missing); err != nil {
+		return err
+	}
+	if !add {
+		return nil
+	}
+
+	added, err := config.AddEntrypoints(file, requests.MissingPaths())
+	if err != nil {
+		return fmt.Errorf("could not add the missing paths to the entrypoints: %w", err)
+	}
+	if len(added) > 0 {
+		_, _ = fmt.Fprintf(c.Out(), "Added %d paths to pages.entrypoints in %s\n", len(added), file)
+	}
 	return nil
 }
 
+// previewURL returns the URL to open the preview listening on addr.
+func previewURL(addr *net.TCPAddr, useHTTPS bool) string {
+	scheme := "http"
+	if useHTTPS {
+		scheme = "https"
+	}
+	host := addr.IP.String()
+	if addr.IP.IsUnspecified() || addr.IP.IsLoopback() {
+		host = "localhost" // matches the certificate, and secure contexts
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(addr.Port)))
+}
+
+// localTLSConfig returns a TLS configuration with a certificate signed by the local
+// certificate authority, created in the user cache directory on first use.
+func localTLSConfig(c *cli.CLI, host string) (*tls.Config, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not find a directory to store certificates: %w", err)
+	}
+	ca, created, err := server.LoadCA(filepath.Join(cacheDir, "bare", "tls"))
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		_, _ = fmt.Fprintf(c.Err(), "Created a local certificate authority, trust %s to remove browser warnings\n", ca.CertFile)
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		// Listening on every interface, e.g. to preview on a phone
+		addrs, _ := net.InterfaceAddrs()
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+				hosts = append(hosts, ipNet.IP.String())
+			}
+		}
+	} else if host != "" && !slices.Contains(hosts, host) {
+		hosts = append(hosts, host)
+	}
+
+	cert, err := ca.Certificate(hosts)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// cacheControlFunc returns the Cache-Control of the files of the preview: the first
+// [[deploy.cache_control]] rule matching them, immutable for fingerprinted files, and
+// revalidation for the others.
+func cacheControlFunc(conf *config.Config, dir string) (func(rel string) string, error) {
+	rules := make([]deploy.CacheRule, len(conf.Deploy.CacheControl))
+	for i, rule := range conf.Deploy.CacheControl {
+		rules[i] = deploy.CacheRule{Paths: rule.Paths, Value: rule.Value}
+	}
+
+	manifest := ""
+	if conf.Fingerprint.Enabled {
+		manifest = conf.Fingerprint.Manifest
+	}
+	immutable, err := deploy.Fingerprinted(dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(rel string) string {
+		if value := deploy.CacheControl(rules, rel); value != "" {
+			return value
+		}
+		if immutable["/"+rel] {
+			return "public, max-age=31536000, immutable"
+		}
+		return server.DefaultCacheControl
+	}, nil
+}
+
+// startWatch refreshes the exported pages when the source directories change, or
+// periodically from the origin without them, and reloads the browsers showing them.
+func startWatch(c *cli.CLI, cmd *cobra.Command, conf *config.Config, dirs []string, live *server.LiveReload) (func(), error) {
+	for _, d := range dirs {
+		if _, err := os.Stat(d); err != nil {
+			return nil, fmt.Errorf("cannot watch %s: %w", d, err)
+		}
+	}
+
+	interval, _ := cmd.Flags().GetDuration("poll")
+	if !cmd.Flags().Changed("poll") && len(dirs) == 0 {
+		interval = 5 * time.Second // fetching every page is slower than listing files
+	}
+
+	manifest := ""
+	if conf.Fingerprint.Enabled {
+		manifest = conf.Fingerprint.Manifest
+	}
+	fingerprinted, err := deploy.Fingerprinted(conf.Output, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	fetcher, closeFetcher, err := newFetcher(c, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	log := c.Log()
+	export := exporter.NewExport(conf, log, fetcher)
+	finish := finishPage(conf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = watcher.Poll(ctx, dirs, interval, func(ctx context.Context) {
+			paths, err := watchedPaths(conf.Output, fingerprinted)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to list exported pages")
+				return
+			}
+			changed, err := export.Refresh(ctx, paths, finish)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to refresh pages")
+			}
+			if err := compressPages(conf, export, changed); err != nil {
+				log.Error().Err(err).Msg("Failed to compress refreshed pages")
+			}
+			if len(changed) > 0 {
+				_, _ = fmt.Fprintf(c.Out(), "Reloading after %d changed files\n", len(changed))
+				live.Reload(changed)
+			}
+		})
+	}()
+
+	if len(dirs) > 0 {
+		_, _ = fmt.Fprintf(c.Out(), "Watching %s for changes\n", strings.Join(dirs, ", "))
+	} else {
+		_, _ = fmt.Fprintf(c.Out(), "Polling %s every %s for changes\n", conf.URL, interval)
+	}
+
+	return func() {
+		cancel()
+		<-done
+		if closeFetcher != nil {
+			_ = closeFetcher()
+		}
+	}, nil
+}
+
+// compareHandler wraps handler to compare the pages it serves with the origin.
+func compareHandler(c *cli.CLI, conf *config.Config, handler http.Handler) (http.Handler, func(), error) {
+	diff, err := diffOptions(conf, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fetcher, closeFetcher, err := newFetcher(c, conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log := c.Log()
+	export := exporter.NewExport(conf, log, fetcher)
+	finish := finishPage(conf)
+
+	handler = server.Compare(conf.Output, handler, server.CompareOptions{
+		Origin: conf.URL.URL,
+		// The origin's response goes through the same stages as the exported file
+		Render: func(ctx context.Context, path string) (int, []byte, error) {
+			return export.Render(ctx, exportedFrom(conf, path), finish)
+		},
+		Diff: diff,
+		OnCompare: func(cmp server.Comparison) {
+			if diffs := cmp.Differences(); len(diffs) > 0 {
+				log.Warn().Str("path", cmp.Path).Strs("missing", cmp.Missing).Msg(strings.Join(diffs, "; "))
+			}
+		},
+	})
+
+	return handler, func() {
+		if closeFetcher != nil {
+			_ = closeFetcher()
+		}
+	}, nil
+}
+
+// newFetcher creates the fetcher configured in conf, and the function closing it, if any.
+// With [[rules]], each URL is sent to the HTTP or the JS fetcher its rules choose.
+func newFetcher(c *cli.CLI, conf *config.Config) (crawler.Fetcher, func() error, error) {
+	httpFetcher := crawler.NewHTTPFetcher(nil)
+	if !conf.UsesJS() && len(conf.Rules) == 0 {
+		return httpFetcher, nil, nil
+	}
+
+	fetchers := []crawler.Fetcher{httpFetcher}
+	var jsFetcher *crawler.JSFetcher
+	if conf.UsesJS() {
+		opts, err := jsFetcherOptions(c, conf)
+		if err != nil {
+			return nil, nil, err
+		}
+		jsFetcher, err = crawler.NewJSFetcher(opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create JS fetcher: %w", err)
+		}
+		if len(conf.Rules) == 0 {
+			return jsFetcher, jsFetcher.Close, nil
+		}
+		fetchers = append(fetchers, jsFetcher)
+	}
+
+	router := crawler.NewRouter(func(u *url.URL) (crawler.Fetcher, crawler.FetchOptions) {
+		js, opts := conf.FetchFor(u)
+		if js {
+			return jsFetcher, fetch.Options(opts)
+		}
+		return httpFetcher, fetch.Options(opts)
+	}, fetchers...)
+	return router, router.Close, nil
+}
+
+// watchedPaths lists the URL paths of the exported pages, stylesheets and scripts.
+// Fingerprinted files are left out: their name changes with their content, so only
+// bare export can update them.
+func watchedPaths(dir string, fingerprinted map[string]bool) ([]string, error) {
+	paths, err := exportedPages(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(p); d.IsDir() || (ext != ".css" && ext != ".js") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if path := "/" + filepath.ToSlash(rel); !fingerprinted[path] {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
 func NewServeCommand(c *cli.CLI) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "serve [directory]",
 		Short: "Serve the exported site",
 		Long: `Starts a local web server to preview the contents of the output directory.
-By default, it serves the directory specified in bare.toml or 'dist/'.`,
+By default, it serves the directory specified in bare.toml or 'dist/'.
+
+The server behaves like the host the site is deployed to, chosen with --emulate:
+  netlify     clean URLs, .html stripped with 301s, _redirects, _headers and 404.html
+  cloudflare  the same as netlify, with 308 redirects
+  nginx       try_files $uri $uri/ $uri.html with error_page 404 /404.html`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runServe(c, cmd, args)
@@ -105,8 +491,19 @@ By default, it serves the directory specified in bare.toml or 'dist/'.`,
 	}
 
 	cmd.Flags().StringP("dir", "d", "", "Directory to serve from")
+	cmd.Flags().String("host", "127.0.0.1", "Interface to listen on, 0.0.0.0 for every interface")
 	cmd.Flags().IntP("port", "p", 8080, "Port to use")
+	cmd.Flags().Bool("https", false, "Serve over HTTPS with a certificate signed by a local certificate authority")
+	cmd.Flags().Bool("compress", true, "Compress responses with brotli or gzip when there is no precompressed file")
 	cmd.Flags().BoolP("open", "o", false, "Open in browser")
+	cmd.Flags().String("emulate", "netlify", "Host to emulate: "+strings.Join(server.ProfileNames(), ", "))
+	cmd.Flags().Bool("spa", false, "Serve /index.html for missing paths, like a single-page app")
+	cmd.Flags().Bool("watch", false, "Refresh pages from the origin when they change and reload the browser")
+	cmd.Flags().StringSlice("watch-dir", nil, "Source directory to watch instead of polling the origin (implies --watch, can be repeated)")
+	cmd.Flags().String("url", "", "Base URL of the origin to refresh pages from (overrides bare.toml)")
+	cmd.Flags().Bool("compare", false, "Compare every page with the origin, and switch to it with ?_bare=origin")
+	cmd.Flags().Bool("add-missing", false, "On exit, add the paths that were not found to pages.entrypoints in bare.toml")
+	cmd.Flags().Duration("poll", time.Second, "How often to check for changes (5s when polling the origin)")
 
 	return cmd
 }
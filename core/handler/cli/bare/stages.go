@@ -0,0 +1,97 @@
+package bare
+
+import (
+	"github.com/felixdorn/bare/core/domain/compressor"
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/domain/exporter"
+	"github.com/felixdorn/bare/core/domain/fingerprint"
+	"github.com/felixdorn/bare/core/domain/minifier"
+	"github.com/felixdorn/bare/core/domain/rewriter"
+)
+
+func newMinifier(conf *config.Config) *minifier.Minifier {
+	return minifier.New(conf.Output, minifier.Options{
+		HTML: conf.Minify.HTML,
+		CSS:  conf.Minify.CSS,
+		Skip: func(p string) bool { return !conf.Minifies(p) },
+	})
+}
+
+func newFingerprinter(conf *config.Config) *fingerprint.Fingerprinter {
+	return fingerprint.New(conf.Output, fingerprint.Options{
+		Extensions: conf.Fingerprint.Extensions,
+		Exclude:    conf.Fingerprint.Exclude,
+		Manifest:   conf.Fingerprint.Manifest,
+	})
+}
+
+func newCompressor(conf *config.Config) *compressor.Compressor {
+	formats := make([]compressor.Format, len(conf.Compress.Formats))
+	for i, f := range conf.Compress.Formats {
+		formats[i] = compressor.Format(f)
+	}
+
+	return compressor.New(conf.Output, compressor.Options{
+		Formats:     formats,
+		GzipLevel:   conf.Compress.GzipLevel,
+		BrotliLevel: conf.Compress.BrotliLevel,
+		MinSize:     conf.Compress.MinSize,
+	})
+}
+
+// finishPage returns the stages of bare export that a page exported again goes through,
+// so it ends up like the files around it: rewritten, then minified and fingerprinted
+// when enabled. Its sidecars are written once it is saved, see compressPages.
+func finishPage(conf *config.Config) exporter.Finish {
+	rw := rewriter.New(conf.Output, conf.URL)
+	m := newMinifier(conf)
+	f := newFingerprinter(conf)
+
+	return func(rel string, body []byte) ([]byte, error) {
+		body = rw.Rewrite(body)
+		if conf.Minify.Enabled {
+			body = m.Minify(rel, body)
+		}
+		if conf.Fingerprint.Enabled {
+			return f.Rewrite(rel, body)
+		}
+		return body, nil
+	}
+}
+
+// compressPages writes the sidecars of pages exported again, when compression is enabled.
+func compressPages(conf *config.Config, export *exporter.Export, paths []string) error {
+	if !conf.Compress.Enabled {
+		return nil
+	}
+
+	c := newCompressor(conf)
+	for _, p := range paths {
+		file, err := export.File(p)
+		if err != nil {
+			return err
+		}
+		if err := c.Compress(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportedFrom returns the path a file was exported from: fingerprinted files are
+// fetched from the origin under their original name.
+func exportedFrom(conf *config.Config, path string) string {
+	if !conf.Fingerprint.Enabled {
+		return path
+	}
+	manifest, err := newFingerprinter(conf).Manifest()
+	if err != nil {
+		return path
+	}
+	for original, fingerprinted := range manifest {
+		if fingerprinted == path {
+			return original
+		}
+	}
+	return path
+}
@@ -0,0 +1,270 @@
+package bare
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/server"
+	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/felixdorn/bare/core/domain/visual"
+	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"github.com/spf13/cobra"
+)
+
+func runVerify(c *cli.CLI, cmd *cobra.Command, args []string) error {
+	if enabled, _ := cmd.Flags().GetBool("visual"); !enabled {
+		return fmt.Errorf("nothing to verify: use --visual")
+	}
+
+	conf, _, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("url") {
+		uStr, _ := cmd.Flags().GetString("url")
+		if !strings.HasPrefix(uStr, "http://") && !strings.HasPrefix(uStr, "https://") {
+			uStr = "http://" + uStr
+		}
+		u, err := url.Parse(uStr)
+		if err != nil {
+			return fmt.Errorf("invalid URL: %w", err)
+		}
+		conf.URL = u
+	}
+
+	dir := conf.Output
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("directory '%s' does not exist. Run 'bare export' first", dir)
+		}
+		return err
+	}
+
+	profileName, _ := cmd.Flags().GetString("emulate")
+	profile, ok := server.Profiles[profileName]
+	if !ok {
+		return fmt.Errorf("unknown host '%s': use %s", profileName, strings.Join(server.ProfileNames(), ", "))
+	}
+
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	reportDir, _ := cmd.Flags().GetString("output")
+
+	paths, err := exportedPages(dir)
+	if err != nil {
+		return fmt.Errorf("could not list exported pages: %w", err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no HTML pages found in %s", dir)
+	}
+
+	// Serve the export on a random port, the same way `bare serve` would.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("could not start export server: %w", err)
+	}
+	srv := &http.Server{Handler: server.Host(dir, server.HostOptions{Profile: profile})}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	exportURL, _ := url.Parse("http://" + ln.Addr().String())
+
+	opts, err := jsFetcherOptions(c, conf)
+	if err != nil {
+		return err
+	}
+	opts.Screenshot = true
+
+	fetcher, err := crawler.NewJSFetcher(opts)
+	if err != nil {
+		return fmt.Errorf("failed to create JS fetcher: %w", err)
+	}
+	defer fetcher.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	for _, sub := range []string{"origin", "export", "diff"} {
+		if err := os.MkdirAll(filepath.Join(reportDir, sub), 0755); err != nil {
+			return fmt.Errorf("could not create report directory: %w", err)
+		}
+	}
+
+	gallery := &visual.Gallery{
+		OriginURL:   conf.URL.String(),
+		ExportURL:   exportURL.String(),
+		Threshold:   threshold,
+		GeneratedAt: time.Now(),
+	}
+
+	log := c.Log()
+	fmt.Printf("Comparing %d pages...\n", len(paths))
+	for _, p := range paths {
+		if ctx.Err() != nil {
+			fmt.Println("\nVerification cancelled.")
+			return nil
+		}
+
+		page := comparePage(ctx, fetcher, conf.URL, exportURL, p, reportDir, threshold)
+		gallery.Pages = append(gallery.Pages, page)
+
+		if page.Error != "" {
+			log.Error().Str("path", p).Str("error", page.Error).Msg("Failed to compare page")
+		} else {
+			log.Info().Str("path", p).Float64("diff", page.Ratio).Bool("failed", page.Failed).Msg("Compared page")
+		}
+	}
+
+	f, err := os.Create(filepath.Join(reportDir, "index.html"))
+	if err != nil {
+		return fmt.Errorf("failed to create gallery: %w", err)
+	}
+	defer f.Close()
+
+	if err := visual.WriteGallery(f, gallery); err != nil {
+		return fmt.Errorf("failed to write gallery: %w", err)
+	}
+
+	fmt.Printf("Visual report saved to %s\n", filepath.Join(reportDir, "index.html"))
+
+	if gallery.TotalFailed > 0 {
+		return &cli.StatusError{
+			Status:     fmt.Sprintf("%d of %d pages differ from the origin", gallery.TotalFailed, len(paths)),
+			StatusCode: 1,
+		}
+	}
+
+	return nil
+}
+
+// comparePage screenshots a page on the origin and on the export, and writes both along with their diff.
+func comparePage(ctx context.Context, fetcher crawler.Fetcher, originURL, exportURL *url.URL, path, reportDir string, threshold float64) visual.GalleryPage {
+	page := visual.GalleryPage{Path: path}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+
+	origin, err := fetcher.Fetch(ctx, originURL.ResolveReference(ref))
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+	exported, err := fetcher.Fetch(ctx, exportURL.ResolveReference(ref))
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+
+	result, err := visual.ComparePNG(origin.Screenshot, exported.Screenshot)
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+
+	name := screenshotName(path)
+	page.Origin = filepath.ToSlash(filepath.Join("origin", name))
+	page.Export = filepath.ToSlash(filepath.Join("export", name))
+	page.DiffPath = filepath.ToSlash(filepath.Join("diff", name))
+	page.Ratio = result.Ratio()
+	page.Failed = page.Ratio > threshold
+
+	if err := os.WriteFile(filepath.Join(reportDir, page.Origin), origin.Screenshot, 0644); err != nil {
+		page.Error = err.Error()
+		return page
+	}
+	if err := os.WriteFile(filepath.Join(reportDir, page.Export), exported.Screenshot, 0644); err != nil {
+		page.Error = err.Error()
+		return page
+	}
+	if err := visual.WritePNG(filepath.Join(reportDir, page.DiffPath), result.Diff); err != nil {
+		page.Error = err.Error()
+		return page
+	}
+
+	return page
+}
+
+// exportedPages lists the URL paths of the HTML pages in an export directory.
+func exportedPages(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".html" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = "/" + filepath.ToSlash(rel)
+
+		if rel == "/index.html" {
+			rel = "/"
+		} else {
+			rel = strings.TrimSuffix(rel, "/index.html")
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	return paths, err
+}
+
+// screenshotName turns a URL path into a flat file name. Flattening alone maps /a/b and
+// /a_b to the same name, so a short hash of the full path, query included, keeps them apart.
+func screenshotName(path string) string {
+	name := strings.Trim(path, "/")
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = strings.Trim(name[:i], "/")
+	}
+	if name == "" {
+		name = "index"
+	}
+	sum := sha256.Sum256([]byte(path))
+	return strings.ReplaceAll(name, "/", "_") + "-" + hex.EncodeToString(sum[:])[:8] + ".png"
+}
+
+func NewVerifyCommand(c *cli.CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [directory]",
+		Short: "Verify that the export matches the origin",
+		Long: `Compares the exported site with the origin.
+
+With --visual, every exported page is screenshotted on the origin and on the export,
+served like the static host chosen with --emulate would, as in bare serve. Pages whose
+pixel difference exceeds the threshold are flagged, and an HTML gallery of the diffs
+is written to the output directory.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(c, cmd, args)
+		},
+	}
+
+	cmd.Flags().Bool("visual", false, "Compare screenshots of the origin and the export")
+	cmd.Flags().String("url", "", "Base URL of the origin (overrides bare.toml)")
+	cmd.Flags().String("emulate", "netlify", "Host to emulate when serving the export: "+strings.Join(server.ProfileNames(), ", "))
+	cmd.Flags().Float64("threshold", 0.01, "Fraction of differing pixels above which a page is flagged")
+	cmd.Flags().StringP("output", "o", "bare-verify", "Directory to write the visual report to")
+
+	return cmd
+}
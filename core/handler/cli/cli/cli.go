@@ -9,6 +9,7 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Stdin provides a minimal interface for  reading stdin.
@@ -27,6 +28,7 @@ type CLI struct {
 	log zerolog.Logger
 
 	commands []*cobra.Command
+	flags    []func(*pflag.FlagSet)
 }
 
 // Out returns the current output writer.
@@ -63,6 +65,9 @@ func (c *CLI) Run(args []string) int {
 	}
 
 	cli.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose logging")
+	for _, fn := range c.flags {
+		fn(cli.PersistentFlags())
+	}
 	cli.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
 			zerolog.SetGlobalLevel(zerolog.DebugLevel)
@@ -185,6 +190,13 @@ func WithLogger(log zerolog.Logger) Opt {
 	}
 }
 
+// WithFlags adds flags shared by every command.
+func WithFlags(fn func(*pflag.FlagSet)) Opt {
+	return func(c *CLI) {
+		c.flags = append(c.flags, fn)
+	}
+}
+
 func When(condition bool, opts ...Opt) Opt {
 	return func(c *CLI) {
 		if condition {
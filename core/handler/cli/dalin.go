@@ -9,7 +9,7 @@ func NewDalin(opts ...cli.Opt) *cli.CLI {
 	// Prepend WithName so it can be overridden by user opts
 	allOpts := append([]cli.Opt{cli.WithName("dalin")}, opts...)
 	app := cli.New(
-		allOpts...,
+		append(allOpts, cli.WithFlags(dalin.ConfigFlags))...,
 	)
 
 	app.Add(
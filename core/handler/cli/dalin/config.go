@@ -0,0 +1,49 @@
+package dalin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ConfigFile is dalin's own config file, read instead of bare.toml when present.
+const ConfigFile = "dalin.toml"
+
+// ConfigFlags adds the flags selecting the config file and its profile, shared by every command.
+func ConfigFlags(flags *pflag.FlagSet) {
+	flags.String("config", "", "Config file to use (defaults to "+ConfigFile+", then "+config.DefaultFile+")")
+	flags.String("profile", "", "Profile of the config file to apply, [profile.<name>] (defaults to $"+config.ProfileEnv+")")
+}
+
+// loadConfig reads the config file selected with --config, or the first of dalin.toml
+// and bare.toml that exists, with the profile selected with --profile or BARE_PROFILE.
+// It returns the file read, or an empty string and the default config if there is none.
+func loadConfig(cmd *cobra.Command) (*config.Config, string, error) {
+	profile, _ := cmd.Flags().GetString("profile")
+	if !cmd.Flags().Changed("profile") {
+		profile = os.Getenv(config.ProfileEnv)
+	}
+
+	candidates := []string{ConfigFile, config.DefaultFile}
+	if cmd.Flags().Changed("config") {
+		file, _ := cmd.Flags().GetString("config")
+		candidates = []string{file}
+	}
+
+	for _, file := range candidates {
+		conf, _, err := config.Load(file, profile)
+		if errors.Is(err, os.ErrNotExist) && !cmd.Flags().Changed("config") {
+			continue
+		}
+		return conf, file, err
+	}
+
+	if profile != "" {
+		return nil, "", fmt.Errorf("profile %q selected, but neither %s nor %s exist", profile, ConfigFile, config.DefaultFile)
+	}
+	return config.NewDefaultConfig(), "", nil
+}
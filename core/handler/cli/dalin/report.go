@@ -2,8 +2,11 @@ package dalin
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"html/template"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -13,12 +16,17 @@ import (
 	"time"
 
 	"github.com/felixdorn/bare/core/domain/analyzer"
+	"github.com/felixdorn/bare/core/domain/config"
 	"github.com/felixdorn/bare/core/domain/crawler"
+	"github.com/felixdorn/bare/core/domain/exporter"
 	"github.com/felixdorn/bare/core/domain/linter"
 	_ "github.com/felixdorn/bare/core/domain/linter/rules" // Register linting rules
 	"github.com/felixdorn/bare/core/domain/reporter"
 	"github.com/felixdorn/bare/core/domain/url"
+	"github.com/felixdorn/bare/core/domain/visual"
 	"github.com/felixdorn/bare/core/handler/cli/cli"
+	"github.com/felixdorn/bare/core/handler/cli/fetch"
+	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	"github.com/temoto/robotstxt"
 )
@@ -30,52 +38,97 @@ var (
 )
 
 func runReport(c *cli.CLI, cmd *cobra.Command, args []string) error {
-	// Get URL from args or flag
-	var siteURL *url.URL
-	var err error
+	conf, file, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
 
+	// Flags take precedence over the config file
+	urlStr := ""
 	if len(args) > 0 {
-		urlStr := args[0]
-		if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
-			urlStr = "http://" + urlStr
-		}
-		siteURL, err = url.Parse(urlStr)
-		if err != nil {
-			return fmt.Errorf("invalid URL: %w", err)
-		}
+		urlStr = args[0]
 	} else if cmd.Flags().Changed("url") {
-		urlStr, _ := cmd.Flags().GetString("url")
+		urlStr, _ = cmd.Flags().GetString("url")
+	} else if file == "" {
+		return fmt.Errorf("URL is required: provide as argument, use --url flag or set url in %s", config.DefaultFile)
+	}
+	if urlStr != "" {
 		if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
 			urlStr = "http://" + urlStr
 		}
-		siteURL, err = url.Parse(urlStr)
+		conf.URL, err = url.Parse(urlStr)
 		if err != nil {
 			return fmt.Errorf("invalid URL: %w", err)
 		}
-	} else {
-		return fmt.Errorf("URL is required: provide as argument or use --url flag")
 	}
+	siteURL := conf.URL
 
-	output, _ := cmd.Flags().GetString("output")
-	workers, _ := cmd.Flags().GetInt("workers")
-	entrypoints, _ := cmd.Flags().GetStringSlice("entrypoint")
-	excludes, _ := cmd.Flags().GetStringSlice("exclude")
+	if cmd.Flags().Changed("output") {
+		conf.Lint.Output, _ = cmd.Flags().GetString("output")
+	}
+	if cmd.Flags().Changed("workers") {
+		conf.WorkersCount, _ = cmd.Flags().GetInt("workers")
+	}
+	if cmd.Flags().Changed("entrypoint") {
+		eps, _ := cmd.Flags().GetStringSlice("entrypoint")
+		conf.Pages.Entrypoints = nil
+		for _, p := range eps {
+			conf.Pages.Entrypoints = append(conf.Pages.Entrypoints, url.Path(p))
+		}
+	}
+	if cmd.Flags().Changed("exclude") {
+		ex, _ := cmd.Flags().GetStringSlice("exclude")
+		conf.Pages.Exclude = nil
+		for _, p := range ex {
+			conf.Pages.Exclude = append(conf.Pages.Exclude, url.Path(p))
+		}
+	}
 
 	// JS config
-	jsEnabled, _ := cmd.Flags().GetBool("js-enabled")
-	jsWait, _ := cmd.Flags().GetDuration("js-wait")
-	jsMaxTabs, _ := cmd.Flags().GetInt("js-max-tabs")
-	jsExecutable, _ := cmd.Flags().GetString("js-executable")
-	jsFlags, _ := cmd.Flags().GetStringSlice("js-flag")
+	if cmd.Flags().Changed("js-enabled") {
+		conf.JS.Enabled, _ = cmd.Flags().GetBool("js-enabled")
+	}
+	if cmd.Flags().Changed("js-wait") {
+		wait, _ := cmd.Flags().GetDuration("js-wait")
+		conf.JS.Wait = config.Duration(wait)
+	}
+	if cmd.Flags().Changed("js-max-tabs") {
+		conf.JS.MaxTabs, _ = cmd.Flags().GetInt("js-max-tabs")
+	}
+	if cmd.Flags().Changed("js-executable") {
+		conf.JS.ExecutablePath, _ = cmd.Flags().GetString("js-executable")
+	}
+	if cmd.Flags().Changed("js-flag") {
+		conf.JS.Flags, _ = cmd.Flags().GetStringSlice("js-flag")
+	}
+	if cmd.Flags().Changed("device") {
+		conf.Lint.Device, _ = cmd.Flags().GetString("device")
+	}
+	if cmd.Flags().Changed("screenshots") {
+		conf.Lint.Screenshots, _ = cmd.Flags().GetBool("screenshots")
+	}
+	if cmd.Flags().Changed("disable") {
+		conf.Lint.Disable, _ = cmd.Flags().GetStringSlice("disable")
+	}
 
-	log := c.Log()
+	if err := checkRuleIDs(conf); err != nil {
+		return err
+	}
 
-	// Build exclude patterns
-	excludePatterns := make([]url.Path, len(excludes))
-	for i, e := range excludes {
-		excludePatterns[i] = url.Path(e)
+	output := conf.Lint.Output
+	jsEnabled := conf.UsesJS()
+	jsWait := time.Duration(conf.JS.Wait)
+	device := conf.Lint.Device
+	if device == "" && jsEnabled {
+		// js.device only applies to JavaScript crawls, a shared config may set it regardless
+		device = conf.JS.Device
 	}
-	excludePaths := url.Paths(excludePatterns)
+	screenshots := conf.Lint.Screenshots
+	if screenshots && !jsEnabled {
+		return fmt.Errorf("screenshots require JavaScript, use --js-enabled or set js.enabled")
+	}
+
+	log := c.Log()
 
 	// Collected page reports
 	var pages []reporter.PageReport
@@ -92,31 +145,88 @@ func runReport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Device emulation needs a browser. With "both", the crawl runs as mobile
+	// and every page is also rendered as desktop so the two can be compared.
+	var jsDevice *crawler.Device
+	compareDesktop := false
+	if device != "" {
+		if !jsEnabled && cmd.Flags().Changed("device") {
+			return fmt.Errorf("--device requires --js-enabled")
+		}
+		if !jsEnabled {
+			return fmt.Errorf("lint.device requires JavaScript, use --js-enabled or set js.enabled")
+		}
+		name := device
+		if device == "both" {
+			name = crawler.Mobile.Name
+			compareDesktop = true
+		}
+		jsDevice, err = crawler.DeviceByName(name)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create the appropriate fetcher based on JS config
-	var fetcher crawler.Fetcher
+	httpFetcher := crawler.NewHTTPFetcher(nil)
+	var fetcher, jsFetcher crawler.Fetcher
 	if jsEnabled {
 		wait := int(jsWait.Milliseconds())
 		if wait == 0 {
 			wait = 2000 // default 2 seconds
 		}
-		maxTabs := jsMaxTabs
+		maxTabs := conf.JS.MaxTabs
 		if maxTabs == 0 {
 			maxTabs = 1
 		}
-		jsFetcher, err := crawler.NewJSFetcher(crawler.JSFetcherOptions{
+		chrome, err := crawler.NewJSFetcher(crawler.JSFetcherOptions{
 			Wait:           wait,
 			MaxTabs:        maxTabs,
-			ExecutablePath: jsExecutable,
-			Flags:          jsFlags,
+			ExecutablePath: conf.JS.ExecutablePath,
+			Flags:          conf.JS.Flags,
+			Interactions:   fetch.Interactions(conf.JS.Interactions),
+			Device:         jsDevice,
+			Screenshot:     screenshots,
 			Logger:         log,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create JS fetcher: %w", err)
 		}
-		defer jsFetcher.Close()
-		fetcher = jsFetcher
+		defer chrome.Close()
+		fetcher, jsFetcher = chrome, chrome
+
+		if compareDesktop {
+			desktop := crawler.Desktop
+			dFetcher, err := crawler.NewJSFetcher(crawler.JSFetcherOptions{
+				Wait:           wait,
+				MaxTabs:        maxTabs,
+				ExecutablePath: conf.JS.ExecutablePath,
+				Flags:          conf.JS.Flags,
+				Interactions:   fetch.Interactions(conf.JS.Interactions),
+				Device:         &desktop,
+				Logger:         log,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create desktop JS fetcher: %w", err)
+			}
+			defer dFetcher.Close()
+			// Rendering on the workers keeps the crawl parallel
+			jsFetcher = &desktopComparer{Fetcher: chrome, desktop: dFetcher, log: log}
+			fetcher = jsFetcher
+		}
 	} else {
-		fetcher = crawler.NewHTTPFetcher(nil)
+		fetcher = httpFetcher
+	}
+
+	// Rules send each URL to the HTTP or the JS fetcher, like in the export
+	if len(conf.Rules) > 0 {
+		fetcher = crawler.NewRouter(func(u *url.URL) (crawler.Fetcher, crawler.FetchOptions) {
+			js, opts := conf.FetchFor(u)
+			if js {
+				return jsFetcher, fetch.Options(opts)
+			}
+			return httpFetcher, fetch.Options(opts)
+		})
 	}
 
 	// Fetch and parse robots.txt
@@ -127,11 +237,24 @@ func runReport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 		robots, _ = robotstxt.FromBytes(robotsResult.Body)
 	}
 
+	// Templated routes reach pages that no link points to, like in the export
+	entrypoints := make([]string, len(conf.Pages.Entrypoints))
+	for i, ep := range conf.Pages.Entrypoints {
+		entrypoints[i] = string(ep)
+	}
+	if len(conf.Routes) > 0 {
+		routes, err := exporter.ExpandRoutes(ctx, siteURL, conf.Routes, &http.Client{Timeout: 30 * time.Second})
+		if err != nil {
+			return err
+		}
+		entrypoints = append(entrypoints, routes...)
+	}
+
 	fmt.Printf("Crawling %s...\n", siteURL.String())
 
 	cr := crawler.New(crawler.Config{
 		BaseURL:     siteURL,
-		WorkerCount: workers,
+		WorkerCount: conf.WorkersCount,
 		Entrypoints: entrypoints,
 		Logger:      log,
 		Fetcher:     fetcher,
@@ -148,7 +271,7 @@ func runReport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 			}
 
 			// Check excludes
-			if excludePaths.MatchAny(link.URL.Path) {
+			if !conf.IsURLAllowed(link.URL) {
 				return ErrExcluded
 			}
 
@@ -184,6 +307,8 @@ func runReport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 			lints, err := linter.Check(page.Body, page.URL, analysis, linter.CheckOptions{
 				StatusCode:    page.StatusCode,
 				RedirectChain: page.RedirectChain,
+				Layout:        page.Layout,
+				DesktopBody:   page.DesktopBody,
 			})
 			if err != nil {
 				log.Error().Err(err).Str("url", page.URL.String()).Msg("Failed to lint page")
@@ -202,6 +327,16 @@ func runReport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 				}
 			}
 
+			var thumbnail template.URL
+			if len(page.Screenshot) > 0 {
+				thumb, err := visual.Thumbnail(page.Screenshot, 320)
+				if err != nil {
+					log.Error().Err(err).Str("url", page.URL.String()).Msg("Failed to create thumbnail")
+				} else {
+					thumbnail = template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumb))
+				}
+			}
+
 			pageReport := reporter.PageReport{
 				URL:           page.URL.String(),
 				Title:         analysis.Title,
@@ -211,6 +346,7 @@ func runReport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 				IsNoindex:     linter.IsNoindexHTML(page.Body),
 				Lints:         lints,
 				InternalLinks: internalLinks,
+				Thumbnail:     thumbnail,
 			}
 
 			pagesMu.Lock()
@@ -303,6 +439,23 @@ func runReport(c *cli.CLI, cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Skip the rules disabled in the config, for every page or by [[rules]]
+	for i := range pages {
+		pageURL, err := url.Parse(pages[i].URL)
+		if err != nil {
+			continue
+		}
+		if disabled := conf.LintDisabled(pageURL); len(disabled) > 0 {
+			lints := pages[i].Lints[:0]
+			for _, l := range pages[i].Lints {
+				if !disabled[l.Rule] {
+					lints = append(lints, l)
+				}
+			}
+			pages[i].Lints = lints
+		}
+	}
+
 	// Generate the report
 	fmt.Printf("Generating report for %d pages...\n", len(pages))
 
@@ -338,7 +491,11 @@ func NewReportCommand(c *cli.CLI) *cobra.Command {
 		Long: `Crawls a website and generates an HTML report with:
 - List of all pages with their title, description, and metadata
 - Images found on each page with their alt text
-- SEO analysis and recommendations (coming soon)`,
+- SEO analysis and recommendations (coming soon)
+
+Crawl settings (url, entrypoints, routes, excludes and JS) are read from dalin.toml,
+or bare.toml, so the audit crawls what is exported. Report options live in its [lint]
+section. Flags take precedence over the config file.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runReport(c, cmd, args)
@@ -357,10 +514,69 @@ func NewReportCommand(c *cli.CLI) *cobra.Command {
 	cmd.Flags().Int("js-max-tabs", 1, "Maximum parallel Chrome tabs for JS fetching")
 	cmd.Flags().String("js-executable", "", "Path to Chrome/Chromium executable")
 	cmd.Flags().StringSlice("js-flag", []string{}, "Additional Chrome flags")
+	cmd.Flags().Bool("screenshots", false, "Attach screenshot thumbnails to page reports (requires --js-enabled)")
+	cmd.Flags().String("device", "", "Device profile to render with: desktop, mobile, or both (requires --js-enabled)")
+	cmd.Flags().StringSlice("disable", []string{}, "IDs of the rules to skip (can be used multiple times)")
 
 	return cmd
 }
 
+// checkRuleIDs checks the IDs of the lint rules toggled in the config, so a typo
+// doesn't silently keep a rule.
+func checkRuleIDs(conf *config.Config) error {
+	known := make(map[string]bool)
+	for _, r := range linter.All() {
+		known[r.ID] = true
+	}
+	for _, r := range linter.AllSiteRules() {
+		known[r.ID] = true
+	}
+
+	check := func(key string, ids []string) error {
+		for _, id := range ids {
+			if !known[id] {
+				return fmt.Errorf("unknown rule %q in %s", id, key)
+			}
+		}
+		return nil
+	}
+	if err := check("lint.disable", conf.Lint.Disable); err != nil {
+		return err
+	}
+	for _, r := range conf.Rules {
+		if err := check("rules.lint_disable", r.LintDisable); err != nil {
+			return err
+		}
+		if err := check("rules.lint_enable", r.LintEnable); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// desktopComparer renders each HTML page with its Fetcher, then as desktop with the
+// same fetch options, so the linter can compare the two renderings.
+type desktopComparer struct {
+	crawler.Fetcher
+	desktop crawler.Fetcher
+	log     zerolog.Logger
+}
+
+func (f *desktopComparer) Fetch(ctx context.Context, u *url.URL) (*crawler.FetchResult, error) {
+	result, err := f.Fetcher.Fetch(ctx, u)
+	if err != nil || !isCrawlable(u) {
+		return result, err
+	}
+
+	desktop, err := f.desktop.Fetch(ctx, u)
+	if err != nil {
+		f.log.Error().Err(err).Str("url", u.String()).Msg("Failed to render desktop version")
+		return result, nil
+	}
+	result.DesktopBody = desktop.Body
+	return result, nil
+}
+
 // isCrawlable checks if a URL should be crawled for more links.
 func isCrawlable(u *url.URL) bool {
 	ext := filepath.Ext(u.Path)
@@ -0,0 +1,31 @@
+// Package fetch converts the fetching settings of the configuration into the options
+// of the crawler, for the bare and dalin commands.
+package fetch
+
+import (
+	"time"
+
+	"github.com/felixdorn/bare/core/domain/config"
+	"github.com/felixdorn/bare/core/domain/crawler"
+)
+
+// Interactions converts js.interactions into the steps the JS fetcher runs.
+func Interactions(is []config.Interaction) crawler.Interactions {
+	interactions := make(crawler.Interactions, len(is))
+	for i, interaction := range is {
+		steps := make([]crawler.Step, len(interaction.Steps))
+		for j, s := range interaction.Steps {
+			steps[j] = crawler.Step{
+				Scroll: s.Scroll, Click: s.Click, Times: s.Times, Eval: s.Eval,
+				Wait: int(time.Duration(s.Wait).Milliseconds()),
+			}
+		}
+		interactions[i] = crawler.Interaction{Paths: interaction.Paths, Steps: steps}
+	}
+	return interactions
+}
+
+// Options converts how the rules fetch a URL into fetch options.
+func Options(f config.Fetch) crawler.FetchOptions {
+	return crawler.FetchOptions{Header: f.Header, Wait: f.Wait, WaitSelector: f.WaitSelector}
+}